@@ -38,6 +38,11 @@ func (l *adapterLogImpl) EmitTraceLink(link *TraceLink) {
 	l.rawLog.EmitTraceLink(l.ctx, link)
 }
 
+// EmitAudit implements the Log interface.
+func (l *adapterLogImpl) EmitAudit(event *AuditEvent) {
+	l.rawLog.EmitAudit(l.ctx, event)
+}
+
 // Begin implements the Log interface.
 func (l *adapterLogImpl) Begin(name string, options ...BeginOption) (context.Context, func()) {
 	return l.rawLog.Begin(l.ctx, name, options...)
@@ -68,11 +73,21 @@ func (l *adapterLogImpl) SetErrorMetadataKey(k string, v any) {
 	l.rawLog.SetErrorMetadataKey(l.ctx, k, v)
 }
 
+// SetStatus implements the Log interface.
+func (l *adapterLogImpl) SetStatus(status SpanStatus, message string) {
+	l.rawLog.SetStatus(l.ctx, status, message)
+}
+
 // GetCurrentTraceLink implements the Log interface.
 func (l *adapterLogImpl) GetCurrentTraceLink() *TraceLink {
 	return l.rawLog.GetCurrentTraceLink(l.ctx)
 }
 
+// GetCurrentBaggage implements the Log interface.
+func (l *adapterLogImpl) GetCurrentBaggage() Baggage {
+	return l.rawLog.GetCurrentBaggage(l.ctx)
+}
+
 // Flush implements the RawLog interface.
 func (l *adapterLogImpl) Flush() {
 	l.rawLog.Flush(l.ctx)
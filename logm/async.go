@@ -0,0 +1,417 @@
+package logm
+
+import (
+	"context"
+	"encoding"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+var (
+	_ transmission.Sender      = (*AsyncSink)(nil)
+	_ encoding.TextUnmarshaler = (*AsyncSinkOverflowPolicy)(nil)
+)
+
+// AsyncSinkOverflowPolicy describes what an [*AsyncSink] does when its queue is full.
+type AsyncSinkOverflowPolicy string
+
+// Known AsyncSinkOverflowPolicy values.
+const (
+	AsyncSinkOverflowPolicyBlock      AsyncSinkOverflowPolicy = "block"
+	AsyncSinkOverflowPolicyDropNewest AsyncSinkOverflowPolicy = "drop-newest"
+	AsyncSinkOverflowPolicyDropOldest AsyncSinkOverflowPolicy = "drop-oldest"
+	AsyncSinkOverflowPolicySample     AsyncSinkOverflowPolicy = "sample"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (p *AsyncSinkOverflowPolicy) UnmarshalText(text []byte) error {
+	switch v := AsyncSinkOverflowPolicy(text); v {
+	case "":
+		*p = AsyncSinkOverflowPolicyBlock
+		return nil
+	case AsyncSinkOverflowPolicyBlock, AsyncSinkOverflowPolicyDropNewest, AsyncSinkOverflowPolicyDropOldest, AsyncSinkOverflowPolicySample:
+		*p = v
+		return nil
+	default:
+		return errorz.Errorf("invalid value for AsyncSinkOverflowPolicy: '%s'", v)
+	}
+}
+
+// String implements the [fmt.Stringer] interface.
+func (p *AsyncSinkOverflowPolicy) String() string {
+	return string(*p)
+}
+
+// Default values for [AsyncSinkConfig].
+const (
+	DefaultAsyncSinkQueueSize     = 10000
+	DefaultAsyncSinkSampleRate    = 10
+	DefaultAsyncSinkStatsInterval = time.Minute
+	DefaultAsyncSinkDrainTimeout  = 5 * time.Second
+)
+
+// AsyncSinkConfig describes the configuration for an [*AsyncSink].
+type AsyncSinkConfig struct {
+	// QueueSize is the maximum number of events waiting to be forwarded.
+	QueueSize int
+	// OverflowPolicy determines what happens when the queue is full.
+	OverflowPolicy AsyncSinkOverflowPolicy
+	// SampleRate is used by [AsyncSinkOverflowPolicySample]: 1 in SampleRate overflowing events is kept.
+	SampleRate uint
+	// DrainTimeout is how long [AsyncSink.Stop] waits for the queue to drain.
+	DrainTimeout time.Duration
+}
+
+// AsyncSinkStats describes the state of an [*AsyncSink].
+type AsyncSinkStats struct {
+	Queued            int
+	DroppedNewest     int64
+	DroppedOldest     int64
+	DroppedSampled    int64
+	DroppedOnShutdown int64
+}
+
+// GetDropped returns the total number of dropped events.
+func (s *AsyncSinkStats) GetDropped() int64 {
+	return s.DroppedNewest + s.DroppedOldest + s.DroppedSampled + s.DroppedOnShutdown
+}
+
+type asyncSinkItem struct {
+	e       *transmission.Event
+	flushed chan struct{}
+}
+
+// AsyncSink is a [transmission.Sender] decorator that forwards events on a background goroutine using a bounded
+// queue, so that callers never wait on slow outputs unless the overflow policy says so.
+type AsyncSink struct {
+	clk            clkm.Clock
+	cfg            AsyncSinkConfig
+	s              transmission.Sender
+	q              chan *asyncSinkItem
+	m              *sync.RWMutex
+	closed         bool
+	closeOnce      *sync.Once
+	closeC         chan struct{}
+	abortC         chan struct{}
+	doneC          chan struct{}
+	overflowCount  *atomic.Uint64
+	droppedNewest  *atomic.Int64
+	droppedOldest  *atomic.Int64
+	droppedSampled *atomic.Int64
+	droppedStop    *atomic.Int64
+}
+
+// NewAsyncSink initializes a new [*AsyncSink] wrapping the given [transmission.Sender].
+func NewAsyncSink(ctx context.Context, sender transmission.Sender, cfg *AsyncSinkConfig) *AsyncSink {
+	errorz.Assertf(sender != nil, "sender is nil")
+
+	if cfg == nil {
+		cfg = &AsyncSinkConfig{}
+	}
+
+	s := &AsyncSink{
+		clk:            clkm.MustGet(ctx),
+		cfg:            *cfg,
+		s:              sender,
+		m:              &sync.RWMutex{},
+		overflowCount:  &atomic.Uint64{},
+		droppedNewest:  &atomic.Int64{},
+		droppedOldest:  &atomic.Int64{},
+		droppedSampled: &atomic.Int64{},
+		droppedStop:    &atomic.Int64{},
+	}
+
+	if s.cfg.QueueSize <= 0 {
+		s.cfg.QueueSize = DefaultAsyncSinkQueueSize
+	}
+
+	if s.cfg.OverflowPolicy == "" {
+		s.cfg.OverflowPolicy = AsyncSinkOverflowPolicyBlock
+	}
+
+	if s.cfg.SampleRate == 0 {
+		s.cfg.SampleRate = DefaultAsyncSinkSampleRate
+	}
+
+	if s.cfg.DrainTimeout <= 0 {
+		s.cfg.DrainTimeout = DefaultAsyncSinkDrainTimeout
+	}
+
+	return s
+}
+
+// GetStats returns the current stats.
+func (s *AsyncSink) GetStats() *AsyncSinkStats {
+	return &AsyncSinkStats{
+		Queued:            len(s.q),
+		DroppedNewest:     s.droppedNewest.Load(),
+		DroppedOldest:     s.droppedOldest.Load(),
+		DroppedSampled:    s.droppedSampled.Load(),
+		DroppedOnShutdown: s.droppedStop.Load(),
+	}
+}
+
+// Add implements the [transmission.Sender] interface. Events added while the sink is not running (i.e. before Start or
+// after Stop) are dropped, and counted in [AsyncSinkStats].DroppedOnShutdown.
+func (s *AsyncSink) Add(e *transmission.Event) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	if s.closed || s.q == nil {
+		// the sink is not running and the wrapped sender may be stopped: just count the event
+		s.droppedStop.Add(1)
+		return
+	}
+
+	item := &asyncSinkItem{e: e}
+
+	select {
+	case s.q <- item:
+		return
+	default:
+	}
+
+	switch s.cfg.OverflowPolicy {
+	case AsyncSinkOverflowPolicyDropNewest:
+		s.drop(e, s.droppedNewest)
+	case AsyncSinkOverflowPolicyDropOldest:
+		for {
+			select {
+			case s.q <- item:
+				return
+			default:
+			}
+
+			select {
+			case oldest := <-s.q:
+				if oldest.flushed != nil {
+					close(oldest.flushed)
+				} else {
+					s.drop(oldest.e, s.droppedOldest)
+				}
+			default:
+			}
+		}
+	case AsyncSinkOverflowPolicySample:
+		if s.overflowCount.Add(1)%uint64(s.cfg.SampleRate) == 0 {
+			s.send(item)
+		} else {
+			s.drop(e, s.droppedSampled)
+		}
+	default:
+		s.send(item)
+	}
+}
+
+// send waits for the item to be queued, unless the sink starts stopping first. Callers hold the read lock, so Stop
+// closes closeC before taking the write lock.
+func (s *AsyncSink) send(item *asyncSinkItem) {
+	select {
+	case s.q <- item:
+	case <-s.closeC:
+		// the wrapped sender may already be stopped: just count the event
+		s.droppedStop.Add(1)
+	}
+}
+
+// Start implements the [transmission.Sender] interface.
+func (s *AsyncSink) Start() error {
+	if err := s.s.Start(); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.q = make(chan *asyncSinkItem, s.cfg.QueueSize)
+	s.closed = false
+	s.closeOnce = &sync.Once{}
+	s.closeC = make(chan struct{})
+	s.abortC = make(chan struct{})
+	s.doneC = make(chan struct{})
+
+	go s.run(s.q)
+
+	return nil
+}
+
+// Stop implements the [transmission.Sender] interface. It waits up to the configured drain timeout for the queue to be
+// forwarded, dropping the remaining events afterwards. If the wrapped sender is still blocked when the timeout expires,
+// Stop does not wait for it any further. Calling Stop more than once (or without Start) is a no-op.
+func (s *AsyncSink) Stop() error {
+	timer := s.clk.Timer(s.cfg.DrainTimeout)
+	defer timer.Stop()
+
+	s.m.RLock()
+	q, closeOnce, closeC := s.q, s.closeOnce, s.closeC
+	s.m.RUnlock()
+
+	if q == nil {
+		return nil
+	}
+
+	// unblocks callers waiting for room in the queue, so that they release the read lock
+	closeOnce.Do(func() { close(closeC) })
+
+	s.m.Lock()
+	if s.closed {
+		s.m.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.q)
+	s.m.Unlock()
+
+	var err error
+
+	select {
+	case <-s.doneC:
+	case <-timer.C:
+		// the worker drops the remaining events once the wrapped sender returns, if ever
+		close(s.abortC)
+		err = errorz.Errorf("async sink: drain timeout exceeded")
+	}
+
+	if stopErr := s.s.Stop(); stopErr != nil && err == nil {
+		err = stopErr
+	}
+
+	return errorz.MaybeWrap(err)
+}
+
+// Flush implements the [transmission.Sender] interface. It waits for the events queued so far to be forwarded, or for
+// the sink to stop if it starts stopping first. It stops waiting if the drain timeout expires while stopping.
+func (s *AsyncSink) Flush() error {
+	s.m.RLock()
+
+	if s.closed || s.q == nil {
+		s.m.RUnlock()
+		return errorz.MaybeWrap(s.s.Flush())
+	}
+
+	flushed := make(chan struct{})
+	waitC, abortC := flushed, s.abortC
+
+	select {
+	case s.q <- &asyncSinkItem{flushed: flushed}:
+	case <-s.closeC:
+		waitC = s.doneC
+	}
+
+	s.m.RUnlock()
+
+	select {
+	case <-waitC:
+	case <-abortC:
+	}
+
+	return errorz.MaybeWrap(s.s.Flush())
+}
+
+// TxResponses implements the [transmission.Sender] interface.
+func (s *AsyncSink) TxResponses() chan transmission.Response {
+	return s.s.TxResponses()
+}
+
+// SendResponse implements the [transmission.Sender] interface.
+func (s *AsyncSink) SendResponse(response transmission.Response) bool {
+	return s.s.SendResponse(response)
+}
+
+func (s *AsyncSink) run(q chan *asyncSinkItem) {
+	defer close(s.doneC)
+
+	for item := range q {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+
+		select {
+		case <-s.abortC:
+			// the wrapped sender may already be stopped: just count the event
+			s.droppedStop.Add(1)
+		default:
+			s.s.Add(item.e)
+		}
+	}
+}
+
+func (s *AsyncSink) drop(e *transmission.Event, counter *atomic.Int64) {
+	counter.Add(1)
+
+	if e != nil {
+		s.s.SendResponse(transmission.Response{
+			Err:      errorz.Errorf("event dropped by async sink"),
+			Metadata: e.Metadata,
+		})
+	}
+}
+
+// StartAsyncSinkStats emits an "async-sink-stats" warning every interval in which the given [*AsyncSink] dropped
+// events, and once more when stopped if needed. It returns a function that stops emitting events. Events dropped while
+// the sink itself is stopping cannot be reported through it, see [AsyncSink.GetStats].
+func StartAsyncSinkStats(ctx context.Context, sink *AsyncSink, interval time.Duration) func() {
+	errorz.Assertf(interval > 0, "async sink stats interval must be positive")
+
+	stopC := make(chan struct{})
+	doneC := make(chan struct{})
+
+	go func() {
+		defer close(doneC)
+
+		ticker := clkm.MustGet(ctx).Ticker(interval)
+		defer ticker.Stop()
+
+		var prevDropped int64
+
+		for {
+			select {
+			case <-stopC:
+				emitAsyncSinkStats(ctx, sink, prevDropped)
+				return
+			case <-ticker.C:
+				prevDropped = emitAsyncSinkStats(ctx, sink, prevDropped)
+			}
+		}
+	}()
+
+	return func() {
+		close(stopC)
+		<-doneC
+	}
+}
+
+func emitAsyncSinkStats(ctx context.Context, sink *AsyncSink, prevDropped int64) int64 {
+	stats := sink.GetStats()
+	dropped := stats.GetDropped()
+
+	if dropped == prevDropped {
+		return prevDropped
+	}
+
+	bL, ok := getRootRawLog(ctx).(*backgroundLogImpl)
+	if !ok {
+		return prevDropped
+	}
+
+	e := newAttachableEvent(ctx, bL.client, "", "async-sink-stats")
+	addWarningFields(e, bL.o.payloadLimits, errorz.Errorf("async sink dropped %v events", dropped-prevDropped))
+	e.AddField("sink.async.dropped", dropped-prevDropped)
+	e.AddField("sink.async.dropped_total", dropped)
+	e.AddField("sink.async.dropped_newest", stats.DroppedNewest)
+	e.AddField("sink.async.dropped_oldest", stats.DroppedOldest)
+	e.AddField("sink.async.dropped_sampled", stats.DroppedSampled)
+	e.AddField("sink.async.dropped_on_shutdown", stats.DroppedOnShutdown)
+	e.AddField("sink.async.queued", stats.Queued)
+	e.AddField("sink.async.overflow_policy", string(sink.cfg.OverflowPolicy))
+	errorz.MaybeMustWrap(e.Send())
+
+	return dropped
+}
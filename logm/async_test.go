@@ -0,0 +1,275 @@
+package logm_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type testGatedSender struct {
+	*tlogm.MockSender
+	gate chan struct{}
+}
+
+func newTestGatedSender() *testGatedSender {
+	return &testGatedSender{
+		MockSender: tlogm.NewMockSender(),
+		gate:       make(chan struct{}),
+	}
+}
+
+func (s *testGatedSender) Add(e *transmission.Event) {
+	<-s.gate
+	s.MockSender.Add(e)
+}
+
+func newTestAsyncEvent(k string) *transmission.Event {
+	return &transmission.Event{
+		Data: map[string]any{"k": k},
+	}
+}
+
+func haveTestAsyncEvent(k string) any {
+	return PointTo(MatchFields(IgnoreExtras, Fields{
+		"Data": HaveKeyWithValue("k", k),
+	}))
+}
+
+type AsyncSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestAsyncSuite(t *testing.T) {
+	fixturez.RunSuite(t, &AsyncSuite{})
+}
+
+func (s *AsyncSuite) TestAsyncSink_Forward(ctx context.Context, g *WithT) {
+	sender := tlogm.NewMockSender()
+	sink := logm.NewAsyncSink(ctx, sender, nil)
+	g.Expect(sink.Start()).To(Succeed())
+
+	sink.Add(newTestAsyncEvent("v1"))
+	sink.Add(newTestAsyncEvent("v2"))
+	g.Expect(sink.Flush()).To(Succeed())
+
+	g.Expect(sender.GetEvents()).To(HaveExactElements(haveTestAsyncEvent("v1"), haveTestAsyncEvent("v2")))
+	g.Expect(sink.GetStats()).To(Equal(&logm.AsyncSinkStats{}))
+	g.Expect(sink.SendResponse(transmission.Response{})).To(BeTrue())
+	g.Expect(sink.TxResponses()).To(Equal(sender.TxResponses()))
+	g.Expect(sink.Stop()).To(Succeed())
+}
+
+func (s *AsyncSuite) TestAsyncSink_DropNewest(ctx context.Context, g *WithT) {
+	sender := newTestGatedSender()
+	sink := s.mustFill(ctx, g, sender, logm.AsyncSinkOverflowPolicyDropNewest, 1)
+
+	sink.Add(newTestAsyncEvent("v3"))
+	sink.Add(newTestAsyncEvent("v4"))
+	g.Expect(sink.GetStats()).To(Equal(&logm.AsyncSinkStats{Queued: 2, DroppedNewest: 2}))
+
+	close(sender.gate)
+	g.Expect(sink.Flush()).To(Succeed())
+
+	g.Expect(sender.GetEvents()).To(HaveExactElements(
+		haveTestAsyncEvent("v0"),
+		haveTestAsyncEvent("v1"),
+		haveTestAsyncEvent("v2")))
+
+	g.Expect(sink.Stop()).To(Succeed())
+}
+
+func (s *AsyncSuite) TestAsyncSink_DropOldest(ctx context.Context, g *WithT) {
+	sender := newTestGatedSender()
+	sink := s.mustFill(ctx, g, sender, logm.AsyncSinkOverflowPolicyDropOldest, 1)
+
+	sink.Add(newTestAsyncEvent("v3"))
+	sink.Add(newTestAsyncEvent("v4"))
+	g.Expect(sink.GetStats()).To(Equal(&logm.AsyncSinkStats{Queued: 2, DroppedOldest: 2}))
+
+	close(sender.gate)
+	g.Expect(sink.Flush()).To(Succeed())
+
+	g.Expect(sender.GetEvents()).To(HaveExactElements(
+		haveTestAsyncEvent("v0"),
+		haveTestAsyncEvent("v3"),
+		haveTestAsyncEvent("v4")))
+
+	g.Expect(sink.Stop()).To(Succeed())
+}
+
+func (s *AsyncSuite) TestAsyncSink_Sample(ctx context.Context, g *WithT) {
+	sender := newTestGatedSender()
+	sink := s.mustFill(ctx, g, sender, logm.AsyncSinkOverflowPolicySample, 3)
+
+	sink.Add(newTestAsyncEvent("v3"))
+	sink.Add(newTestAsyncEvent("v4"))
+	g.Expect(sink.GetStats()).To(Equal(&logm.AsyncSinkStats{Queued: 2, DroppedSampled: 2}))
+
+	close(sender.gate)
+	sink.Add(newTestAsyncEvent("v5"))
+	g.Expect(sink.Flush()).To(Succeed())
+
+	g.Expect(sender.GetEvents()).To(HaveExactElements(
+		haveTestAsyncEvent("v0"),
+		haveTestAsyncEvent("v1"),
+		haveTestAsyncEvent("v2"),
+		haveTestAsyncEvent("v5")))
+
+	g.Expect(sink.Stop()).To(Succeed())
+}
+
+func (s *AsyncSuite) TestAsyncSink_StatsEvent(ctx context.Context, g *WithT) {
+	sender := newTestGatedSender()
+	sink := s.mustFill(ctx, g, sender, logm.AsyncSinkOverflowPolicyDropNewest, 1)
+
+	sink.Add(newTestAsyncEvent("v3"))
+	close(sender.gate)
+	g.Expect(sink.Flush()).To(Succeed())
+
+	statsSender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: statsSender,
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	ctx = logm.NewSingletonInjector(logm.NewRawLogFromClient(client))(ctx)
+	stop := logm.StartAsyncSinkStats(ctx, sink, logm.DefaultAsyncSinkStatsInterval)
+
+	g.Eventually(func() []*transmission.Event {
+		s.CLK.GetMock().Add(logm.DefaultAsyncSinkStatsInterval)
+		return statsSender.GetEvents()
+	}, "1s", "10ms").Should(ContainElement(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Data": And(
+			HaveKeyWithValue("name", "async-sink-stats"),
+			HaveKeyWithValue("warning", "generic"),
+			HaveKeyWithValue("warning.message", "async sink dropped 1 events"),
+			HaveKeyWithValue("sink.async.dropped", int64(1)),
+			HaveKeyWithValue("sink.async.dropped_newest", int64(1)),
+			HaveKeyWithValue("sink.async.overflow_policy", "drop-newest"),
+		),
+	}))))
+
+	stop()
+	g.Expect(statsSender.GetEvents()).To(HaveLen(1))
+	g.Expect(sink.Stop()).To(Succeed())
+	g.Expect(func() { logm.StartAsyncSinkStats(ctx, sink, 0) }).To(PanicWith(MatchError("async sink stats interval must be positive")))
+}
+
+func (s *AsyncSuite) TestAsyncSink_DrainTimeout(ctx context.Context, g *WithT) {
+	sender := newTestGatedSender()
+	sink := s.mustFill(ctx, g, sender, logm.AsyncSinkOverflowPolicyBlock, 1)
+
+	stopped := &atomic.Bool{}
+	var stopErr error
+
+	go func() {
+		stopErr = sink.Stop()
+		stopped.Store(true)
+	}()
+
+	// Stop returns once the drain timeout expires, even though the wrapped sender is still blocked.
+	g.Eventually(func() bool {
+		s.CLK.GetMock().Add(time.Second)
+		return stopped.Load()
+	}, "1s", "10ms").Should(BeTrue())
+
+	g.Expect(stopErr).To(MatchError("async sink: drain timeout exceeded"))
+	g.Expect(sink.Stop()).To(Succeed())
+
+	close(sender.gate)
+	g.Eventually(func() int64 { return sink.GetStats().DroppedOnShutdown }, "1s", "10ms").Should(Equal(int64(2)))
+
+	sink.Add(newTestAsyncEvent("v3"))
+	g.Expect(sink.GetStats().DroppedOnShutdown).To(Equal(int64(3)))
+}
+
+func (s *AsyncSuite) TestAsyncSink_StopWhileBlocked(ctx context.Context, g *WithT) {
+	sender := newTestGatedSender()
+	sink := s.mustFill(ctx, g, sender, logm.AsyncSinkOverflowPolicyBlock, 1)
+
+	addedC := make(chan struct{})
+	go func() {
+		sink.Add(newTestAsyncEvent("v3"))
+		close(addedC)
+	}()
+
+	flushC := make(chan error, 1)
+	go func() {
+		flushC <- sink.Flush()
+	}()
+
+	stopC := make(chan error, 1)
+	go func() {
+		stopC <- sink.Stop()
+	}()
+
+	// Callers blocked on the full queue are released, and Stop returns once the drain timeout expires.
+	g.Eventually(addedC, "1s", "10ms").Should(BeClosed())
+	g.Eventually(func() chan error {
+		s.CLK.GetMock().Add(time.Second)
+		return stopC
+	}, "1s", "10ms").Should(Receive(MatchError("async sink: drain timeout exceeded")))
+	g.Eventually(flushC, "1s", "10ms").Should(Receive(Succeed()))
+
+	close(sender.gate)
+	g.Eventually(func() int64 { return sink.GetStats().DroppedOnShutdown }, "1s", "10ms").Should(Equal(int64(3)))
+	g.Expect(sender.GetEvents()).To(HaveExactElements(haveTestAsyncEvent("v0")))
+}
+
+func (*AsyncSuite) TestAsyncSink_NotStarted(ctx context.Context, g *WithT) {
+	sender := tlogm.NewMockSender()
+	sink := logm.NewAsyncSink(ctx, sender, nil)
+
+	sink.Add(newTestAsyncEvent("v0"))
+	g.Expect(sink.Flush()).To(Succeed())
+	g.Expect(sink.GetStats()).To(Equal(&logm.AsyncSinkStats{DroppedOnShutdown: 1}))
+	g.Expect(sender.GetEvents()).To(BeEmpty())
+}
+
+func (*AsyncSuite) TestAsyncSink_StopWithoutStart(ctx context.Context, g *WithT) {
+	g.Expect(logm.NewAsyncSink(ctx, tlogm.NewMockSender(), nil).Stop()).To(Succeed())
+}
+
+func (s *AsyncSuite) TestAsyncSinkOverflowPolicy(g *WithT) {
+	p := logm.AsyncSinkOverflowPolicy("")
+	g.Expect(p.UnmarshalText([]byte(""))).To(Succeed())
+	g.Expect(p.String()).To(Equal("block"))
+	g.Expect(p.UnmarshalText([]byte("sample"))).To(Succeed())
+	g.Expect(p).To(Equal(logm.AsyncSinkOverflowPolicySample))
+	g.Expect(p.UnmarshalText([]byte("invalid"))).To(MatchError("invalid value for AsyncSinkOverflowPolicy: 'invalid'"))
+}
+
+// mustFill starts an async sink with a queue of size 2, then fills it while the gated sender blocks on "v0".
+func (s *AsyncSuite) mustFill(ctx context.Context, g *WithT, sender *testGatedSender, policy logm.AsyncSinkOverflowPolicy, sampleRate uint) *logm.AsyncSink {
+	sink := logm.NewAsyncSink(ctx, sender, &logm.AsyncSinkConfig{
+		QueueSize:      2,
+		OverflowPolicy: policy,
+		SampleRate:     sampleRate,
+		DrainTimeout:   time.Second,
+	})
+	g.Expect(sink.Start()).To(Succeed())
+
+	sink.Add(newTestAsyncEvent("v0"))
+	g.Eventually(func() int { return sink.GetStats().Queued }, "1s", "10ms").Should(BeZero())
+	sink.Add(newTestAsyncEvent("v1"))
+	sink.Add(newTestAsyncEvent("v2"))
+	g.Expect(sink.GetStats().Queued).To(Equal(2))
+
+	return sink
+}
@@ -0,0 +1,312 @@
+package logm
+
+import (
+	"context"
+	"encoding"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/jsonz"
+)
+
+var (
+	_ encoding.TextUnmarshaler = (*AttributeRegistryMode)(nil)
+)
+
+var (
+	reservedAttributeKeyPrefixes = append(
+		slices.Clone(reservedBaggageKeyPrefixes),
+		"scope.",
+		"span.",
+		"context.",
+		"payload.",
+		"rate_limit.")
+)
+
+// AttributeType describes the type of attribute.
+type AttributeType string
+
+// Known AttributeType values.
+const (
+	AttributeTypeString      AttributeType = "string"
+	AttributeTypeInt         AttributeType = "int"
+	AttributeTypeBool        AttributeType = "bool"
+	AttributeTypeDuration    AttributeType = "duration"
+	AttributeTypeStringSlice AttributeType = "string-slice"
+)
+
+// Attribute describes a typed attribute.
+type Attribute struct {
+	Key   string
+	Type  AttributeType
+	Value any
+}
+
+// StringKey describes a string attribute key.
+type StringKey string
+
+// V returns an [*Attribute] with the given value.
+func (k StringKey) V(v string) *Attribute {
+	return &Attribute{Key: string(k), Type: AttributeTypeString, Value: v}
+}
+
+// Spec returns an [*AttributeSpec] for the key, with the given description.
+func (k StringKey) Spec(description string) *AttributeSpec {
+	return &AttributeSpec{Key: string(k), Type: AttributeTypeString, Description: description}
+}
+
+// IntKey describes an int attribute key.
+type IntKey string
+
+// V returns an [*Attribute] with the given value.
+func (k IntKey) V(v int64) *Attribute {
+	return &Attribute{Key: string(k), Type: AttributeTypeInt, Value: v}
+}
+
+// Spec returns an [*AttributeSpec] for the key, with the given description.
+func (k IntKey) Spec(description string) *AttributeSpec {
+	return &AttributeSpec{Key: string(k), Type: AttributeTypeInt, Description: description}
+}
+
+// BoolKey describes a bool attribute key.
+type BoolKey string
+
+// V returns an [*Attribute] with the given value.
+func (k BoolKey) V(v bool) *Attribute {
+	return &Attribute{Key: string(k), Type: AttributeTypeBool, Value: v}
+}
+
+// Spec returns an [*AttributeSpec] for the key, with the given description.
+func (k BoolKey) Spec(description string) *AttributeSpec {
+	return &AttributeSpec{Key: string(k), Type: AttributeTypeBool, Description: description}
+}
+
+// DurationKey describes a duration attribute key. Durations are rendered as float milliseconds.
+type DurationKey string
+
+// V returns an [*Attribute] with the given value.
+func (k DurationKey) V(v time.Duration) *Attribute {
+	return &Attribute{Key: string(k), Type: AttributeTypeDuration, Value: float64(v) / float64(time.Millisecond)}
+}
+
+// Spec returns an [*AttributeSpec] for the key, with the given description.
+func (k DurationKey) Spec(description string) *AttributeSpec {
+	return &AttributeSpec{Key: string(k), Type: AttributeTypeDuration, Description: description}
+}
+
+// StringSliceKey describes a string slice attribute key.
+type StringSliceKey string
+
+// V returns an [*Attribute] with the given value.
+func (k StringSliceKey) V(v ...string) *Attribute {
+	return &Attribute{Key: string(k), Type: AttributeTypeStringSlice, Value: v}
+}
+
+// Spec returns an [*AttributeSpec] for the key, with the given description.
+func (k StringSliceKey) Spec(description string) *AttributeSpec {
+	return &AttributeSpec{Key: string(k), Type: AttributeTypeStringSlice, Description: description}
+}
+
+// Semantic attribute keys.
+const (
+	AttrHTTPMethod       StringKey      = "http.method"
+	AttrHTTPRoute        StringKey      = "http.route"
+	AttrHTTPURL          StringKey      = "http.url"
+	AttrHTTPUserAgent    StringKey      = "http.user_agent"
+	AttrHTTPClientIP     StringKey      = "http.client_ip"
+	AttrHTTPStatusCode   IntKey         = "http.status_code"
+	AttrHTTPRequestSize  IntKey         = "http.request_size"
+	AttrHTTPResponseSize IntKey         = "http.response_size"
+	AttrDBSystem         StringKey      = "db.system"
+	AttrDBName           StringKey      = "db.name"
+	AttrDBStatement      StringKey      = "db.statement"
+	AttrDBOperation      StringKey      = "db.operation"
+	AttrDBRowsAffected   IntKey         = "db.rows_affected"
+	AttrDBDuration       DurationKey    = "db.duration_ms"
+	AttrMsgSystem        StringKey      = "messaging.system"
+	AttrMsgDestination   StringKey      = "messaging.destination"
+	AttrMsgMessageID     StringKey      = "messaging.message_id"
+	AttrMsgBatchSize     IntKey         = "messaging.batch_size"
+	AttrMsgRedelivered   BoolKey        = "messaging.redelivered"
+	AttrUserID           StringKey      = "user.id"
+	AttrUserEmail        StringKey      = "user.email"
+	AttrUserRoles        StringSliceKey = "user.roles"
+)
+
+// AttributeRegistryMode describes how unknown attribute keys are handled.
+type AttributeRegistryMode string
+
+// Known AttributeRegistryMode values.
+const (
+	AttributeRegistryModeAllow  AttributeRegistryMode = "allow"
+	AttributeRegistryModeWarn   AttributeRegistryMode = "warn"
+	AttributeRegistryModeReject AttributeRegistryMode = "reject"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (m *AttributeRegistryMode) UnmarshalText(text []byte) error {
+	switch v := AttributeRegistryMode(text); v {
+	case "":
+		*m = AttributeRegistryModeWarn
+		return nil
+	case AttributeRegistryModeAllow, AttributeRegistryModeWarn, AttributeRegistryModeReject:
+		*m = v
+		return nil
+	default:
+		return errorz.Errorf("invalid value for AttributeRegistryMode: '%s'", v)
+	}
+}
+
+// String implements the [fmt.Stringer] interface.
+func (m *AttributeRegistryMode) String() string {
+	return string(*m)
+}
+
+// AttributeSpec describes a registered attribute.
+type AttributeSpec struct {
+	Key         string        `json:"key"`
+	Type        AttributeType `json:"type"`
+	Description string        `json:"description,omitempty"`
+}
+
+// AttributeSchema describes the schema document exported by an [*AttributeRegistry].
+type AttributeSchema struct {
+	Attributes []*AttributeSpec `json:"attributes"`
+}
+
+// AttributeRegistry describes a set of known attributes.
+type AttributeRegistry struct {
+	m     *sync.RWMutex
+	specs map[string]*AttributeSpec
+}
+
+// DefaultAttributeRegistry is the default registry, pre-populated with the semantic attribute keys.
+var DefaultAttributeRegistry = NewAttributeRegistry().
+	MustRegister(AttrHTTPMethod.Spec("HTTP request method")).
+	MustRegister(AttrHTTPRoute.Spec("HTTP route template")).
+	MustRegister(AttrHTTPURL.Spec("HTTP request URL")).
+	MustRegister(AttrHTTPUserAgent.Spec("HTTP user agent")).
+	MustRegister(AttrHTTPClientIP.Spec("HTTP client IP address")).
+	MustRegister(AttrHTTPStatusCode.Spec("HTTP response status code")).
+	MustRegister(AttrHTTPRequestSize.Spec("HTTP request body size in bytes")).
+	MustRegister(AttrHTTPResponseSize.Spec("HTTP response body size in bytes")).
+	MustRegister(AttrDBSystem.Spec("Database system")).
+	MustRegister(AttrDBName.Spec("Database name")).
+	MustRegister(AttrDBStatement.Spec("Normalized database statement")).
+	MustRegister(AttrDBOperation.Spec("Database operation")).
+	MustRegister(AttrDBRowsAffected.Spec("Number of rows affected by the statement")).
+	MustRegister(AttrDBDuration.Spec("Database statement duration")).
+	MustRegister(AttrMsgSystem.Spec("Messaging system")).
+	MustRegister(AttrMsgDestination.Spec("Messaging queue or topic")).
+	MustRegister(AttrMsgMessageID.Spec("Messaging message ID")).
+	MustRegister(AttrMsgBatchSize.Spec("Number of messages in the batch")).
+	MustRegister(AttrMsgRedelivered.Spec("Whether the message was redelivered")).
+	MustRegister(AttrUserID.Spec("User ID")).
+	MustRegister(AttrUserEmail.Spec("User email")).
+	MustRegister(AttrUserRoles.Spec("User roles"))
+
+// NewAttributeRegistry initializes a new, empty [*AttributeRegistry].
+func NewAttributeRegistry() *AttributeRegistry {
+	return &AttributeRegistry{
+		m:     &sync.RWMutex{},
+		specs: make(map[string]*AttributeSpec),
+	}
+}
+
+// Register registers an attribute. Registering a reserved key, or the same key twice with different types fails.
+func (r *AttributeRegistry) Register(spec *AttributeSpec) error {
+	if isAttributeKeyReserved(spec.Key) {
+		return errorz.Errorf("attribute '%v' is reserved", spec.Key)
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if prev, ok := r.specs[spec.Key]; ok && prev.Type != spec.Type {
+		return errorz.Errorf("attribute '%v' already registered with type '%v'", spec.Key, prev.Type)
+	}
+
+	r.specs[spec.Key] = spec
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It returns the registry to allow chaining.
+func (r *AttributeRegistry) MustRegister(spec *AttributeSpec) *AttributeRegistry {
+	errorz.MaybeMustWrap(r.Register(spec))
+	return r
+}
+
+// Validate validates an attribute against the registry. Reserved keys are always rejected, regardless of the mode.
+func (r *AttributeRegistry) Validate(a *Attribute, mode AttributeRegistryMode) (bool, error) {
+	if isAttributeKeyReserved(a.Key) {
+		return false, errorz.Errorf("attribute '%v' is reserved", a.Key)
+	}
+
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	spec, ok := r.specs[a.Key]
+
+	switch {
+	case ok && spec.Type != a.Type:
+		return false, errorz.Errorf("attribute '%v' has type '%v', expected '%v'", a.Key, a.Type, spec.Type)
+	case ok || mode == AttributeRegistryModeAllow:
+		return true, nil
+	case mode == AttributeRegistryModeReject:
+		return false, errorz.Errorf("unknown attribute '%v'", a.Key)
+	default:
+		return true, errorz.Errorf("unknown attribute '%v'", a.Key)
+	}
+}
+
+// ExportSchema exports the registry as a schema document, sorted by key.
+func (r *AttributeRegistry) ExportSchema() *AttributeSchema {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	s := &AttributeSchema{
+		Attributes: make([]*AttributeSpec, 0, len(r.specs)),
+	}
+
+	for _, spec := range r.specs {
+		s.Attributes = append(s.Attributes, spec)
+	}
+
+	slices.SortFunc(s.Attributes, func(a, b *AttributeSpec) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return s
+}
+
+// MustExportSchemaJSON exports the registry as an indented JSON schema document.
+func (r *AttributeRegistry) MustExportSchemaJSON() []byte {
+	return jsonz.MustMarshalPretty(r.ExportSchema())
+}
+
+// SetAttributes sets typed attributes on the current span. Unlike metadata keys, attributes are emitted as top-level
+// fields of the span, using their own key. It does nothing if there is no [RawLog] in context.
+func SetAttributes(ctx context.Context, attrs ...*Attribute) {
+	if rawLog, ok := ctx.Value(logContextKey).(RawLog); ok {
+		rawLog.SetAttributes(ctx, attrs...)
+	}
+}
+
+// SetPropagatingAttributes is like [SetAttributes], but the attributes are also set on all descendant spans, and
+// included in [Baggage]. It does nothing if there is no [RawLog] in context.
+func SetPropagatingAttributes(ctx context.Context, attrs ...*Attribute) {
+	if rawLog, ok := ctx.Value(logContextKey).(RawLog); ok {
+		rawLog.SetPropagatingAttributes(ctx, attrs...)
+	}
+}
+
+// isAttributeKeyReserved returns true if the key has no namespace or uses a reserved one, so that attributes can't
+// override the fields used to build traces and events (e.g. "name", "duration_ms", "trace.trace_id").
+func isAttributeKeyReserved(k string) bool {
+	return !strings.Contains(k, ".") || slices.ContainsFunc(reservedAttributeKeyPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(k, prefix)
+	})
+}
@@ -0,0 +1,135 @@
+package logm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type AttributesSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestAttributesSuite(t *testing.T) {
+	fixturez.RunSuite(t, &AttributesSuite{})
+}
+
+func (s *AttributesSuite) TestAttributeRegistry(g *WithT) {
+	r := logm.NewAttributeRegistry().
+		MustRegister(logm.AttrHTTPMethod.Spec("method")).
+		MustRegister(logm.IntKey("k.int").Spec(""))
+
+	g.Expect(r.Register(logm.StringKey("k.int").Spec(""))).
+		To(MatchError("attribute 'k.int' already registered with type 'int'"))
+	g.Expect(r.Register(logm.StringKey("trace.trace_id").Spec(""))).
+		To(MatchError("attribute 'trace.trace_id' is reserved"))
+
+	for _, k := range []string{"name", "duration_ms", "error", "trace.span_id", "scope.user", "span.kind", "error.message"} {
+		ok, err := r.Validate(logm.StringKey(k).V("v"), logm.AttributeRegistryModeAllow)
+		g.Expect(ok).To(BeFalse())
+		g.Expect(err).To(MatchError(fmt.Sprintf("attribute '%v' is reserved", k)))
+	}
+
+	ok, err := r.Validate(logm.AttrHTTPMethod.V("GET"), logm.AttributeRegistryModeReject)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(err).To(Succeed())
+
+	ok, err = r.Validate(logm.StringKey("k.int").V("v"), logm.AttributeRegistryModeAllow)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(MatchError("attribute 'k.int' has type 'string', expected 'int'"))
+
+	ok, err = r.Validate(logm.StringKey("k.unknown").V("v"), logm.AttributeRegistryModeAllow)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(err).To(Succeed())
+
+	ok, err = r.Validate(logm.StringKey("k.unknown").V("v"), logm.AttributeRegistryModeWarn)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(err).To(MatchError("unknown attribute 'k.unknown'"))
+
+	ok, err = r.Validate(logm.StringKey("k.unknown").V("v"), logm.AttributeRegistryModeReject)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(MatchError("unknown attribute 'k.unknown'"))
+
+	g.Expect(r.ExportSchema()).To(Equal(&logm.AttributeSchema{
+		Attributes: []*logm.AttributeSpec{
+			{Key: "http.method", Type: logm.AttributeTypeString, Description: "method"},
+			{Key: "k.int", Type: logm.AttributeTypeInt},
+		},
+	}))
+
+	g.Expect(string(r.MustExportSchemaJSON())).To(MatchJSON(`{"attributes":[{"key":"http.method","type":"string","description":"method"},{"key":"k.int","type":"int"}]}`))
+	g.Expect(logm.DefaultAttributeRegistry.ExportSchema().Attributes).To(ContainElement(logm.AttrUserRoles.Spec("User roles")))
+}
+
+func (s *AttributesSuite) TestAttributes(ctx context.Context, g *WithT) {
+	ctx, end := logm.MustGet(ctx).Begin("S1", logm.BeginAttributes(logm.AttrMsgBatchSize.V(10)))
+	logm.SetPropagatingAttributes(ctx, logm.AttrUserID.V("user-id"))
+	logm.SetAttributes(ctx,
+		logm.AttrHTTPMethod.V("GET"),
+		logm.AttrHTTPStatusCode.V(200),
+		logm.AttrMsgRedelivered.V(true),
+		logm.AttrDBDuration.V(1500*time.Microsecond),
+		logm.AttrUserRoles.V("r1", "r2"),
+		logm.StringKey("http.status_code").V("invalid"),
+		logm.StringKey("name").V("override"),
+		logm.DurationKey("trace.trace_id").V(time.Second),
+		logm.StringKey("custom.key").V("v"),
+		nil)
+	end()
+
+	events := s.LOG.GetMock().GetEvents()
+	g.Expect(events).To(HaveLen(5))
+
+	g.Expect(events[0].Data).To(And(
+		HaveKeyWithValue("warning.message", "attribute 'http.status_code' has type 'string', expected 'int'"),
+		HaveKeyWithValue("user.id", "user-id")))
+	g.Expect(events[1].Data).To(HaveKeyWithValue("warning.message", "attribute 'name' is reserved"))
+	g.Expect(events[2].Data).To(HaveKeyWithValue("warning.message", "attribute 'trace.trace_id' is reserved"))
+	g.Expect(events[3].Data).To(HaveKeyWithValue("warning.message", "unknown attribute 'custom.key'"))
+	g.Expect(events[4]).To(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Data": And(
+			HaveKeyWithValue("name", "S1"),
+			HaveKeyWithValue("trace.trace_id", events[0].Data["trace.trace_id"]),
+			HaveKeyWithValue("messaging.batch_size", int64(10)),
+			HaveKeyWithValue("user.id", "user-id"),
+			HaveKeyWithValue("http.method", "GET"),
+			HaveKeyWithValue("http.status_code", int64(200)),
+			HaveKeyWithValue("messaging.redelivered", true),
+			HaveKeyWithValue("db.duration_ms", 1.5),
+			HaveKeyWithValue("user.roles", []string{"r1", "r2"}),
+			HaveKeyWithValue("custom.key", "v")),
+	})))
+}
+
+func (s *AttributesSuite) TestAttributes_Background(ctx context.Context, g *WithT) {
+	logm.SetAttributes(ctx, logm.AttrHTTPMethod.V("GET"))
+	logm.SetPropagatingAttributes(ctx, logm.AttrHTTPMethod.V("GET"))
+
+	g.Expect(s.LOG.GetMock().GetEvents()).To(HaveExactElements(
+		HaveField("Data", HaveKeyWithValue("warning.message", "called SetAttributes in background Log")),
+		HaveField("Data", HaveKeyWithValue("warning.message", "called SetPropagatingAttributes in background Log"))))
+}
+
+func (*AttributesSuite) TestAttributes_NoLog(g *WithT) {
+	g.Expect(func() { logm.SetAttributes(context.Background(), logm.AttrHTTPMethod.V("GET")) }).ToNot(Panic())
+	g.Expect(func() { logm.SetPropagatingAttributes(context.Background(), logm.AttrHTTPMethod.V("GET")) }).ToNot(Panic())
+}
+
+func (s *AttributesSuite) TestAttributeRegistryMode(g *WithT) {
+	m := logm.AttributeRegistryMode("")
+	g.Expect(m.UnmarshalText([]byte(""))).To(Succeed())
+	g.Expect(m.String()).To(Equal("warn"))
+	g.Expect(m.UnmarshalText([]byte("reject"))).To(Succeed())
+	g.Expect(m).To(Equal(logm.AttributeRegistryModeReject))
+	g.Expect(m.UnmarshalText([]byte("invalid"))).To(MatchError("invalid value for AttributeRegistryMode: 'invalid'"))
+}
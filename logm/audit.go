@@ -0,0 +1,441 @@
+package logm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/hashz"
+
+	"github.com/ibrt/golang-modules/cfgm"
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+const (
+	auditLogMaxLineBytes = 1024 * 1024
+)
+
+var (
+	_ encoding.TextUnmarshaler = (*AuditOutcome)(nil)
+)
+
+// AuditOutcome describes the outcome of an audited action.
+type AuditOutcome string
+
+// Known AuditOutcome values.
+const (
+	AuditOutcomeSuccess AuditOutcome = "success"
+	AuditOutcomeFailure AuditOutcome = "failure"
+	AuditOutcomeDenied  AuditOutcome = "denied"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (o *AuditOutcome) UnmarshalText(text []byte) error {
+	switch v := AuditOutcome(text); v {
+	case AuditOutcomeSuccess, AuditOutcomeFailure, AuditOutcomeDenied:
+		*o = v
+		return nil
+	default:
+		return errorz.Errorf("invalid value for AuditOutcome: '%s'", v)
+	}
+}
+
+// String implements the [fmt.Stringer] interface.
+func (o *AuditOutcome) String() string {
+	return string(*o)
+}
+
+// AuditEvent describes a security-relevant action, see [Log.EmitAudit].
+type AuditEvent struct {
+	// Actor is the user performing the action. It defaults to the user set on the current span using [Log.SetUser].
+	Actor    *User
+	Action   string
+	Target   string
+	Outcome  AuditOutcome
+	Metadata map[string]any
+}
+
+// AuditRecord describes a record in an [*AuditLog]. Each record includes the hash of the previous one, so that
+// removing, reordering or modifying records is detectable using [VerifyAuditLog], see [*AuditLog] for the limits.
+type AuditRecord struct {
+	Sequence   int64          `json:"sequence"`
+	Time       time.Time      `json:"time"`
+	ActorID    string         `json:"actorId,omitempty"`
+	ActorEmail string         `json:"actorEmail,omitempty"`
+	Action     string         `json:"action"`
+	Target     string         `json:"target,omitempty"`
+	Outcome    AuditOutcome   `json:"outcome"`
+	TraceLink  string         `json:"traceLink,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	PrevHash   string         `json:"prevHash,omitempty"`
+	Hash       string         `json:"hash,omitempty"`
+}
+
+// computeHash computes the hash of the record: an HMAC-SHA256 if a key is given, a plain SHA-256 otherwise.
+func (r *AuditRecord) computeHash(key []byte) (string, error) {
+	unhashed := *r
+	unhashed.Hash = ""
+
+	buf, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", errorz.Wrap(err)
+	}
+
+	if len(key) == 0 {
+		return hashz.MustHashSHA256(buf), nil
+	}
+
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write(buf)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// normalizeAuditMetadata round-trips the metadata through JSON, so that the hash computed by [*AuditLog.Append] matches
+// the one computed by [VerifyAuditLog] on the decoded record (e.g. for structs or integers that don't fit a float64).
+func normalizeAuditMetadata(metadata map[string]any) (map[string]any, error) {
+	if metadata == nil {
+		return nil, nil
+	}
+
+	buf, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	normalized := map[string]any{}
+	if err := unmarshalAuditJSON(buf, &normalized); err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	return normalized, nil
+}
+
+// unmarshalAuditJSON unmarshals the given JSON, decoding numbers as [json.Number] so that they are not rounded.
+func unmarshalAuditJSON(buf []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(buf))
+	d.UseNumber()
+	return errorz.MaybeWrap(d.Decode(v))
+}
+
+// AuditAnchor describes a record of an [*AuditLog], stored outside of it (e.g. periodically published to a separate
+// system), so that truncating or rewriting the log can be detected by [VerifyAuditLog].
+type AuditAnchor struct {
+	Sequence int64  `json:"sequence"`
+	Hash     string `json:"hash"`
+}
+
+// AuditLog is a tamper-evident, append-only log of [*AuditRecord], written as JSON lines. Records are never sampled.
+//
+// Threat model: each record is chained to the previous one using its hash. Without a key, the hash is a plain SHA-256,
+// which only detects accidental corruption: anyone with write access to the log can rewrite the whole chain. With a
+// key, the hash is an HMAC-SHA256, so modifying, removing or reordering records is detectable as long as the key is
+// kept away from the log (e.g. in a secrets manager). Truncating the log (i.e. removing the most recent records) is
+// only detectable by comparing it against an [*AuditAnchor] stored elsewhere, see [*AuditLog.GetAnchor]. The log does
+// not provide confidentiality.
+type AuditLog struct {
+	m    *sync.Mutex
+	w    io.Writer
+	key  []byte
+	last *AuditRecord
+	err  error
+}
+
+// NewAuditLog initializes a new [*AuditLog] that writes to the given writer, hashing records using the given key (see
+// [*AuditLog] for the threat model). If the log continues an existing chain, last must be the last record in the chain
+// (e.g. as returned by [VerifyAuditLog]), nil otherwise. If the writer implements Sync (e.g. [*os.File]), it is synced
+// after each record.
+func NewAuditLog(w io.Writer, key []byte, last *AuditRecord) *AuditLog {
+	return &AuditLog{
+		m:    &sync.Mutex{},
+		w:    w,
+		key:  key,
+		last: last,
+	}
+}
+
+// MustNewDefaultAuditLog initializes a default [*AuditLog] using the [LogConfigMixin] from context, keyed using its
+// AuditKey. It returns nil if no audit file path is configured, and panics if the existing file fails verification.
+func MustNewDefaultAuditLog(ctx context.Context) *AuditLog {
+	logCfg := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig()
+
+	if path := logCfg.AuditFilePath; path != "" && path != cfgm.DisabledValue {
+		auditLog, err := OpenAuditFile(path, []byte(logCfg.AuditKey))
+		errorz.MaybeMustWrap(err)
+		return auditLog
+	}
+
+	return nil
+}
+
+// OpenAuditFile opens an [*AuditLog] backed by the given file, creating it if necessary. A partially written last
+// record (e.g. after a crash) is discarded, then existing records are verified using the given key and the chain is
+// continued.
+func OpenAuditFile(path string, key []byte) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	last, err := repairAndVerifyAuditFile(f, key)
+	if err != nil {
+		_ = f.Close()
+		return nil, errorz.Wrap(err)
+	}
+
+	return NewAuditLog(f, key, last), nil
+}
+
+// repairAndVerifyAuditFile truncates the file after its last complete line, then verifies it. Records are always
+// written as a single line terminated by a newline, so an unterminated last line can only be a partial write.
+func repairAndVerifyAuditFile(f *os.File, key []byte) (*AuditRecord, error) {
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	if size := int64(bytes.LastIndexByte(buf, '\n') + 1); size < int64(len(buf)) {
+		if err := f.Truncate(size); err != nil {
+			return nil, errorz.Wrap(err)
+		}
+		buf = buf[:size]
+	}
+
+	return VerifyAuditLog(bytes.NewReader(buf), key)
+}
+
+// GetAnchor returns an [*AuditAnchor] for the last record, or nil if the log is empty. Storing it outside the log
+// allows [VerifyAuditLog] to detect truncation.
+func (a *AuditLog) GetAnchor() *AuditAnchor {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if a.last == nil {
+		return nil
+	}
+
+	return &AuditAnchor{
+		Sequence: a.last.Sequence,
+		Hash:     a.last.Hash,
+	}
+}
+
+// Append appends a record to the log, filling in its sequence number and hashes. It returns the appended record, with
+// its metadata normalized to what [VerifyAuditLog] decodes (i.e. JSON values, with numbers as [json.Number]). If a
+// write fails, the log is rolled back to the previous record when the writer supports it (e.g. [*os.File]), otherwise
+// all subsequent appends fail, so that the chain is never continued after a partial record.
+func (a *AuditLog) Append(r *AuditRecord) (*AuditRecord, error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if a.err != nil {
+		return nil, errorz.Wrap(a.err)
+	}
+
+	metadata, err := normalizeAuditMetadata(r.Metadata)
+	if err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	r = &AuditRecord{
+		Sequence:   1,
+		Time:       r.Time.UTC(),
+		ActorID:    r.ActorID,
+		ActorEmail: r.ActorEmail,
+		Action:     r.Action,
+		Target:     r.Target,
+		Outcome:    r.Outcome,
+		TraceLink:  r.TraceLink,
+		Metadata:   metadata,
+	}
+
+	if a.last != nil {
+		r.Sequence = a.last.Sequence + 1
+		r.PrevHash = a.last.Hash
+	}
+
+	hash, err := r.computeHash(a.key)
+	if err != nil {
+		return nil, errorz.Wrap(err)
+	}
+	r.Hash = hash
+
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	if err := a.write(append(buf, '\n')); err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	a.last = r
+	return r, nil
+}
+
+// write writes and syncs the given line, rolling back on failure.
+func (a *AuditLog) write(line []byte) error {
+	f, isFile := a.w.(interface {
+		Stat() (os.FileInfo, error)
+		Truncate(size int64) error
+	})
+
+	size := int64(-1)
+	if isFile {
+		if fi, err := f.Stat(); err == nil {
+			size = fi.Size()
+		}
+	}
+
+	_, err := a.w.Write(line)
+	if err == nil {
+		if s, ok := a.w.(interface{ Sync() error }); ok {
+			err = s.Sync()
+		}
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if size < 0 || f.Truncate(size) != nil {
+		a.err = errorz.Errorf("audit log is in an inconsistent state after a failed write")
+	}
+
+	return errorz.Wrap(err)
+}
+
+// Close closes the underlying writer if it implements [io.Closer].
+func (a *AuditLog) Close() error {
+	if c, ok := a.w.(io.Closer); ok {
+		return errorz.MaybeWrap(c.Close())
+	}
+
+	return nil
+}
+
+// VerifyAuditLog reads JSON lines written by an [*AuditLog] and verifies the hash chain using the given key, from the
+// first record. Each given [*AuditAnchor] must match a record in the log, which detects truncation. It returns the
+// last record, or nil if there are no records, or an error describing the first inconsistency.
+func VerifyAuditLog(r io.Reader, key []byte, anchors ...*AuditAnchor) (*AuditRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), auditLogMaxLineBytes)
+
+	var last *AuditRecord
+	hashes := make(map[int64]string, len(anchors))
+
+	for _, anchor := range anchors {
+		hashes[anchor.Sequence] = anchor.Hash
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		record := &AuditRecord{}
+
+		if err := unmarshalAuditJSON(scanner.Bytes(), record); err != nil {
+			return nil, errorz.Wrap(err, errorz.Errorf("audit log line %v", line))
+		}
+
+		expectedSequence, expectedPrevHash := int64(1), ""
+		if last != nil {
+			expectedSequence, expectedPrevHash = last.Sequence+1, last.Hash
+		}
+
+		if record.Sequence != expectedSequence {
+			return nil, errorz.Errorf("audit log line %v: expected sequence %v, got %v", line, expectedSequence, record.Sequence)
+		}
+
+		if record.PrevHash != expectedPrevHash {
+			return nil, errorz.Errorf("audit log line %v: previous hash mismatch", line)
+		}
+
+		hash, err := record.computeHash(key)
+		if err != nil {
+			return nil, errorz.Wrap(err, errorz.Errorf("audit log line %v", line))
+		}
+
+		if !hmac.Equal([]byte(record.Hash), []byte(hash)) {
+			return nil, errorz.Errorf("audit log line %v: hash mismatch", line)
+		}
+
+		if anchorHash, ok := hashes[record.Sequence]; ok {
+			if anchorHash != record.Hash {
+				return nil, errorz.Errorf("audit log line %v: anchor mismatch", line)
+			}
+			delete(hashes, record.Sequence)
+		}
+
+		last = record
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	if len(hashes) > 0 {
+		return nil, errorz.Errorf("audit log is missing %v anchored record(s), it may have been truncated", len(hashes))
+	}
+
+	return last, nil
+}
+
+func newAuditRecord(ctx context.Context, event *AuditEvent, user *User, traceLink *TraceLink) *AuditRecord {
+	if event.Actor != nil {
+		user = event.Actor
+	}
+
+	r := &AuditRecord{
+		Time:      clkm.MustGet(ctx).Now(),
+		Action:    event.Action,
+		Target:    event.Target,
+		Outcome:   event.Outcome,
+		TraceLink: traceLink.Serialize(),
+		Metadata:  event.Metadata,
+	}
+
+	if user != nil {
+		r.ActorID = user.ID
+		r.ActorEmail = user.Email
+	}
+
+	return r
+}
+
+// getBaggageUser returns the user set using SetUser, as recorded in the span baggage, or nil.
+func getBaggageUser(baggage Baggage) *User {
+	id, _ := baggage["scope.user"].(string)
+	email, _ := baggage["scope.user.email"].(string)
+
+	if id == "" && email == "" {
+		return nil
+	}
+
+	return &User{
+		ID:    id,
+		Email: email,
+	}
+}
+
+func (bL *backgroundLogImpl) appendAudit(ctx context.Context, rawLog RawLog, event *AuditEvent, user *User, traceLink *TraceLink) {
+	if bL.o.auditLog == nil {
+		rawLog.EmitWarning(ctx, errorz.Errorf("called EmitAudit without an audit log"))
+		return
+	}
+
+	if _, err := bL.o.auditLog.Append(newAuditRecord(ctx, event, user, traceLink)); err != nil {
+		rawLog.EmitError(ctx, errorz.Wrap(err, errorz.Errorf("failed to append audit record")))
+	}
+}
@@ -0,0 +1,323 @@
+package logm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/filez"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/cfgm"
+	"github.com/ibrt/golang-modules/clkm"
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type AuditSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestAuditSuite(t *testing.T) {
+	fixturez.RunSuite(t, &AuditSuite{})
+}
+
+func (s *AuditSuite) newContext(ctx context.Context, g *WithT, auditLog *logm.AuditLog) (context.Context, *tlogm.MockSender, func()) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+
+	return logm.NewSingletonInjector(logm.NewRawLogFromClient(client, logm.RawLogAudit(auditLog)))(ctx), sender, client.Close
+}
+
+func (s *AuditSuite) TestAuditLog(ctx context.Context, g *WithT) {
+	buf := &bytes.Buffer{}
+	ctx, _, closer := s.newContext(ctx, g, logm.NewAuditLog(buf, []byte("key"), nil))
+	defer closer()
+
+	logm.MustGet(ctx).EmitAudit(&logm.AuditEvent{
+		Actor:   &logm.User{ID: "system"},
+		Action:  "rotate-keys",
+		Outcome: logm.AuditOutcomeSuccess,
+	})
+
+	spanCtx, end := logm.MustGet(ctx).Begin("span")
+	logm.MustGet(spanCtx).SetUser(&logm.User{ID: "u1", Email: "u1@example.com"})
+	logm.MustGet(spanCtx).EmitAudit(&logm.AuditEvent{
+		Action:   "delete-document",
+		Target:   "documents/1",
+		Outcome:  logm.AuditOutcomeDenied,
+		Metadata: map[string]any{"reason": "forbidden"},
+	})
+	end()
+
+	last, err := logm.VerifyAuditLog(bytes.NewReader(buf.Bytes()), []byte("key"))
+	g.Expect(err).To(Succeed())
+
+	g.Expect(last).To(PointTo(MatchAllFields(Fields{
+		"Sequence":   Equal(int64(2)),
+		"Time":       Equal(clkm.MustGet(ctx).Now().UTC()),
+		"ActorID":    Equal("u1"),
+		"ActorEmail": Equal("u1@example.com"),
+		"Action":     Equal("delete-document"),
+		"Target":     Equal("documents/1"),
+		"Outcome":    Equal(logm.AuditOutcomeDenied),
+		"TraceLink":  Equal(logm.MustGet(spanCtx).GetCurrentTraceLink().Serialize()),
+		"Metadata":   Equal(map[string]any{"reason": "forbidden"}),
+		"PrevHash":   Not(BeEmpty()),
+		"Hash":       HaveLen(64),
+	})))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	g.Expect(lines).To(HaveLen(2))
+
+	first := &logm.AuditRecord{}
+	g.Expect(json.Unmarshal([]byte(lines[0]), first)).To(Succeed())
+	g.Expect(first).To(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Sequence":  Equal(int64(1)),
+		"ActorID":   Equal("system"),
+		"TraceLink": BeEmpty(),
+		"PrevHash":  BeEmpty(),
+	})))
+	g.Expect(last.PrevHash).To(Equal(first.Hash))
+}
+
+func (s *AuditSuite) TestAuditLog_Disabled(ctx context.Context, g *WithT) {
+	logm.MustGet(ctx).EmitAudit(&logm.AuditEvent{
+		Action:  "action",
+		Outcome: logm.AuditOutcomeSuccess,
+	})
+
+	g.Expect(s.LOG.GetMock()).To(tlogm.HaveEvent("warning", "called EmitAudit without an audit log"))
+}
+
+func (s *AuditSuite) TestVerifyAuditLog(ctx context.Context, g *WithT) {
+	newLines := func() []string {
+		buf := &bytes.Buffer{}
+		a := logm.NewAuditLog(buf, []byte("key"), nil)
+
+		for _, action := range []string{"a1", "a2", "a3"} {
+			_, err := a.Append(&logm.AuditRecord{
+				Time:    clkm.MustGet(ctx).Now(),
+				Action:  action,
+				Outcome: logm.AuditOutcomeSuccess,
+			})
+			g.Expect(err).To(Succeed())
+		}
+
+		return strings.Split(strings.TrimSpace(buf.String()), "\n")
+	}
+
+	verify := func(lines []string, anchors ...*logm.AuditAnchor) error {
+		_, err := logm.VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), []byte("key"), anchors...)
+		return err
+	}
+
+	last, err := logm.VerifyAuditLog(strings.NewReader(""), []byte("key"))
+	g.Expect(err).To(Succeed())
+	g.Expect(last).To(BeNil())
+
+	lines := newLines()
+	g.Expect(verify(lines)).To(Succeed())
+
+	lines = newLines()
+	lines[1] = strings.Replace(lines[1], `"action":"a2"`, `"action":"a4"`, 1)
+	g.Expect(verify(lines)).To(MatchError(ContainSubstring("audit log line 2: hash mismatch")))
+
+	lines = newLines()
+	g.Expect(verify([]string{lines[0], lines[2]})).To(MatchError(ContainSubstring("audit log line 2: expected sequence 2, got 3")))
+	g.Expect(verify(lines[1:])).To(MatchError(ContainSubstring("audit log line 1: expected sequence 1, got 2")))
+
+	lines = newLines()
+	record := &logm.AuditRecord{}
+	g.Expect(json.Unmarshal([]byte(lines[1]), record)).To(Succeed())
+	record.PrevHash = strings.Repeat("0", 64)
+	buf, err := json.Marshal(record)
+	g.Expect(err).To(Succeed())
+	lines[1] = string(buf)
+	g.Expect(verify(lines)).To(MatchError(ContainSubstring("audit log line 2: previous hash mismatch")))
+
+	g.Expect(verify([]string{"{"})).To(MatchError(ContainSubstring("audit log line 1")))
+
+	// Without the key, the chain can't be verified, nor rewritten.
+	lines = newLines()
+	_, err = logm.VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), nil)
+	g.Expect(err).To(MatchError(ContainSubstring("audit log line 1: hash mismatch")))
+
+	unkeyed := &bytes.Buffer{}
+	_, err = logm.NewAuditLog(unkeyed, nil, nil).Append(&logm.AuditRecord{Action: "a1", Outcome: logm.AuditOutcomeSuccess})
+	g.Expect(err).To(Succeed())
+	_, err = logm.VerifyAuditLog(bytes.NewReader(unkeyed.Bytes()), nil)
+	g.Expect(err).To(Succeed())
+	_, err = logm.VerifyAuditLog(bytes.NewReader(unkeyed.Bytes()), []byte("key"))
+	g.Expect(err).To(MatchError(ContainSubstring("audit log line 1: hash mismatch")))
+
+	// Anchors detect truncation.
+	lines = newLines()
+	record = &logm.AuditRecord{}
+	g.Expect(json.Unmarshal([]byte(lines[2]), record)).To(Succeed())
+	anchor := &logm.AuditAnchor{Sequence: record.Sequence, Hash: record.Hash}
+	g.Expect(verify(lines, anchor)).To(Succeed())
+	g.Expect(verify(lines[:2], anchor)).To(MatchError("audit log is missing 1 anchored record(s), it may have been truncated"))
+	g.Expect(verify(lines, &logm.AuditAnchor{Sequence: 2, Hash: record.Hash})).
+		To(MatchError(ContainSubstring("audit log line 2: anchor mismatch")))
+}
+
+func (s *AuditSuite) TestAuditLog_Metadata(ctx context.Context, g *WithT) {
+	type metadata struct {
+		Zebra string `json:"zebra"`
+		Alpha int64  `json:"alpha"`
+	}
+
+	buf := &bytes.Buffer{}
+	a := logm.NewAuditLog(buf, []byte("key"), nil)
+
+	appended, err := a.Append(&logm.AuditRecord{
+		Time:    clkm.MustGet(ctx).Now(),
+		Action:  "action",
+		Outcome: logm.AuditOutcomeSuccess,
+		Metadata: map[string]any{
+			"struct": &metadata{Zebra: "z", Alpha: 1},
+			"large":  int64(1<<53 + 1),
+		},
+	})
+	g.Expect(err).To(Succeed())
+
+	last, err := logm.VerifyAuditLog(bytes.NewReader(buf.Bytes()), []byte("key"))
+	g.Expect(err).To(Succeed())
+	g.Expect(last).To(Equal(appended))
+	g.Expect(last.Metadata).To(Equal(map[string]any{
+		"struct": map[string]any{"zebra": "z", "alpha": json.Number("1")},
+		"large":  json.Number("9007199254740993"),
+	}))
+
+	_, err = a.Append(&logm.AuditRecord{
+		Action:   "action",
+		Outcome:  logm.AuditOutcomeSuccess,
+		Metadata: map[string]any{"invalid": func() {}},
+	})
+	g.Expect(err).To(MatchError(ContainSubstring("unsupported type")))
+	g.Expect(a.GetAnchor().Sequence).To(Equal(int64(1)))
+}
+
+func (s *AuditSuite) TestAuditLog_Anchor(ctx context.Context, g *WithT) {
+	a := logm.NewAuditLog(&bytes.Buffer{}, []byte("key"), nil)
+	g.Expect(a.GetAnchor()).To(BeNil())
+
+	r, err := a.Append(&logm.AuditRecord{Action: "a1", Outcome: logm.AuditOutcomeSuccess})
+	g.Expect(err).To(Succeed())
+	g.Expect(a.GetAnchor()).To(Equal(&logm.AuditAnchor{Sequence: 1, Hash: r.Hash}))
+}
+
+func (s *AuditSuite) TestAuditLog_WriteError(ctx context.Context, g *WithT) {
+	w := &failingWriter{}
+	ctx, sender, closer := s.newContext(ctx, g, logm.NewAuditLog(w, []byte("key"), nil))
+	defer closer()
+
+	g.Expect(func() {
+		logm.MustGet(ctx).EmitAudit(&logm.AuditEvent{Action: "a1", Outcome: logm.AuditOutcomeSuccess})
+	}).ToNot(Panic())
+
+	g.Expect(sender).To(tlogm.HaveEvent("error", "failed to append audit record: write error"))
+
+	// The partial record can't be rolled back, so the log refuses further appends.
+	w.isOK = true
+	logm.MustGet(ctx).EmitAudit(&logm.AuditEvent{Action: "a2", Outcome: logm.AuditOutcomeSuccess})
+	g.Expect(w.n).To(Equal(0))
+	g.Expect(sender).To(tlogm.HaveEvent("error",
+		"failed to append audit record: audit log is in an inconsistent state after a failed write"))
+}
+
+func (s *AuditSuite) TestOpenAuditFile(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	path := filepath.Join(dir, "sub", "audit.jsonl")
+
+	for _, action := range []string{"a1", "a2"} {
+		a := logm.MustNewDefaultAuditLog(cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{
+			AuditFilePath: path,
+		})(ctx))
+		g.Expect(a).ToNot(BeNil())
+
+		_, err := a.Append(&logm.AuditRecord{
+			Time:    clkm.MustGet(ctx).Now(),
+			Action:  action,
+			Outcome: logm.AuditOutcomeSuccess,
+		})
+		g.Expect(err).To(Succeed())
+		g.Expect(a.Close()).To(Succeed())
+	}
+
+	last, err := logm.VerifyAuditLog(strings.NewReader(filez.MustReadFileString(path)), nil)
+	g.Expect(err).To(Succeed())
+	g.Expect(last).To(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Sequence": Equal(int64(2)),
+		"Action":   Equal("a2"),
+	})))
+
+	// A partially written record is discarded when the file is reopened.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	g.Expect(err).To(Succeed())
+	_, err = f.WriteString(`{"sequence":3,"act`)
+	g.Expect(err).To(Succeed())
+	g.Expect(f.Close()).To(Succeed())
+
+	a, err := logm.OpenAuditFile(path, nil)
+	g.Expect(err).To(Succeed())
+	g.Expect(a.GetAnchor()).To(PointTo(HaveField("Sequence", int64(2))))
+	_, err = a.Append(&logm.AuditRecord{Action: "a3", Outcome: logm.AuditOutcomeSuccess})
+	g.Expect(err).To(Succeed())
+	g.Expect(a.Close()).To(Succeed())
+
+	last, err = logm.VerifyAuditLog(strings.NewReader(filez.MustReadFileString(path)), nil)
+	g.Expect(err).To(Succeed())
+	g.Expect(last.Sequence).To(Equal(int64(3)))
+
+	filez.MustWriteFile(path, 0700, 0600, []byte("{}\n"))
+	_, err = logm.OpenAuditFile(path, nil)
+	g.Expect(err).To(MatchError(ContainSubstring("audit log line 1: expected sequence 1, got 0")))
+
+	g.Expect(logm.MustNewDefaultAuditLog(cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{
+		AuditFilePath: cfgm.DisabledValue,
+	})(ctx))).To(BeNil())
+}
+
+func (s *AuditSuite) TestAuditOutcome(g *WithT) {
+	o := logm.AuditOutcome("")
+	g.Expect(o.UnmarshalText([]byte("denied"))).To(Succeed())
+	g.Expect(o).To(Equal(logm.AuditOutcomeDenied))
+	g.Expect(o.String()).To(Equal("denied"))
+	g.Expect(o.UnmarshalText([]byte("invalid"))).To(MatchError("invalid value for AuditOutcome: 'invalid'"))
+}
+
+type failingWriter struct {
+	isOK bool
+	n    int
+}
+
+// Write implements the [io.Writer] interface.
+func (w *failingWriter) Write(buf []byte) (int, error) {
+	if !w.isOK {
+		return 0, errorz.Errorf("write error")
+	}
+
+	w.n += len(buf)
+	return len(buf), nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/honeycombio/libhoney-go"
 	"github.com/ibrt/golang-utils/errorz"
 	"github.com/ibrt/golang-utils/idz"
+	"github.com/ibrt/golang-utils/memz"
 
 	"github.com/ibrt/golang-modules/clkm"
 )
@@ -16,22 +17,37 @@ var (
 )
 
 type backgroundLogImpl struct {
-	client *libhoney.Client
+	client  *libhoney.Client
+	o       *rawLogOptions
+	errAgg  *errorAggregator
+	limiter *rateLimiter
+	refs    *errorReferences
+	tracker *spanTracker
 }
 
 // EmitDebug implements the [RawLog] interface.
 func (bL *backgroundLogImpl) EmitDebug(ctx context.Context, format string, options ...EmitOption) {
 	o := newEmitOptions(options...)
+
+	if !bL.limiter.allow(ctx, "debug", o) {
+		return
+	}
+
 	e := newAttachableEvent(ctx, bL.client, "", "debug")
-	addDebugFields(e, format, o)
+	addDebugFields(e, bL.o.payloadLimits, format, o)
 	errorz.MaybeMustWrap(e.Send())
 }
 
 // EmitInfo implements the [RawLog] interface.
 func (bL *backgroundLogImpl) EmitInfo(ctx context.Context, format string, options ...EmitOption) {
 	o := newEmitOptions(options...)
+
+	if !bL.limiter.allow(ctx, "info", o) {
+		return
+	}
+
 	e := newAttachableEvent(ctx, bL.client, "", "info")
-	addInfoFields(e, format, o)
+	addInfoFields(e, bL.o.payloadLimits, format, o)
 	errorz.MaybeMustWrap(e.Send())
 }
 
@@ -39,15 +55,23 @@ func (bL *backgroundLogImpl) EmitInfo(ctx context.Context, format string, option
 func (bL *backgroundLogImpl) EmitWarning(ctx context.Context, err error) {
 	maybeSetIsEmitted(err)
 	e := newAttachableEvent(ctx, bL.client, "", "warning")
-	addWarningFields(e, err)
+	addWarningFields(e, bL.o.payloadLimits, err)
 	errorz.MaybeMustWrap(e.Send())
 }
 
 // EmitError implements the [RawLog] interface.
 func (bL *backgroundLogImpl) EmitError(ctx context.Context, err error) {
 	maybeSetIsEmitted(err)
+	ref := maybeSetErrorReference(err)
+	bL.refs.add(ref, nil)
+
+	if !bL.errAgg.allow(ctx, err) {
+		return
+	}
+
 	e := newAttachableEvent(ctx, bL.client, "", "error")
-	addErrorFields(e, err)
+	addErrorFields(e, bL.o.payloadLimits, err)
+	e.AddField("error.reference", ref)
 	errorz.MaybeMustWrap(e.Send())
 }
 
@@ -56,6 +80,12 @@ func (bL *backgroundLogImpl) EmitTraceLink(ctx context.Context, _ *TraceLink) {
 	bL.EmitWarning(ctx, errorz.Errorf("called EmitTraceLink in background Log"))
 }
 
+// EmitAudit implements the [RawLog] interface. In the background log, the actor must be set explicitly and the record
+// has no trace link.
+func (bL *backgroundLogImpl) EmitAudit(ctx context.Context, event *AuditEvent) {
+	bL.appendAudit(ctx, bL, event, nil, nil)
+}
+
 // Begin implements the [RawLog] interface.
 func (bL *backgroundLogImpl) Begin(ctx context.Context, name string, options ...BeginOption) (context.Context, func()) {
 	o := newBeginOptions(options...)
@@ -70,14 +100,24 @@ func (bL *backgroundLogImpl) Begin(ctx context.Context, name string, options ...
 		spanID:       idz.MustNewRandomUUID(),
 		metadata:     o.metadata,
 		errMetadata:  o.errMetadata,
+		attributes:   map[string]any{},
+		baggage:      Baggage{},
+		kind:         o.kind,
 		hasErrorFlag: false,
+		bL:           bL,
 	}
 
 	sL.b.AddField("trace.trace_id", sL.traceID)
+	sL.captureDeadline(ctx)
+	sL.restoreBaggage(o.baggage)
+	sL.emitLinks(ctx, o.links)
 	ctx = NewSingletonInjector(sL)(ctx)
+	sL.SetAttributes(ctx, o.attributes...)
+	bL.tracker.track(ctx, sL, nil)
+	sL.startWatchdog(ctx, memz.Ternary(o.budget > 0, o.budget, bL.o.spanBudget))
 
 	return ctx, func() {
-		sL.end(ctx)
+		sL.end(ctx, false)
 	}
 }
 
@@ -101,17 +141,68 @@ func (bL *backgroundLogImpl) SetErrorMetadataKey(ctx context.Context, _ string,
 	bL.EmitWarning(ctx, errorz.Errorf("called SetErrorMetadataKey in background Log"))
 }
 
+// SetAttributes implements the [RawLog] interface.
+func (bL *backgroundLogImpl) SetAttributes(ctx context.Context, _ ...*Attribute) {
+	bL.EmitWarning(ctx, errorz.Errorf("called SetAttributes in background Log"))
+}
+
+// SetPropagatingAttributes implements the [RawLog] interface.
+func (bL *backgroundLogImpl) SetPropagatingAttributes(ctx context.Context, _ ...*Attribute) {
+	bL.EmitWarning(ctx, errorz.Errorf("called SetPropagatingAttributes in background Log"))
+}
+
+// validateAttributes returns the attributes accepted by the registry, and the validation errors.
+func (bL *backgroundLogImpl) validateAttributes(attrs []*Attribute) ([]*Attribute, []error) {
+	valid := make([]*Attribute, 0, len(attrs))
+	errs := make([]error, 0)
+
+	for _, attr := range attrs {
+		if attr == nil {
+			continue
+		}
+
+		ok, err := bL.o.attrRegistry.Validate(attr, bL.o.attrMode)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		if ok {
+			valid = append(valid, attr)
+		}
+	}
+
+	return valid, errs
+}
+
 // SetErrorFlag implements the [RawLog] interface.
 func (bL *backgroundLogImpl) SetErrorFlag(ctx context.Context) {
 	bL.EmitWarning(ctx, errorz.Errorf("called SetErrorFlag in background Log"))
 }
 
+// SetStatus implements the [RawLog] interface.
+func (bL *backgroundLogImpl) SetStatus(ctx context.Context, _ SpanStatus, _ string) {
+	bL.EmitWarning(ctx, errorz.Errorf("called SetStatus in background Log"))
+}
+
 // GetCurrentTraceLink implements the [RawLog] interface.
 func (bL *backgroundLogImpl) GetCurrentTraceLink(_ context.Context) *TraceLink {
 	return nil
 }
 
+// GetCurrentBaggage implements the [RawLog] interface.
+func (bL *backgroundLogImpl) GetCurrentBaggage(_ context.Context) Baggage {
+	return nil
+}
+
+// stopSweeping stops the goroutines that sweep expired error dedup and rate limit windows, when the log is released.
+func (bL *backgroundLogImpl) stopSweeping() {
+	bL.errAgg.stop()
+	bL.limiter.stop()
+}
+
 // Flush implements the [RawLog] interface.
-func (bL *backgroundLogImpl) Flush(_ context.Context) {
+func (bL *backgroundLogImpl) Flush(ctx context.Context) {
+	bL.errAgg.flush(ctx)
+	bL.limiter.flush(ctx)
 	bL.client.Flush()
 }
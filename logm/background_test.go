@@ -247,6 +247,25 @@ func (s *BackgroundSuite) TestBackgroundSetErrorFlag(ctx context.Context, g *Wit
 			}))))
 }
 
+func (s *BackgroundSuite) TestBackgroundSetStatus(ctx context.Context, g *WithT) {
+	logm.MustGet(ctx).SetStatus(logm.SpanStatusError, "message")
+
+	g.Expect(s.LOG.GetMock().GetEvents()).
+		To(HaveExactElements(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Timestamp": Equal(clkm.MustGet(ctx).Now()),
+				"Data": And(
+					HaveKeyWithValue("warning", "generic"),
+					HaveKeyWithValue("warning.message", "called SetStatus in background Log"),
+				),
+			}))))
+}
+
 func (s *BackgroundSuite) TestBackgroundGetCurrentTraceLink(ctx context.Context, g *WithT) {
 	g.Expect(logm.MustGet(ctx).GetCurrentTraceLink()).To(BeNil())
 }
+
+func (s *BackgroundSuite) TestMaybeGet(ctx context.Context, g *WithT) {
+	g.Expect(logm.MaybeGet(ctx)).ToNot(BeNil())
+	g.Expect(logm.MaybeGet(context.Background())).To(BeNil())
+}
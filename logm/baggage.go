@@ -0,0 +1,179 @@
+package logm
+
+import (
+	"encoding/json"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Baggage-related constants.
+const (
+	// BaggageHeaderKey is the conventional key for serialized [Baggage] in HTTP headers and message metadata. It is
+	// distinct from the W3C "baggage" header, as values are encoded as JSON, so that the two can coexist.
+	BaggageHeaderKey = "X-Logm-Baggage"
+	// DefaultBaggageMaxBytes is the default maximum size of serialized [Baggage].
+	DefaultBaggageMaxBytes = 8192
+)
+
+var (
+	_ AddField = (Baggage)(nil)
+)
+
+var (
+	reservedBaggageKeyPrefixes = []string{
+		"trace.",
+		"meta.",
+		"location.",
+		"debug.",
+		"info.",
+		"warning.",
+		"error.",
+	}
+)
+
+// Baggage describes the propagating fields of a span, i.e. the fields set by [Log.SetUser],
+// [Log.SetPropagatingField] and [SetPropagatingAttributes]. It is obtained using [Log.GetCurrentBaggage], and
+// restored on the receiving side of a process boundary using [BeginBaggage].
+type Baggage map[string]any
+
+// AddField implements the [AddField] interface.
+func (b Baggage) AddField(k string, v any) {
+	b[k] = v
+}
+
+// Serialize the [Baggage], using a format similar to (but not compatible with) the W3C baggage header: comma-separated,
+// URL-escaped key-value pairs, with values encoded as JSON. Keys are sorted. Values that cannot be encoded as JSON are
+// skipped.
+func (b Baggage) Serialize() string {
+	parts := make([]string, 0, len(b))
+
+	for _, k := range slices.Sorted(maps.Keys(b)) {
+		if part, ok := serializeBaggageEntry(k, b[k]); ok {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// MaybeParseBaggage parses a serialized [Baggage]. It returns nil if the baggage is empty or invalid.
+func MaybeParseBaggage(baggage string) Baggage {
+	if baggage == "" {
+		return nil
+	}
+
+	b := Baggage{}
+
+	for _, part := range strings.Split(baggage, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil
+		}
+
+		k, err := url.QueryUnescape(k)
+		if err != nil || k == "" {
+			return nil
+		}
+
+		v, err = url.QueryUnescape(v)
+		if err != nil {
+			return nil
+		}
+
+		var value any
+		if err := json.Unmarshal([]byte(v), &value); err != nil {
+			return nil
+		}
+
+		b[k] = value
+	}
+
+	return b
+}
+
+func serializeBaggageEntry(k string, v any) (string, bool) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+
+	return url.QueryEscape(k) + "=" + url.QueryEscape(string(buf)), true
+}
+
+// limitBaggage returns a copy of the baggage containing only the allowed keys (all non-reserved keys if the allowlist
+// is empty), skipping entries that would make the serialized size exceed maxBytes.
+func limitBaggage(b Baggage, allowlist []string, maxBytes int) Baggage {
+	if len(b) == 0 {
+		return nil
+	}
+
+	limited := Baggage{}
+	size := 0
+
+	for _, k := range slices.Sorted(maps.Keys(b)) {
+		if !isBaggageKeyAllowed(k, allowlist) {
+			continue
+		}
+
+		part, ok := serializeBaggageEntry(k, b[k])
+		if !ok {
+			continue
+		}
+
+		partSize := len(part)
+		if size > 0 {
+			partSize++
+		}
+
+		if maxBytes > 0 && size+partSize > maxBytes {
+			continue
+		}
+
+		limited[k] = b[k]
+		size += partSize
+	}
+
+	if len(limited) == 0 {
+		return nil
+	}
+
+	return limited
+}
+
+func isBaggageKeyAllowed(k string, allowlist []string) bool {
+	// Keys without a namespace and reserved namespaces are never allowed, so that baggage can't override the fields
+	// used to build traces and events.
+	if !strings.Contains(k, ".") || slices.ContainsFunc(reservedBaggageKeyPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(k, prefix)
+	}) {
+		return false
+	}
+
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(allowlist, func(pattern string) bool {
+		matched, err := path.Match(pattern, k)
+		return err == nil && matched
+	})
+}
+
+// isBaggageKeyRestorable returns true if the key can be restored from inbound baggage. Inbound baggage is untrusted, so
+// nothing is restored if the allowlist is empty. The user fields set by [Log.SetUser] are also the actor of audit
+// records, so they are only trusted if the allowlist contains them verbatim (i.e. not through a wildcard), otherwise any
+// caller could impersonate any user.
+func isBaggageKeyRestorable(k string, allowlist []string) bool {
+	if len(allowlist) == 0 {
+		return false
+	}
+
+	if k == "scope.user" || strings.HasPrefix(k, "scope.user.") {
+		return slices.Contains(allowlist, k)
+	}
+
+	return true
+}
@@ -0,0 +1,190 @@
+package logm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type BaggageSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestBaggageSuite(t *testing.T) {
+	fixturez.RunSuite(t, &BaggageSuite{})
+}
+
+func (s *BaggageSuite) TestSerialize(g *WithT) {
+	b := logm.Baggage{
+		"scope.tenant_id": "t 1",
+		"scope.count":     float64(2),
+		"http.route":      "/a,b=c",
+		"invalid.value":   func() {},
+	}
+
+	serialized := b.Serialize()
+	g.Expect(serialized).To(Equal("http.route=%22%2Fa%2Cb%3Dc%22,scope.count=2,scope.tenant_id=%22t+1%22"))
+	g.Expect(logm.MaybeParseBaggage(serialized)).To(Equal(logm.Baggage{
+		"scope.tenant_id": "t 1",
+		"scope.count":     float64(2),
+		"http.route":      "/a,b=c",
+	}))
+
+	g.Expect(logm.Baggage{}.Serialize()).To(BeEmpty())
+	g.Expect(logm.MaybeParseBaggage("")).To(BeNil())
+	g.Expect(logm.MaybeParseBaggage("k")).To(BeNil())
+	g.Expect(logm.MaybeParseBaggage("=1")).To(BeNil())
+	g.Expect(logm.MaybeParseBaggage("k=%zz")).To(BeNil())
+	g.Expect(logm.MaybeParseBaggage("k=invalid")).To(BeNil())
+}
+
+func (s *BaggageSuite) TestBackground(ctx context.Context, g *WithT) {
+	g.Expect(logm.MustGet(ctx).GetCurrentBaggage()).To(BeNil())
+}
+
+func (s *BaggageSuite) TestPropagation(ctx context.Context, g *WithT) {
+	var serialized string
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("sender")
+		defer end()
+
+		g.Expect(logm.MustGet(ctx).GetCurrentBaggage()).To(BeNil())
+
+		logm.MustGet(ctx).SetUser(&logm.User{ID: "u1", Email: "u1@example.com"})
+		logm.MustGet(ctx).SetPropagatingField("tenant_id", "t1")
+		logm.SetPropagatingAttributes(ctx, logm.AttrHTTPRoute.V("/route"))
+		logm.MustGet(ctx).SetMetadataKey("k", "v")
+
+		ctx, end = logm.MustGet(ctx).Begin("child")
+		defer end()
+
+		serialized = logm.MustGet(ctx).GetCurrentBaggage().Serialize()
+	}()
+
+	g.Expect(logm.MaybeParseBaggage(serialized)).To(Equal(logm.Baggage{
+		"scope.user":       "u1",
+		"scope.user.email": "u1@example.com",
+		"scope.tenant_id":  "t1",
+		"http.route":       "/route",
+	}))
+
+	s.LOG.GetMock().ClearEvents()
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("receiver", logm.BeginBaggage(logm.MaybeParseBaggage(serialized)))
+		defer end()
+
+		ctx, end = logm.MustGet(ctx).Begin("child")
+		defer end()
+
+		// Inbound baggage is not trusted unless explicitly allowlisted (see TestRestoreUser).
+		g.Expect(logm.MustGet(ctx).GetCurrentBaggage()).To(BeEmpty())
+	}()
+
+	g.Expect(s.LOG.GetMock().GetEvents()).To(HaveEach(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": And(
+				Not(HaveKey("scope.user")),
+				Not(HaveKey("scope.user.email")),
+				Not(HaveKey("scope.tenant_id")),
+				Not(HaveKey("http.route"))),
+		}))))
+}
+
+func (s *BaggageSuite) TestLimits(ctx context.Context, g *WithT) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	ctx = logm.NewSingletonInjector(logm.NewRawLogFromClient(client,
+		logm.RawLogBaggage([]string{"scope.tenant_*", "scope.request_id"}, 64)))(ctx)
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("span", logm.BeginBaggage(logm.Baggage{
+			"scope.tenant_id":    "t1",
+			"scope.tenant_name":  "a tenant with a very long name",
+			"scope.request_id":   "r1",
+			"scope.other":        "o1",
+			"trace.trace_id":     "overridden",
+			"name":               "overridden",
+			"error.message":      "overridden",
+			"meta.annotation_id": "overridden",
+		}))
+		defer end()
+
+		g.Expect(logm.MustGet(ctx).GetCurrentBaggage()).To(Equal(logm.Baggage{
+			"scope.tenant_id":  "t1",
+			"scope.request_id": "r1",
+		}))
+	}()
+
+	g.Expect(sender.GetEvents()).To(HaveExactElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": And(
+				HaveKeyWithValue("name", "span"),
+				HaveKeyWithValue("scope.tenant_id", "t1"),
+				HaveKeyWithValue("scope.request_id", "r1"),
+				Not(HaveKey("scope.tenant_name")),
+				Not(HaveKey("scope.other")),
+				Not(HaveKey("error.message")),
+				Not(HaveKey("meta.annotation_id")),
+				Not(HaveKeyWithValue("trace.trace_id", "overridden"))),
+		}))))
+}
+
+func (s *BaggageSuite) TestRestoreUser(ctx context.Context, g *WithT) {
+	inbound := logm.Baggage{
+		"scope.user":       "u1",
+		"scope.user.email": "u1@example.com",
+		"scope.tenant_id":  "t1",
+	}
+
+	for allowlist, expected := range map[string]logm.Baggage{
+		"": nil,
+		"scope.*": {
+			"scope.tenant_id": "t1",
+		},
+		"scope.*,scope.user": {
+			"scope.user":      "u1",
+			"scope.tenant_id": "t1",
+		},
+		"scope.*,scope.user,scope.user.email": inbound,
+	} {
+		client, err := libhoney.NewClient(libhoney.ClientConfig{
+			APIKey:       "test-honeycomb-api-key",
+			Dataset:      "test-dataset",
+			SampleRate:   1,
+			Transmission: tlogm.NewMockSender(),
+		})
+		g.Expect(err).To(Succeed())
+
+		ctx := logm.NewSingletonInjector(logm.NewRawLogFromClient(client,
+			logm.RawLogBaggage(strings.FieldsFunc(allowlist, func(r rune) bool { return r == ',' }), 0)))(ctx)
+
+		func() {
+			ctx, end := logm.MustGet(ctx).Begin("span", logm.BeginBaggage(inbound))
+			defer end()
+			g.Expect(logm.MustGet(ctx).GetCurrentBaggage()).To(Equal(expected), allowlist)
+		}()
+
+		client.Close()
+	}
+}
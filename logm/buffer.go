@@ -0,0 +1,729 @@
+package logm
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+var (
+	_ transmission.Sender = (*DiskBufferSender)(nil)
+)
+
+const (
+	diskBufferSegmentExt      = ".seg"
+	diskBufferRecordHeaderLen = 8
+	diskBufferResponsesLen    = 1024
+)
+
+// Default values for [DiskBufferConfig].
+const (
+	DefaultDiskBufferMaxBytes      int64 = 256 * 1024 * 1024
+	DefaultDiskBufferQueueSize           = 1000
+	DefaultDiskBufferRetryInterval       = 10 * time.Second
+)
+
+// DiskBufferConfig describes the configuration for a [*DiskBufferSender].
+type DiskBufferConfig struct {
+	// Dir is the directory where segment files are stored.
+	Dir string
+	// MaxBytes is the maximum size of the backlog on disk, oldest segments are dropped past it.
+	MaxBytes int64
+	// SegmentMaxBytes is the size after which a new segment file is started. Defaults to MaxBytes / 16.
+	SegmentMaxBytes int64
+	// QueueSize is the maximum number of events waiting to be forwarded to the wrapped sender, past which events are
+	// spilled (e.g. while the wrapped sender blocks at the start of an outage).
+	QueueSize int
+	// RetryInterval is how often the wrapped sender is retried after a failure.
+	RetryInterval time.Duration
+}
+
+// DiskBufferStats describes the state of a [*DiskBufferSender].
+type DiskBufferStats struct {
+	Healthy         bool
+	BacklogSegments int
+	BacklogEvents   int
+	BacklogBytes    int64
+	PendingEvents   int
+	SpilledEvents   int64
+	ReplayedEvents  int64
+	DroppedEvents   int64
+	RejectedEvents  int64
+	CorruptRecords  int64
+}
+
+type diskBufferMetadata struct {
+	id       uint64
+	metadata any
+	segment  *diskBufferSegment
+}
+
+type diskBufferSegment struct {
+	seq      uint64
+	path     string
+	size     int64
+	count    int
+	inflight int
+}
+
+type diskBufferRecord struct {
+	APIKey     string         `json:"apiKey,omitempty"`
+	Dataset    string         `json:"dataset,omitempty"`
+	SampleRate uint           `json:"sampleRate,omitempty"`
+	APIHost    string         `json:"apiHost,omitempty"`
+	Timestamp  time.Time      `json:"timestamp"`
+	Data       map[string]any `json:"data"`
+}
+
+// DiskBufferSender is a [transmission.Sender] decorator that spills events to a bounded on-disk queue while the
+// wrapped sender is failing, and replays them once it recovers. Events are forwarded on a background goroutine, so
+// that callers don't block on the wrapped sender. Replayed segments are only deleted once all their events are either
+// delivered, rejected, or spilled again, so an event may be delivered twice after a crash, but is never lost.
+type DiskBufferSender struct {
+	clk          clkm.Clock
+	cfg          DiskBufferConfig
+	s            transmission.Sender
+	c            chan transmission.Response
+	m            *sync.Mutex
+	wg           *sync.WaitGroup
+	addC         chan *transmission.Event
+	replayC      chan struct{}
+	stopC        chan struct{}
+	doneC        chan struct{}
+	forwardDoneC chan struct{}
+	drainC       chan struct{}
+	pending      map[uint64]*transmission.Event
+	nextID       uint64
+	nextSeq      uint64
+	segments     []*diskBufferSegment
+	tail         *os.File
+	healthy      bool
+	failedAt     time.Time
+	stats        DiskBufferStats
+}
+
+// NewDiskBufferSender initializes a new [*DiskBufferSender] wrapping the given [transmission.Sender].
+func NewDiskBufferSender(ctx context.Context, sender transmission.Sender, cfg *DiskBufferConfig) *DiskBufferSender {
+	errorz.Assertf(sender != nil, "sender is nil")
+	errorz.Assertf(cfg != nil && cfg.Dir != "", "cfg.Dir is empty")
+
+	s := &DiskBufferSender{
+		clk:     clkm.MustGet(ctx),
+		cfg:     *cfg,
+		s:       sender,
+		c:       make(chan transmission.Response, diskBufferResponsesLen),
+		m:       &sync.Mutex{},
+		wg:      &sync.WaitGroup{},
+		pending: make(map[uint64]*transmission.Event),
+		nextSeq: 1,
+		healthy: true,
+	}
+
+	if s.cfg.MaxBytes <= 0 {
+		s.cfg.MaxBytes = DefaultDiskBufferMaxBytes
+	}
+
+	if s.cfg.SegmentMaxBytes <= 0 || s.cfg.SegmentMaxBytes > s.cfg.MaxBytes {
+		s.cfg.SegmentMaxBytes = s.cfg.MaxBytes / 16
+	}
+
+	if s.cfg.QueueSize <= 0 {
+		s.cfg.QueueSize = DefaultDiskBufferQueueSize
+	}
+
+	if s.cfg.RetryInterval <= 0 {
+		s.cfg.RetryInterval = DefaultDiskBufferRetryInterval
+	}
+
+	return s
+}
+
+// GetStats returns the current stats.
+func (s *DiskBufferSender) GetStats() *DiskBufferStats {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	stats := s.stats
+	stats.Healthy = s.healthy
+	stats.BacklogSegments = len(s.segments)
+	stats.PendingEvents = len(s.pending)
+
+	for _, segment := range s.segments {
+		stats.BacklogEvents += segment.count
+		stats.BacklogBytes += segment.size
+	}
+
+	return &stats
+}
+
+// Add implements the [transmission.Sender] interface. Events added while the sender is not running are spilled, so
+// that they are replayed on the next start.
+func (s *DiskBufferSender) Add(e *transmission.Event) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if !s.healthy {
+		s.spill(e)
+		return
+	}
+
+	e = s.track(e, nil)
+
+	select {
+	case s.addC <- e:
+	default:
+		// the queue is full (e.g. the wrapped sender is blocked), or the sender is not running
+		s.untrackAndSpill(e)
+	}
+}
+
+// Start implements the [transmission.Sender] interface.
+func (s *DiskBufferSender) Start() error {
+	if err := s.load(); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	if err := s.s.Start(); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	s.m.Lock()
+	s.addC = make(chan *transmission.Event, s.cfg.QueueSize)
+	s.m.Unlock()
+
+	s.replayC = make(chan struct{}, 1)
+	s.stopC = make(chan struct{})
+	s.doneC = make(chan struct{})
+	s.forwardDoneC = make(chan struct{})
+	s.drainC = make(chan struct{})
+	s.wg.Add(1)
+
+	go s.receive(s.s.TxResponses())
+	go s.run(s.clk.Ticker(s.cfg.RetryInterval))
+	go s.forwardQueued()
+
+	s.signalReplay()
+	return nil
+}
+
+// Stop implements the [transmission.Sender] interface. Pending events are spilled, so that they are replayed on the
+// next start. If the sender was not started, it only closes the segment spilled to by Add, if any.
+func (s *DiskBufferSender) Stop() error {
+	if s.stopC == nil {
+		s.m.Lock()
+		defer s.m.Unlock()
+		s.closeTail()
+		return nil
+	}
+
+	close(s.stopC)
+	<-s.doneC
+	<-s.forwardDoneC
+
+	stopErr := s.s.Stop()
+	close(s.drainC)
+	s.wg.Wait()
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.addC = nil
+
+	for _, e := range s.pending {
+		s.untrackAndSpill(e)
+	}
+
+	var closeErr error
+
+	if s.tail != nil {
+		closeErr = s.tail.Close()
+		s.tail = nil
+	}
+
+	close(s.c)
+
+	if stopErr != nil {
+		return errorz.Wrap(stopErr)
+	}
+
+	return errorz.MaybeWrap(closeErr)
+}
+
+// Flush implements the [transmission.Sender] interface. It forwards the queued events before flushing the wrapped
+// sender.
+func (s *DiskBufferSender) Flush() error {
+	s.m.Lock()
+	addC := s.addC
+	s.m.Unlock()
+
+	for {
+		select {
+		case e := <-addC:
+			s.forward(e)
+		default:
+			return errorz.MaybeWrap(s.s.Flush())
+		}
+	}
+}
+
+// TxResponses implements the [transmission.Sender] interface.
+func (s *DiskBufferSender) TxResponses() chan transmission.Response {
+	return s.c
+}
+
+// SendResponse implements the [transmission.Sender] interface.
+func (s *DiskBufferSender) SendResponse(response transmission.Response) bool {
+	select {
+	case s.c <- response:
+		return false
+	default:
+		return true
+	}
+}
+
+// track records the event as pending until the wrapped sender responds. If the event is replayed, segment is the
+// segment it was read from, which is deleted once all its events are resolved (see release).
+func (s *DiskBufferSender) track(e *transmission.Event, segment *diskBufferSegment) *transmission.Event {
+	s.nextID++
+	s.pending[s.nextID] = e
+
+	e.Metadata = &diskBufferMetadata{
+		id:       s.nextID,
+		metadata: e.Metadata,
+		segment:  segment,
+	}
+
+	return e
+}
+
+// untrackAndSpill spills a pending event, e.g. because the wrapped sender failed to deliver it.
+func (s *DiskBufferSender) untrackAndSpill(e *transmission.Event) {
+	md := e.Metadata.(*diskBufferMetadata)
+	delete(s.pending, md.id)
+	e.Metadata = md.metadata
+	s.spill(e)
+	s.release(md.segment)
+}
+
+// release deletes the given replayed segment once all its events are resolved.
+func (s *DiskBufferSender) release(segment *diskBufferSegment) {
+	if segment == nil {
+		return
+	}
+
+	if segment.inflight--; segment.inflight <= 0 {
+		_ = os.Remove(segment.path)
+	}
+}
+
+// forwardQueued forwards the events queued by Add until the sender is stopped.
+func (s *DiskBufferSender) forwardQueued() {
+	defer close(s.forwardDoneC)
+
+	for {
+		select {
+		case <-s.stopC:
+			return
+		case e := <-s.addC:
+			s.forward(e)
+		}
+	}
+}
+
+// forward forwards a pending event to the wrapped sender, unless it became unhealthy in the meantime.
+func (s *DiskBufferSender) forward(e *transmission.Event) {
+	s.m.Lock()
+
+	if !s.healthy {
+		defer s.m.Unlock()
+		s.untrackAndSpill(e)
+		return
+	}
+
+	s.m.Unlock()
+	s.s.Add(e)
+}
+
+// receive consumes the responses of the wrapped sender. Once the wrapped sender is stopped, it drains the responses
+// still buffered and returns, even if the wrapped sender does not close its channel.
+func (s *DiskBufferSender) receive(c chan transmission.Response) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case r, ok := <-c:
+			if !ok {
+				return
+			}
+			s.receiveOne(r)
+		case <-s.drainC:
+			for {
+				select {
+				case r, ok := <-c:
+					if !ok {
+						return
+					}
+					s.receiveOne(r)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *DiskBufferSender) receiveOne(r transmission.Response) {
+	md, ok := r.Metadata.(*diskBufferMetadata)
+	if !ok {
+		s.SendResponse(r)
+		return
+	}
+
+	r.Metadata = md.metadata
+
+	if s.handleResponse(md, r) {
+		s.SendResponse(r)
+	}
+}
+
+func (s *DiskBufferSender) handleResponse(md *diskBufferMetadata, r transmission.Response) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	e, ok := s.pending[md.id]
+
+	if isRetriableResponse(r) {
+		if ok {
+			s.healthy = false
+			s.failedAt = s.clk.Now()
+			s.untrackAndSpill(e)
+			return false
+		}
+		return true
+	}
+
+	if ok {
+		delete(s.pending, md.id)
+		s.release(md.segment)
+	}
+
+	// Other failures are specific to the event (e.g. it exceeds the maximum size, or cannot be marshaled): it is not
+	// spilled, as replaying it would fail again, and they say nothing about the health of the wrapped sender.
+	if r.Err != nil || r.StatusCode >= http.StatusBadRequest {
+		s.stats.RejectedEvents++
+		return true
+	}
+
+	if !s.healthy || len(s.segments) > 0 {
+		s.healthy = true
+		s.signalReplay()
+	}
+
+	return true
+}
+
+func (s *DiskBufferSender) run(ticker *clock.Ticker) {
+	defer close(s.doneC)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopC:
+			return
+		case <-ticker.C:
+			s.m.Lock()
+			if !s.healthy && s.clk.Since(s.failedAt) >= s.cfg.RetryInterval {
+				s.healthy = true
+			}
+			s.m.Unlock()
+			s.replay()
+		case <-s.replayC:
+			s.replay()
+		}
+	}
+}
+
+func (s *DiskBufferSender) signalReplay() {
+	select {
+	case s.replayC <- struct{}{}:
+	default:
+	}
+}
+
+func (s *DiskBufferSender) replay() {
+	s.m.Lock()
+
+	if !s.healthy || len(s.segments) == 0 {
+		s.m.Unlock()
+		return
+	}
+
+	segment := s.segments[0]
+	s.segments = s.segments[1:]
+
+	if len(s.segments) == 0 {
+		s.closeTail()
+	}
+
+	records, _, corrupt := readDiskBufferSegment(segment.path, s.cfg.MaxBytes)
+
+	if n := segment.count - len(records); n > 0 {
+		s.stats.DroppedEvents += int64(n)
+		s.stats.CorruptRecords += corrupt
+	}
+
+	// the segment is deleted once all its events are resolved, see release
+	segment.inflight = len(records)
+	if segment.inflight == 0 {
+		_ = os.Remove(segment.path)
+	}
+
+	events := make([]*transmission.Event, 0, len(records))
+
+	for _, record := range records {
+		events = append(events, s.track(&transmission.Event{
+			APIKey:     record.APIKey,
+			Dataset:    record.Dataset,
+			SampleRate: record.SampleRate,
+			APIHost:    record.APIHost,
+			Timestamp:  record.Timestamp,
+			Data:       record.Data,
+		}, segment))
+	}
+
+	s.stats.ReplayedEvents += int64(len(events))
+	s.m.Unlock()
+
+	for _, e := range events {
+		s.forward(e)
+	}
+
+	s.signalReplay()
+}
+
+func (s *DiskBufferSender) spill(e *transmission.Event) {
+	buf, err := json.Marshal(&diskBufferRecord{
+		APIKey:     e.APIKey,
+		Dataset:    e.Dataset,
+		SampleRate: e.SampleRate,
+		APIHost:    e.APIHost,
+		Timestamp:  e.Timestamp,
+		Data:       e.Data,
+	})
+	if err != nil {
+		s.stats.DroppedEvents++
+		return
+	}
+
+	if err := s.maybeOpenTail(); err != nil {
+		s.stats.DroppedEvents++
+		return
+	}
+
+	header := make([]byte, diskBufferRecordHeaderLen)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(buf)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(buf))
+
+	if _, err := s.tail.Write(append(header, buf...)); err != nil {
+		s.stats.DroppedEvents++
+		return
+	}
+
+	segment := s.segments[len(s.segments)-1]
+	segment.size += int64(diskBufferRecordHeaderLen + len(buf))
+	segment.count++
+	s.stats.SpilledEvents++
+
+	if segment.size >= s.cfg.SegmentMaxBytes {
+		s.closeTail()
+	}
+
+	s.maybeDropOldest()
+}
+
+func (s *DiskBufferSender) maybeOpenTail() error {
+	if s.tail != nil {
+		return nil
+	}
+
+	// segments being replayed are no longer in s.segments, but their files may still exist
+	seq := s.nextSeq
+	s.nextSeq++
+
+	segment := &diskBufferSegment{
+		seq:  seq,
+		path: filepath.Join(s.cfg.Dir, fmt.Sprintf("%020d%v", seq, diskBufferSegmentExt)),
+	}
+
+	f, err := os.OpenFile(segment.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_EXCL, 0600)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+
+	s.tail = f
+	s.segments = append(s.segments, segment)
+	return nil
+}
+
+func (s *DiskBufferSender) closeTail() {
+	if s.tail != nil {
+		_ = s.tail.Sync()
+		_ = s.tail.Close()
+		s.tail = nil
+	}
+}
+
+func (s *DiskBufferSender) maybeDropOldest() {
+	total := int64(0)
+	for _, segment := range s.segments {
+		total += segment.size
+	}
+
+	for total > s.cfg.MaxBytes && len(s.segments) > 0 {
+		segment := s.segments[0]
+		s.segments = s.segments[1:]
+
+		if len(s.segments) == 0 {
+			s.closeTail()
+		}
+
+		_ = os.Remove(segment.path)
+		total -= segment.size
+		s.stats.DroppedEvents += int64(segment.count)
+	}
+}
+
+func (s *DiskBufferSender) load() error {
+	if err := os.MkdirAll(s.cfg.Dir, 0700); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), diskBufferSegmentExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), diskBufferSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		segment := &diskBufferSegment{
+			seq:  seq,
+			path: filepath.Join(s.cfg.Dir, entry.Name()),
+		}
+
+		records, size, corrupt := readDiskBufferSegment(segment.path, s.cfg.MaxBytes)
+		segment.count = len(records)
+		segment.size = size
+		s.stats.CorruptRecords += corrupt
+
+		s.segments = append(s.segments, segment)
+		s.nextSeq = max(s.nextSeq, seq+1)
+	}
+
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].seq < s.segments[j].seq
+	})
+
+	s.maybeDropOldest()
+	return nil
+}
+
+// readDiskBufferSegment reads all valid records from a segment, stopping at the first corrupt or truncated one. A
+// record length past the end of the file or above maxBytes is corrupt. It returns the records, the number of valid
+// bytes read, and the number of corrupt records encountered.
+func readDiskBufferSegment(path string, maxBytes int64) ([]*diskBufferRecord, int64, int64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, 0
+	}
+	defer errorz.IgnoreClose(f)
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, 0, 0
+	}
+
+	r := bufio.NewReader(f)
+	records := make([]*diskBufferRecord, 0)
+	header := make([]byte, diskBufferRecordHeaderLen)
+	size := int64(0)
+	corrupt := int64(0)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err != io.EOF {
+				corrupt++
+			}
+			break
+		}
+
+		n := int64(binary.BigEndian.Uint32(header[0:4]))
+		if n > fi.Size()-size-diskBufferRecordHeaderLen || n > maxBytes {
+			corrupt++
+			break
+		}
+
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil || crc32.ChecksumIEEE(buf) != binary.BigEndian.Uint32(header[4:8]) {
+			corrupt++
+			break
+		}
+
+		size += int64(diskBufferRecordHeaderLen + len(buf))
+		record := &diskBufferRecord{}
+
+		if err := json.Unmarshal(buf, record); err != nil {
+			corrupt++
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, size, corrupt
+}
+
+// isRetriableResponse returns true if the response denotes a transient failure: throttling, server errors, or network
+// errors (including timeouts) that prevented the event from being delivered.
+func isRetriableResponse(r transmission.Response) bool {
+	if r.StatusCode == http.StatusTooManyRequests || r.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+
+	if r.Err == nil || r.StatusCode != 0 {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(r.Err, &netErr) || errors.Is(r.Err, io.EOF) || errors.Is(r.Err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,369 @@
+package logm_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/filez"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm"
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type testHoneycombStub struct {
+	srv      *httptest.Server
+	failing  *atomic.Bool
+	received *atomic.Int64
+}
+
+func newTestHoneycombStub() *testHoneycombStub {
+	s := &testHoneycombStub{
+		failing:  &atomic.Bool{},
+		received: &atomic.Int64{},
+	}
+
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		events := make([]map[string]any, 0)
+		if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.received.Add(int64(len(events)))
+		responses := make([]map[string]any, len(events))
+		for i := range responses {
+			responses[i] = map[string]any{"status": http.StatusAccepted}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(responses)
+	}))
+
+	return s
+}
+
+func (s *testHoneycombStub) newSender() *transmission.Honeycomb {
+	return &transmission.Honeycomb{
+		BatchTimeout:         10 * time.Millisecond,
+		BlockOnSend:          true,
+		MaxBatchSize:         50,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  100,
+		DisableCompression:   true,
+	}
+}
+
+func (s *testHoneycombStub) newEvent(ctx context.Context, k string) *transmission.Event {
+	return &transmission.Event{
+		APIKey:     "test-honeycomb-api-key",
+		Dataset:    "test-dataset",
+		SampleRate: 1,
+		APIHost:    s.srv.URL,
+		Timestamp:  clkm.MustGet(ctx).Now(),
+		Data:       map[string]any{"k": k},
+	}
+}
+
+type BufferSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestBufferSuite(t *testing.T) {
+	fixturez.RunSuite(t, &BufferSuite{})
+}
+
+func (s *BufferSuite) TestDiskBufferSender_SpillAndReplay(ctx context.Context, g *WithT) {
+	stub := newTestHoneycombStub()
+	defer stub.srv.Close()
+
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	sender := logm.NewDiskBufferSender(ctx, stub.newSender(), &logm.DiskBufferConfig{
+		Dir:           dir,
+		RetryInterval: time.Minute,
+	})
+	g.Expect(sender.Start()).To(Succeed())
+
+	stub.failing.Store(true)
+	sender.Add(stub.newEvent(ctx, "v1"))
+	g.Eventually(func() int { return sender.GetStats().BacklogEvents }, "5s", "10ms").Should(Equal(1))
+	g.Expect(sender.GetStats().Healthy).To(BeFalse())
+
+	sender.Add(stub.newEvent(ctx, "v2"))
+	g.Expect(sender.GetStats()).To(Equal(&logm.DiskBufferStats{
+		Healthy:         false,
+		BacklogSegments: 1,
+		BacklogEvents:   2,
+		BacklogBytes:    sender.GetStats().BacklogBytes,
+		SpilledEvents:   2,
+	}))
+	g.Expect(stub.received.Load()).To(BeZero())
+
+	stub.failing.Store(false)
+	s.CLK.GetMock().Add(time.Minute)
+
+	g.Eventually(stub.received.Load, "5s", "10ms").Should(Equal(int64(2)))
+	g.Eventually(func() int { return sender.GetStats().PendingEvents }, "5s", "10ms").Should(BeZero())
+	g.Expect(sender.GetStats()).To(Equal(&logm.DiskBufferStats{
+		Healthy:        true,
+		SpilledEvents:  2,
+		ReplayedEvents: 2,
+	}))
+
+	sender.Add(stub.newEvent(ctx, "v3"))
+	g.Eventually(stub.received.Load, "5s", "10ms").Should(Equal(int64(3)))
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (s *BufferSuite) TestDiskBufferSender_DropOldest(ctx context.Context, g *WithT) {
+	stub := newTestHoneycombStub()
+	defer stub.srv.Close()
+
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	sender := logm.NewDiskBufferSender(ctx, stub.newSender(), &logm.DiskBufferConfig{
+		Dir:             dir,
+		MaxBytes:        1024,
+		SegmentMaxBytes: 256,
+		RetryInterval:   time.Minute,
+	})
+	g.Expect(sender.Start()).To(Succeed())
+
+	stub.failing.Store(true)
+	sender.Add(stub.newEvent(ctx, "v0"))
+	g.Eventually(func() bool { return sender.GetStats().Healthy }, "5s", "10ms").Should(BeFalse())
+
+	for i := 0; i < 100; i++ {
+		sender.Add(stub.newEvent(ctx, "v"))
+	}
+
+	stats := sender.GetStats()
+	g.Expect(stats.BacklogBytes).To(BeNumerically("<=", 1024))
+	g.Expect(stats.DroppedEvents).To(BeNumerically(">", 0))
+	g.Expect(stats.SpilledEvents).To(Equal(int64(101)))
+	g.Expect(int64(stats.BacklogEvents) + stats.DroppedEvents).To(Equal(int64(101)))
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (s *BufferSuite) TestDiskBufferSender_Reload(ctx context.Context, g *WithT) {
+	stub := newTestHoneycombStub()
+	defer stub.srv.Close()
+
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	cfg := &logm.DiskBufferConfig{
+		Dir:           dir,
+		RetryInterval: time.Minute,
+	}
+
+	sender := logm.NewDiskBufferSender(ctx, stub.newSender(), cfg)
+	g.Expect(sender.Start()).To(Succeed())
+
+	stub.failing.Store(true)
+	sender.Add(stub.newEvent(ctx, "v1"))
+	g.Eventually(func() bool { return sender.GetStats().Healthy }, "5s", "10ms").Should(BeFalse())
+	sender.Add(stub.newEvent(ctx, "v2"))
+	g.Expect(sender.Stop()).To(Succeed())
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	g.Expect(err).To(Succeed())
+	g.Expect(paths).To(HaveLen(1))
+
+	// Corrupt the last byte of the segment, invalidating the checksum of the second record.
+	buf := filez.MustReadFile(paths[0])
+	buf[len(buf)-2] ^= 0xff
+	g.Expect(os.WriteFile(paths[0], buf, 0600)).To(Succeed())
+
+	stub.failing.Store(false)
+	sender = logm.NewDiskBufferSender(ctx, stub.newSender(), cfg)
+	g.Expect(sender.Start()).To(Succeed())
+
+	g.Eventually(stub.received.Load, "5s", "10ms").Should(Equal(int64(1)))
+	g.Eventually(func() int { return sender.GetStats().BacklogEvents }, "5s", "10ms").Should(BeZero())
+	g.Expect(sender.GetStats().CorruptRecords).To(Equal(int64(1)))
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (s *BufferSuite) TestDiskBufferSender_Responses(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	sender := logm.NewDiskBufferSender(ctx, &transmission.WriterSender{W: io.Discard}, &logm.DiskBufferConfig{Dir: dir})
+	g.Expect(sender.Start()).To(Succeed())
+
+	sender.Add(&transmission.Event{Metadata: "m1", Data: map[string]any{}})
+	g.Eventually(sender.TxResponses(), "5s", "10ms").Should(Receive(HaveField("Metadata", "m1")))
+	g.Expect(sender.SendResponse(transmission.Response{Metadata: "m2"})).To(BeFalse())
+	g.Eventually(sender.TxResponses(), "5s", "10ms").Should(Receive(HaveField("Metadata", "m2")))
+	g.Expect(sender.Flush()).To(Succeed())
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (s *BufferSuite) TestDiskBufferSender_Failures(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	wrapped := newTestFailingSender()
+	sender := logm.NewDiskBufferSender(ctx, wrapped, &logm.DiskBufferConfig{Dir: dir, RetryInterval: time.Minute})
+	g.Expect(sender.Start()).To(Succeed())
+
+	// Permanent failures are forwarded and counted, but not spilled.
+	wrapped.err = errorz.Errorf("event exceeds max event size")
+	sender.Add(&transmission.Event{Metadata: "m1", Data: map[string]any{}})
+	g.Eventually(sender.TxResponses(), "5s", "10ms").Should(Receive(HaveField("Metadata", "m1")))
+	g.Expect(sender.GetStats()).To(Equal(&logm.DiskBufferStats{
+		Healthy:        true,
+		RejectedEvents: 1,
+	}))
+
+	// Network errors are spilled.
+	wrapped.err = &net.OpError{Op: "dial", Net: "tcp", Err: errorz.Errorf("connection refused")}
+	sender.Add(&transmission.Event{Metadata: "m2", Data: map[string]any{}})
+	g.Eventually(func() int { return sender.GetStats().BacklogEvents }, "5s", "10ms").Should(Equal(1))
+	g.Expect(sender.GetStats().Healthy).To(BeFalse())
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (s *BufferSuite) TestDiskBufferSender_CorruptLength(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	// A record header claiming a 4GB record, followed by a few bytes.
+	filez.MustWriteFile(filepath.Join(dir, "00000000000000000001.seg"), 0700, 0600,
+		[]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, '{', '}'})
+
+	sender := logm.NewDiskBufferSender(ctx, tlogm.NewMockSender(), &logm.DiskBufferConfig{Dir: dir})
+	g.Expect(sender.Start()).To(Succeed())
+	g.Expect(sender.GetStats()).To(HaveField("CorruptRecords", int64(1)))
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (s *BufferSuite) TestDiskBufferSender_BlockedSender(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	wrapped := newTestGatedSender()
+	sender := logm.NewDiskBufferSender(ctx, wrapped, &logm.DiskBufferConfig{Dir: dir, QueueSize: 1})
+	g.Expect(sender.Start()).To(Succeed())
+
+	// Add does not block while the wrapped sender does: events past the queue are spilled.
+	for _, k := range []string{"v0", "v1", "v2"} {
+		sender.Add(newTestAsyncEvent(k))
+	}
+
+	g.Expect(sender.GetStats()).To(And(
+		HaveField("Healthy", true),
+		HaveField("SpilledEvents", BeNumerically(">=", 1))))
+
+	close(wrapped.gate)
+	g.Expect(sender.Flush()).To(Succeed())
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (s *BufferSuite) TestDiskBufferSender_ReplayInFlight(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	cfg := &logm.DiskBufferConfig{
+		Dir:           dir,
+		RetryInterval: time.Minute,
+	}
+
+	// Events added before Start are spilled.
+	sender := logm.NewDiskBufferSender(ctx, tlogm.NewMockSender(), cfg)
+	sender.Add(newTestAsyncEvent("v1"))
+	g.Expect(sender.GetStats()).To(HaveField("SpilledEvents", int64(1)))
+	g.Expect(sender.Stop()).To(Succeed())
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	g.Expect(err).To(Succeed())
+	g.Expect(paths).To(HaveLen(1))
+
+	wrapped := newTestGatedSender()
+	sender = logm.NewDiskBufferSender(ctx, wrapped, cfg)
+	g.Expect(sender.Start()).To(Succeed())
+
+	// The segment is kept while its events are in flight.
+	g.Eventually(func() int64 { return sender.GetStats().ReplayedEvents }, "5s", "10ms").Should(Equal(int64(1)))
+	g.Expect(paths[0]).To(BeAnExistingFile())
+
+	// Undelivered events are spilled again on Stop, then the replayed segment is deleted.
+	close(wrapped.gate)
+	g.Expect(sender.Stop()).To(Succeed())
+	g.Expect(paths[0]).ToNot(BeAnExistingFile())
+
+	newPaths, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	g.Expect(err).To(Succeed())
+	g.Expect(newPaths).To(HaveLen(1))
+	g.Expect(newPaths[0]).ToNot(Equal(paths[0]))
+}
+
+func (s *BufferSuite) TestDiskBufferSender_StopWithoutStart(ctx context.Context, g *WithT) {
+	sender := logm.NewDiskBufferSender(ctx, newTestFailingSender(), &logm.DiskBufferConfig{Dir: "unused"})
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+type testFailingSender struct {
+	err error
+	c   chan transmission.Response
+}
+
+func newTestFailingSender() *testFailingSender {
+	return &testFailingSender{
+		c: make(chan transmission.Response, 16),
+	}
+}
+
+// Add implements the [transmission.Sender] interface.
+func (s *testFailingSender) Add(e *transmission.Event) {
+	s.c <- transmission.Response{Err: s.err, Metadata: e.Metadata}
+}
+
+// Start implements the [transmission.Sender] interface.
+func (s *testFailingSender) Start() error {
+	return nil
+}
+
+// Stop implements the [transmission.Sender] interface.
+func (s *testFailingSender) Stop() error {
+	return nil
+}
+
+// Flush implements the [transmission.Sender] interface.
+func (s *testFailingSender) Flush() error {
+	return nil
+}
+
+// TxResponses implements the [transmission.Sender] interface.
+func (s *testFailingSender) TxResponses() chan transmission.Response {
+	return s.c
+}
+
+// SendResponse implements the [transmission.Sender] interface.
+func (s *testFailingSender) SendResponse(r transmission.Response) bool {
+	s.c <- r
+	return false
+}
@@ -0,0 +1,149 @@
+// Package main implements a command that queries events captured as JSON lines, for incident analysis without access
+// to Honeycomb. Events can be captured using the JSON logrus output or a file sender, see the logm package.
+//
+// Usage:
+//
+//	logm-replay [flags] [file ...]
+//
+// Events are read from the given files, or from stdin if none are given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/logm"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("logm-replay", flag.ContinueOnError)
+	q := &logm.TraceQuery{}
+
+	fs.StringVar(&q.TraceID, "trace-id", "", "only include the trace with the given ID")
+	fs.StringVar(&q.SpanName, "span", "", "only include traces with a span with the given name")
+	fs.StringVar(&q.User, "user", "", "only include traces with a span with the given user ID or email")
+	fs.BoolVar(&q.HasError, "error", false, "only include traces with a span with an error")
+	fs.DurationVar(&q.MinDuration, "min-duration", 0, "only include traces with a span lasting at least the given duration")
+	stats := fs.Bool("stats", false, "print latency percentiles by span name instead of span trees")
+	replayAPIKey := fs.String("replay-api-key", "", "re-send the selected events to Honeycomb using the given API key")
+	replayAPIHost := fs.String("replay-api-host", "https://api.honeycomb.io/", "the Honeycomb API host used to re-send events")
+	replayDataset := fs.String("replay-dataset", "", "the Honeycomb dataset used to re-send events captured without one")
+
+	if err := fs.Parse(args); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	events, err := readEvents(fs.Args(), stdin)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+
+	traces := logm.QueryTraces(logm.NewCapturedTraces(events), q)
+
+	if *stats {
+		printStats(stdout, traces)
+	} else {
+		printTraces(stdout, traces)
+	}
+
+	if *replayAPIKey != "" {
+		return replay(*replayAPIKey, *replayAPIHost, *replayDataset, traces)
+	}
+
+	return nil
+}
+
+func readEvents(paths []string, stdin io.Reader) ([]*transmission.Event, error) {
+	if len(paths) == 0 {
+		return logm.ReadCapturedEvents(stdin)
+	}
+
+	events := make([]*transmission.Event, 0)
+
+	for _, path := range paths {
+		fileEvents, err := readFileEvents(path)
+		if err != nil {
+			return nil, errorz.Wrap(err, errorz.Errorf("file '%v'", path))
+		}
+
+		events = append(events, fileEvents...)
+	}
+
+	return events, nil
+}
+
+func readFileEvents(path string) ([]*transmission.Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errorz.Wrap(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return logm.ReadCapturedEvents(f)
+}
+
+func printTraces(w io.Writer, traces []*logm.CapturedTrace) {
+	for _, t := range traces {
+		status := ""
+		if t.HasError {
+			status += " [error]"
+		}
+		if t.IsIncomplete {
+			status += " [incomplete]"
+		}
+
+		_, _ = fmt.Fprintf(w, "trace %v %v %v (%.2fms)%v\n%v\n\n",
+			t.TraceID, t.Timestamp.Format(time.RFC3339Nano), t.Name, t.DurationMS, status, t.Tree)
+	}
+}
+
+func printStats(w io.Writer, traces []*logm.CapturedTrace) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SPAN\tCOUNT\tP50 (ms)\tP90 (ms)\tP99 (ms)\tMAX (ms)")
+
+	for _, l := range logm.GetSpanLatencies(traces) {
+		_, _ = fmt.Fprintf(tw, "%v\t%v\t%.2f\t%.2f\t%.2f\t%.2f\n", l.Name, l.Count, l.P50, l.P90, l.P99, l.Max)
+	}
+
+	_ = tw.Flush()
+}
+
+func replay(apiKey, apiHost, dataset string, traces []*logm.CapturedTrace) error {
+	sender := &transmission.Honeycomb{
+		MaxBatchSize:         libhoney.DefaultMaxBatchSize,
+		BatchTimeout:         libhoney.DefaultBatchTimeout,
+		MaxConcurrentBatches: libhoney.DefaultMaxConcurrentBatches,
+		PendingWorkCapacity:  libhoney.DefaultPendingWorkCapacity,
+		BlockOnSend:          true,
+		UserAgentAddition:    libhoney.UserAgentAddition,
+	}
+
+	if err := sender.Start(); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	logm.ReplayTraces(sender, &transmission.Event{
+		APIKey:  apiKey,
+		APIHost: apiHost,
+		Dataset: dataset,
+	}, traces)
+
+	return errorz.MaybeWrap(sender.Stop())
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ibrt/golang-utils/filez"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	testEvents = "" +
+		`{"time":"2024-01-01T00:00:00Z","trace.trace_id":"t1","trace.span_id":"s1","name":"root","duration_ms":1000}` + "\n" +
+		`{"time":"2024-01-01T00:00:00Z","trace.trace_id":"t1","trace.span_id":"s2","trace.parent_id":"s1","name":"child","duration_ms":500,"error":true}` + "\n" +
+		"unrelated output\n" +
+		`{"time":"2024-01-01T00:00:01Z","trace.trace_id":"t2","trace.span_id":"s3","name":"root","duration_ms":3000,"scope.user":"u1"}` + "\n"
+)
+
+type MainSuite struct {
+	// intentionally empty
+}
+
+func TestMainSuite(t *testing.T) {
+	fixturez.RunSuite(t, &MainSuite{})
+}
+
+func (*MainSuite) TestRun_Traces(g *WithT) {
+	stdout := &bytes.Buffer{}
+	g.Expect(run(nil, strings.NewReader(testEvents), stdout)).To(Succeed())
+	g.Expect(stdout.String()).To(Equal("" +
+		"trace t1 2024-01-01T00:00:00Z root (1000.00ms) [error]\n" +
+		"[+0.00ms] root (1000.00ms)\n" +
+		"└── [+0.00ms] child (500.00ms) [error]\n\n" +
+		"trace t2 2024-01-01T00:00:01Z root (3000.00ms)\n" +
+		"[+0.00ms] root (3000.00ms)\n\n"))
+}
+
+func (*MainSuite) TestRun_Query(g *WithT) {
+	for _, args := range [][]string{
+		{"-trace-id", "t2"},
+		{"-user", "u1"},
+		{"-min-duration", "2s"},
+	} {
+		stdout := &bytes.Buffer{}
+		g.Expect(run(args, strings.NewReader(testEvents), stdout)).To(Succeed())
+		g.Expect(stdout.String()).To(HavePrefix("trace t2 "))
+		g.Expect(stdout.String()).ToNot(ContainSubstring("trace t1 "))
+	}
+
+	stdout := &bytes.Buffer{}
+	g.Expect(run([]string{"-error", "-span", "child"}, strings.NewReader(testEvents), stdout)).To(Succeed())
+	g.Expect(stdout.String()).To(HavePrefix("trace t1 "))
+	g.Expect(stdout.String()).ToNot(ContainSubstring("trace t2 "))
+}
+
+func (*MainSuite) TestRun_Stats(g *WithT) {
+	stdout := &bytes.Buffer{}
+	g.Expect(run([]string{"-stats"}, strings.NewReader(testEvents), stdout)).To(Succeed())
+	g.Expect(strings.Split(strings.TrimSpace(stdout.String()), "\n")).To(HaveExactElements(
+		MatchRegexp(`^SPAN\s+COUNT\s+P50 \(ms\)\s+P90 \(ms\)\s+P99 \(ms\)\s+MAX \(ms\)$`),
+		MatchRegexp(`^child\s+1\s+500.00\s+500.00\s+500.00\s+500.00$`),
+		MatchRegexp(`^root\s+2\s+1000.00\s+3000.00\s+3000.00\s+3000.00$`)))
+}
+
+func (*MainSuite) TestRun_Files(g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	lines := strings.SplitAfter(testEvents, "\n")
+	filePath1 := filez.MustWriteFile(filepath.Join(dir, "1.json"), 0777, 0666, []byte(lines[0]+lines[1]))
+	filePath2 := filez.MustWriteFile(filepath.Join(dir, "2.json"), 0777, 0666, []byte(lines[3]))
+
+	stdout := &bytes.Buffer{}
+	g.Expect(run([]string{"-stats", filePath1, filePath2}, strings.NewReader(""), stdout)).To(Succeed())
+	g.Expect(stdout.String()).To(MatchRegexp(`(?m)^root\s+2\s`))
+
+	err := run([]string{filepath.Join(dir, "missing.json")}, strings.NewReader(""), &bytes.Buffer{})
+	g.Expect(err).To(MatchError(ContainSubstring("file '" + filepath.Join(dir, "missing.json") + "'")))
+}
+
+func (*MainSuite) TestRun_Errors(g *WithT) {
+	g.Expect(run([]string{"-unknown"}, strings.NewReader(""), &bytes.Buffer{})).
+		To(MatchError(ContainSubstring("flag provided but not defined: -unknown")))
+	g.Expect(run(nil, strings.NewReader(`{"time":"invalid"}`), &bytes.Buffer{})).
+		To(MatchError(ContainSubstring("captured events line 1")))
+}
+
+func (*MainSuite) TestRun_Replay(g *WithT) {
+	m := &sync.Mutex{}
+	paths := make([]string, 0)
+	apiKeys := make([]string, 0)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		paths = append(paths, r.URL.Path)
+		apiKeys = append(apiKeys, r.Header.Get("X-Honeycomb-Team"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	args := []string{
+		"-trace-id", "t1",
+		"-replay-api-key", "test-honeycomb-api-key",
+		"-replay-api-host", srv.URL,
+		"-replay-dataset", "test-dataset",
+	}
+
+	stdout := &bytes.Buffer{}
+	g.Expect(run(args, strings.NewReader(testEvents), stdout)).To(Succeed())
+	g.Expect(stdout.String()).To(HavePrefix("trace t1 "))
+
+	m.Lock()
+	defer m.Unlock()
+	g.Expect(paths).To(HaveExactElements("/1/batch/test-dataset"))
+	g.Expect(apiKeys).To(HaveExactElements("test-honeycomb-api-key"))
+}
@@ -3,6 +3,9 @@ package logm
 import (
 	"encoding"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/ibrt/golang-utils/errorz"
 	"github.com/sirupsen/logrus"
@@ -50,24 +53,125 @@ type LogConfigMixin interface {
 
 // LogConfig describes the module configuration.
 type LogConfig struct {
-	HoneycombAPIKey     string                `env:"LOG_HONEYCOMB_API_KEY,required"`
-	HoneycombDataset    string                `env:"LOG_HONEYCOMB_DATASET,required"`
-	HoneycombSampleRate uint                  `env:"LOG_HONEYCOMB_SAMPLE_RATE,required" validate:"required,min=1"`
-	LogrusOutput        LogConfigLogrusOutput `env:"LOG_LOGRUS_OUTPUT,required"`
-	LogrusLevel         logrus.Level          `env:"LOG_LOGRUS_LEVEL,required"`
+	HoneycombAPIKey            string                  `env:"LOG_HONEYCOMB_API_KEY,required"`
+	HoneycombDataset           string                  `env:"LOG_HONEYCOMB_DATASET,required"`
+	HoneycombSampleRate        uint                    `env:"LOG_HONEYCOMB_SAMPLE_RATE,required" validate:"required,min=1"`
+	HoneycombBufferDir         string                  `env:"LOG_HONEYCOMB_BUFFER_DIR"`
+	HoneycombBufferMaxBytes    int64                   `env:"LOG_HONEYCOMB_BUFFER_MAX_BYTES" validate:"min=0"`
+	HoneycombRoute             SinkRouteSpec           `env:"LOG_HONEYCOMB_ROUTE"`
+	LogrusOutput               LogConfigLogrusOutput   `env:"LOG_LOGRUS_OUTPUT,required"`
+	LogrusLevel                logrus.Level            `env:"LOG_LOGRUS_LEVEL,required"`
+	LogrusRoute                SinkRouteSpec           `env:"LOG_LOGRUS_ROUTE"`
+	OTLPEndpoint               string                  `env:"LOG_OTLP_ENDPOINT"`
+	OTLPHeaders                map[string]string       `env:"LOG_OTLP_HEADERS"`
+	OTLPRoute                  SinkRouteSpec           `env:"LOG_OTLP_ROUTE"`
+	SinkQueueSize              int                     `env:"LOG_SINK_QUEUE_SIZE" validate:"min=0"`
+	SinkOverflowPolicy         AsyncSinkOverflowPolicy `env:"LOG_SINK_OVERFLOW_POLICY"`
+	FilePath                   string                  `env:"LOG_FILE_PATH"`
+	FileMaxBytes               int64                   `env:"LOG_FILE_MAX_BYTES" validate:"min=0"`
+	FileRotateInterval         time.Duration           `env:"LOG_FILE_ROTATE_INTERVAL" validate:"min=0"`
+	FileCompress               bool                    `env:"LOG_FILE_COMPRESS"`
+	FileMaxBackups             int                     `env:"LOG_FILE_MAX_BACKUPS" validate:"min=0"`
+	FileSyncPolicy             FileSyncPolicy          `env:"LOG_FILE_SYNC_POLICY"`
+	FileSyncInterval           time.Duration           `env:"LOG_FILE_SYNC_INTERVAL" validate:"min=0"`
+	FileRoute                  SinkRouteSpec           `env:"LOG_FILE_ROUTE"`
+	ErrorDedupWindow           time.Duration           `env:"LOG_ERROR_DEDUP_WINDOW" validate:"min=0"`
+	ErrorDedupBurst            int                     `env:"LOG_ERROR_DEDUP_BURST" validate:"min=0"`
+	AttributeRegistryMode      AttributeRegistryMode   `env:"LOG_ATTRIBUTE_REGISTRY_MODE"`
+	PanicPolicy                PanicPolicy             `env:"LOG_PANIC_POLICY"`
+	PanicGoroutineDumpMaxBytes int                     `env:"LOG_PANIC_GOROUTINE_DUMP_MAX_BYTES" validate:"min=0"`
+	TraceViewer                bool                    `env:"LOG_TRACE_VIEWER"`
+	TraceViewerAddr            string                  `env:"LOG_TRACE_VIEWER_ADDR"`
+	TraceViewerRoute           SinkRouteSpec           `env:"LOG_TRACE_VIEWER_ROUTE"`
+	BaggageAllowlist           []string                `env:"LOG_BAGGAGE_ALLOWLIST"`
+	BaggageMaxBytes            int                     `env:"LOG_BAGGAGE_MAX_BYTES" validate:"min=0"`
+	SpanTracking               bool                    `env:"LOG_SPAN_TRACKING"`
+	SpanAutoCloseChildren      bool                    `env:"LOG_SPAN_AUTO_CLOSE_CHILDREN"`
+	SpanBudget                 time.Duration           `env:"LOG_SPAN_BUDGET" validate:"min=0"`
+	ServiceName                string                  `env:"LOG_SERVICE_NAME"`
+	ServiceEnvironment         string                  `env:"LOG_SERVICE_ENVIRONMENT"`
+	ServiceVersion             string                  `env:"LOG_SERVICE_VERSION"`
+	RuntimeStatsInterval       time.Duration           `env:"LOG_RUNTIME_STATS_INTERVAL" validate:"min=0"`
+	AuditFilePath              string                  `env:"LOG_AUDIT_FILE_PATH"`
+	AuditKey                   string                  `env:"LOG_AUDIT_KEY"`
+	RateLimitPolicy            RateLimitPolicy         `env:"LOG_RATE_LIMIT_POLICY"`
+	RateLimitWindow            time.Duration           `env:"LOG_RATE_LIMIT_WINDOW" validate:"min=0"`
+	RateLimitBurst             int                     `env:"LOG_RATE_LIMIT_BURST" validate:"min=0"`
+	RateLimitSampleEvery       int                     `env:"LOG_RATE_LIMIT_SAMPLE_EVERY" validate:"min=0"`
+	PayloadMaxFields           int                     `env:"LOG_PAYLOAD_MAX_FIELDS" validate:"min=0"`
+	PayloadMaxStringBytes      int                     `env:"LOG_PAYLOAD_MAX_STRING_BYTES" validate:"min=0"`
+	PayloadMaxTotalBytes       int                     `env:"LOG_PAYLOAD_MAX_TOTAL_BYTES" validate:"min=0"`
+	PayloadMaxDepth            int                     `env:"LOG_PAYLOAD_MAX_DEPTH" validate:"min=0"`
+	PayloadMaxBlobBytes        int                     `env:"LOG_PAYLOAD_MAX_BLOB_BYTES" validate:"min=0"`
 }
 
 // ToEnv converts the config to an env map.
 func (c *LogConfig) ToEnv(prefix string) map[string]string {
 	return map[string]string{
-		prefix + "LOG_HONEYCOMB_API_KEY":     c.HoneycombAPIKey,
-		prefix + "LOG_HONEYCOMB_DATASET":     c.HoneycombDataset,
-		prefix + "LOG_HONEYCOMB_SAMPLE_RATE": fmt.Sprintf("%v", c.HoneycombSampleRate),
-		prefix + "LOG_LOGRUS_OUTPUT":         c.LogrusOutput.String(),
-		prefix + "LOG_LOGRUS_LEVEL":          c.LogrusLevel.String(),
+		prefix + "LOG_HONEYCOMB_API_KEY":              c.HoneycombAPIKey,
+		prefix + "LOG_HONEYCOMB_DATASET":              c.HoneycombDataset,
+		prefix + "LOG_HONEYCOMB_SAMPLE_RATE":          fmt.Sprintf("%v", c.HoneycombSampleRate),
+		prefix + "LOG_HONEYCOMB_BUFFER_DIR":           c.HoneycombBufferDir,
+		prefix + "LOG_HONEYCOMB_BUFFER_MAX_BYTES":     fmt.Sprintf("%v", c.HoneycombBufferMaxBytes),
+		prefix + "LOG_HONEYCOMB_ROUTE":                c.HoneycombRoute.String(),
+		prefix + "LOG_LOGRUS_OUTPUT":                  c.LogrusOutput.String(),
+		prefix + "LOG_LOGRUS_LEVEL":                   c.LogrusLevel.String(),
+		prefix + "LOG_LOGRUS_ROUTE":                   c.LogrusRoute.String(),
+		prefix + "LOG_OTLP_ENDPOINT":                  c.OTLPEndpoint,
+		prefix + "LOG_OTLP_HEADERS":                   joinEnvMap(c.OTLPHeaders),
+		prefix + "LOG_OTLP_ROUTE":                     c.OTLPRoute.String(),
+		prefix + "LOG_SINK_QUEUE_SIZE":                fmt.Sprintf("%v", c.SinkQueueSize),
+		prefix + "LOG_SINK_OVERFLOW_POLICY":           c.SinkOverflowPolicy.String(),
+		prefix + "LOG_FILE_PATH":                      c.FilePath,
+		prefix + "LOG_FILE_MAX_BYTES":                 fmt.Sprintf("%v", c.FileMaxBytes),
+		prefix + "LOG_FILE_ROTATE_INTERVAL":           c.FileRotateInterval.String(),
+		prefix + "LOG_FILE_COMPRESS":                  fmt.Sprintf("%v", c.FileCompress),
+		prefix + "LOG_FILE_MAX_BACKUPS":               fmt.Sprintf("%v", c.FileMaxBackups),
+		prefix + "LOG_FILE_SYNC_POLICY":               c.FileSyncPolicy.String(),
+		prefix + "LOG_FILE_SYNC_INTERVAL":             c.FileSyncInterval.String(),
+		prefix + "LOG_FILE_ROUTE":                     c.FileRoute.String(),
+		prefix + "LOG_ERROR_DEDUP_WINDOW":             c.ErrorDedupWindow.String(),
+		prefix + "LOG_ERROR_DEDUP_BURST":              fmt.Sprintf("%v", c.ErrorDedupBurst),
+		prefix + "LOG_ATTRIBUTE_REGISTRY_MODE":        c.AttributeRegistryMode.String(),
+		prefix + "LOG_PANIC_POLICY":                   c.PanicPolicy.String(),
+		prefix + "LOG_PANIC_GOROUTINE_DUMP_MAX_BYTES": fmt.Sprintf("%v", c.PanicGoroutineDumpMaxBytes),
+		prefix + "LOG_TRACE_VIEWER":                   fmt.Sprintf("%v", c.TraceViewer),
+		prefix + "LOG_TRACE_VIEWER_ADDR":              c.TraceViewerAddr,
+		prefix + "LOG_TRACE_VIEWER_ROUTE":             c.TraceViewerRoute.String(),
+		prefix + "LOG_BAGGAGE_ALLOWLIST":              strings.Join(c.BaggageAllowlist, ","),
+		prefix + "LOG_BAGGAGE_MAX_BYTES":              fmt.Sprintf("%v", c.BaggageMaxBytes),
+		prefix + "LOG_SPAN_TRACKING":                  fmt.Sprintf("%v", c.SpanTracking),
+		prefix + "LOG_SPAN_AUTO_CLOSE_CHILDREN":       fmt.Sprintf("%v", c.SpanAutoCloseChildren),
+		prefix + "LOG_SPAN_BUDGET":                    c.SpanBudget.String(),
+		prefix + "LOG_SERVICE_NAME":                   c.ServiceName,
+		prefix + "LOG_SERVICE_ENVIRONMENT":            c.ServiceEnvironment,
+		prefix + "LOG_SERVICE_VERSION":                c.ServiceVersion,
+		prefix + "LOG_RUNTIME_STATS_INTERVAL":         c.RuntimeStatsInterval.String(),
+		prefix + "LOG_AUDIT_FILE_PATH":                c.AuditFilePath,
+		prefix + "LOG_AUDIT_KEY":                      c.AuditKey,
+		prefix + "LOG_RATE_LIMIT_POLICY":              c.RateLimitPolicy.String(),
+		prefix + "LOG_RATE_LIMIT_WINDOW":              c.RateLimitWindow.String(),
+		prefix + "LOG_RATE_LIMIT_BURST":               fmt.Sprintf("%v", c.RateLimitBurst),
+		prefix + "LOG_RATE_LIMIT_SAMPLE_EVERY":        fmt.Sprintf("%v", c.RateLimitSampleEvery),
+		prefix + "LOG_PAYLOAD_MAX_FIELDS":             fmt.Sprintf("%v", c.PayloadMaxFields),
+		prefix + "LOG_PAYLOAD_MAX_STRING_BYTES":       fmt.Sprintf("%v", c.PayloadMaxStringBytes),
+		prefix + "LOG_PAYLOAD_MAX_TOTAL_BYTES":        fmt.Sprintf("%v", c.PayloadMaxTotalBytes),
+		prefix + "LOG_PAYLOAD_MAX_DEPTH":              fmt.Sprintf("%v", c.PayloadMaxDepth),
+		prefix + "LOG_PAYLOAD_MAX_BLOB_BYTES":         fmt.Sprintf("%v", c.PayloadMaxBlobBytes),
 	}
 }
 
+func joinEnvMap(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+
+	for k, v := range m {
+		pairs = append(pairs, k+":"+v)
+	}
+
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
 // Config implements the [cfgm.Config] interface.
 func (c *LogConfig) Config() {
 	// intentionally empty
@@ -2,6 +2,7 @@ package logm_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/ibrt/golang-utils/envz"
@@ -24,11 +25,56 @@ func TestConfigSuite(t *testing.T) {
 func (*ConfigSuite) TestLogConfig(g *WithT) {
 	{
 		e := map[string]string{
-			"PREFIX_LOG_HONEYCOMB_API_KEY":     cfgm.DisabledValue,
-			"PREFIX_LOG_HONEYCOMB_DATASET":     "test",
-			"PREFIX_LOG_HONEYCOMB_SAMPLE_RATE": "1",
-			"PREFIX_LOG_LOGRUS_OUTPUT":         cfgm.DisabledValue,
-			"PREFIX_LOG_LOGRUS_LEVEL":          logrus.InfoLevel.String(),
+			"PREFIX_LOG_HONEYCOMB_API_KEY":              cfgm.DisabledValue,
+			"PREFIX_LOG_HONEYCOMB_DATASET":              "test",
+			"PREFIX_LOG_HONEYCOMB_SAMPLE_RATE":          "1",
+			"PREFIX_LOG_HONEYCOMB_BUFFER_DIR":           "/tmp/buffer",
+			"PREFIX_LOG_HONEYCOMB_BUFFER_MAX_BYTES":     "1024",
+			"PREFIX_LOG_HONEYCOMB_ROUTE":                "sample=10",
+			"PREFIX_LOG_LOGRUS_OUTPUT":                  cfgm.DisabledValue,
+			"PREFIX_LOG_LOGRUS_LEVEL":                   logrus.InfoLevel.String(),
+			"PREFIX_LOG_LOGRUS_ROUTE":                   "level=warning",
+			"PREFIX_LOG_OTLP_ENDPOINT":                  "http://localhost:4318",
+			"PREFIX_LOG_OTLP_HEADERS":                   "Authorization:Bearer token",
+			"PREFIX_LOG_OTLP_ROUTE":                     "fields=trace.span_id",
+			"PREFIX_LOG_SINK_QUEUE_SIZE":                "100",
+			"PREFIX_LOG_SINK_OVERFLOW_POLICY":           string(logm.AsyncSinkOverflowPolicyDropOldest),
+			"PREFIX_LOG_FILE_PATH":                      "/tmp/events.jsonl",
+			"PREFIX_LOG_FILE_MAX_BYTES":                 "2048",
+			"PREFIX_LOG_FILE_ROTATE_INTERVAL":           "1h0m0s",
+			"PREFIX_LOG_FILE_COMPRESS":                  "true",
+			"PREFIX_LOG_FILE_MAX_BACKUPS":               "5",
+			"PREFIX_LOG_FILE_SYNC_POLICY":               string(logm.FileSyncPolicyInterval),
+			"PREFIX_LOG_FILE_SYNC_INTERVAL":             "1s",
+			"PREFIX_LOG_FILE_ROUTE":                     "level=error",
+			"PREFIX_LOG_ERROR_DEDUP_WINDOW":             "1m0s",
+			"PREFIX_LOG_ERROR_DEDUP_BURST":              "3",
+			"PREFIX_LOG_ATTRIBUTE_REGISTRY_MODE":        string(logm.AttributeRegistryModeReject),
+			"PREFIX_LOG_PANIC_POLICY":                   string(logm.PanicPolicyRepanic),
+			"PREFIX_LOG_PANIC_GOROUTINE_DUMP_MAX_BYTES": "4096",
+			"PREFIX_LOG_TRACE_VIEWER":                   "true",
+			"PREFIX_LOG_TRACE_VIEWER_ADDR":              "127.0.0.1:8090",
+			"PREFIX_LOG_TRACE_VIEWER_ROUTE":             "name=http.*,pgm.*",
+			"PREFIX_LOG_BAGGAGE_ALLOWLIST":              "scope.tenant_id,scope.request_id",
+			"PREFIX_LOG_BAGGAGE_MAX_BYTES":              "1024",
+			"PREFIX_LOG_SPAN_TRACKING":                  "true",
+			"PREFIX_LOG_SPAN_AUTO_CLOSE_CHILDREN":       "true",
+			"PREFIX_LOG_SPAN_BUDGET":                    "1s",
+			"PREFIX_LOG_SERVICE_NAME":                   "svc",
+			"PREFIX_LOG_SERVICE_ENVIRONMENT":            "production",
+			"PREFIX_LOG_SERVICE_VERSION":                "v1.2.3",
+			"PREFIX_LOG_RUNTIME_STATS_INTERVAL":         "1m0s",
+			"PREFIX_LOG_AUDIT_FILE_PATH":                "/tmp/audit.jsonl",
+			"PREFIX_LOG_AUDIT_KEY":                      "audit-key",
+			"PREFIX_LOG_RATE_LIMIT_POLICY":              string(logm.RateLimitPolicySample),
+			"PREFIX_LOG_RATE_LIMIT_WINDOW":              "1m0s",
+			"PREFIX_LOG_RATE_LIMIT_BURST":               "10",
+			"PREFIX_LOG_RATE_LIMIT_SAMPLE_EVERY":        "100",
+			"PREFIX_LOG_PAYLOAD_MAX_FIELDS":             "50",
+			"PREFIX_LOG_PAYLOAD_MAX_STRING_BYTES":       "1024",
+			"PREFIX_LOG_PAYLOAD_MAX_TOTAL_BYTES":        "65536",
+			"PREFIX_LOG_PAYLOAD_MAX_DEPTH":              "4",
+			"PREFIX_LOG_PAYLOAD_MAX_BLOB_BYTES":         "8192",
 		}
 
 		envz.WithEnv(e,
@@ -36,11 +82,56 @@ func (*ConfigSuite) TestLogConfig(g *WithT) {
 				logCfg, err := env.ParseAsWithOptions[logm.LogConfig](env.Options{Prefix: "PREFIX_"})
 				g.Expect(err).ToNot(HaveOccurred())
 				g.Expect(logCfg).To(Equal(logm.LogConfig{
-					HoneycombAPIKey:     cfgm.DisabledValue,
-					HoneycombDataset:    "test",
-					HoneycombSampleRate: 1,
-					LogrusOutput:        cfgm.DisabledValue,
-					LogrusLevel:         logrus.InfoLevel,
+					HoneycombAPIKey:            cfgm.DisabledValue,
+					HoneycombDataset:           "test",
+					HoneycombSampleRate:        1,
+					HoneycombBufferDir:         "/tmp/buffer",
+					HoneycombBufferMaxBytes:    1024,
+					HoneycombRoute:             "sample=10",
+					LogrusOutput:               cfgm.DisabledValue,
+					LogrusLevel:                logrus.InfoLevel,
+					LogrusRoute:                "level=warning",
+					OTLPEndpoint:               "http://localhost:4318",
+					OTLPHeaders:                map[string]string{"Authorization": "Bearer token"},
+					OTLPRoute:                  "fields=trace.span_id",
+					SinkQueueSize:              100,
+					SinkOverflowPolicy:         logm.AsyncSinkOverflowPolicyDropOldest,
+					FilePath:                   "/tmp/events.jsonl",
+					FileMaxBytes:               2048,
+					FileRotateInterval:         time.Hour,
+					FileCompress:               true,
+					FileMaxBackups:             5,
+					FileSyncPolicy:             logm.FileSyncPolicyInterval,
+					FileSyncInterval:           time.Second,
+					FileRoute:                  "level=error",
+					ErrorDedupWindow:           time.Minute,
+					ErrorDedupBurst:            3,
+					AttributeRegistryMode:      logm.AttributeRegistryModeReject,
+					PanicPolicy:                logm.PanicPolicyRepanic,
+					PanicGoroutineDumpMaxBytes: 4096,
+					TraceViewer:                true,
+					TraceViewerAddr:            "127.0.0.1:8090",
+					TraceViewerRoute:           "name=http.*,pgm.*",
+					BaggageAllowlist:           []string{"scope.tenant_id", "scope.request_id"},
+					BaggageMaxBytes:            1024,
+					SpanTracking:               true,
+					SpanAutoCloseChildren:      true,
+					SpanBudget:                 time.Second,
+					ServiceName:                "svc",
+					ServiceEnvironment:         "production",
+					ServiceVersion:             "v1.2.3",
+					RuntimeStatsInterval:       time.Minute,
+					AuditFilePath:              "/tmp/audit.jsonl",
+					AuditKey:                   "audit-key",
+					RateLimitPolicy:            logm.RateLimitPolicySample,
+					RateLimitWindow:            time.Minute,
+					RateLimitBurst:             10,
+					RateLimitSampleEvery:       100,
+					PayloadMaxFields:           50,
+					PayloadMaxStringBytes:      1024,
+					PayloadMaxTotalBytes:       65536,
+					PayloadMaxDepth:            4,
+					PayloadMaxBlobBytes:        8192,
 				}))
 				g.Expect(logCfg.ToEnv("PREFIX_")).To(Equal(e))
 			})
@@ -58,4 +149,18 @@ func (*ConfigSuite) TestLogConfig(g *WithT) {
 			_, err := env.ParseAs[logm.LogConfig]()
 			g.Expect(err).To(MatchError(`env: parse error on field "LogrusOutput" of type "logm.LogConfigLogrusOutput": invalid value for LogConfigLogrusOutput: 'invalid'`))
 		})
+
+	envz.WithEnv(
+		map[string]string{
+			"LOG_HONEYCOMB_API_KEY":     cfgm.DisabledValue,
+			"LOG_HONEYCOMB_DATASET":     "test",
+			"LOG_HONEYCOMB_SAMPLE_RATE": "1",
+			"LOG_LOGRUS_OUTPUT":         cfgm.DisabledValue,
+			"LOG_LOGRUS_LEVEL":          logrus.InfoLevel.String(),
+			"LOG_LOGRUS_ROUTE":          "invalid",
+		},
+		func() {
+			_, err := env.ParseAs[logm.LogConfig]()
+			g.Expect(err).To(MatchError(`env: parse error on field "LogrusRoute" of type "logm.SinkRouteSpec": invalid sink route term: 'invalid'`))
+		})
 }
@@ -0,0 +1,225 @@
+package logm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/hashz"
+
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+const (
+	errorFingerprintFrames = 3
+)
+
+// GetErrorFingerprint returns a stable fingerprint for the given error, computed from its name and the functions in
+// its top stack frames. Line numbers and messages are excluded, so that fingerprints survive unrelated code changes
+// and group errors with variable messages.
+func GetErrorFingerprint(err error) string {
+	parts := []string{getErrorName(err)}
+
+	for i, f := range getLocationFrames(err) {
+		if i >= errorFingerprintFrames {
+			break
+		}
+		parts = append(parts, f.Package+"."+f.Function)
+	}
+
+	return hashz.MustHashFNV1128([]byte(strings.Join(parts, "\n")))
+}
+
+type errorAggregatorEntry struct {
+	windowStart time.Time
+	count       int
+	suppressed  int
+	name        string
+	message     string
+}
+
+// errorAggregator rate-limits error events by fingerprint: within each window, only the first burst occurrences are
+// emitted, the others are counted and reported in a summary event when the window expires or the log is flushed.
+// Expired windows are swept by a background goroutine, which runs only while there are entries, until stopped.
+type errorAggregator struct {
+	ne         newEvent
+	window     time.Duration
+	burst      int
+	m          *sync.Mutex
+	entries    map[string]*errorAggregatorEntry
+	isSweeping bool
+	isStopped  bool
+	stopC      chan struct{}
+	doneC      chan struct{}
+}
+
+func newErrorAggregator(ne newEvent, window time.Duration, burst int) *errorAggregator {
+	if window <= 0 {
+		return nil
+	}
+
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &errorAggregator{
+		ne:      ne,
+		window:  window,
+		burst:   burst,
+		m:       &sync.Mutex{},
+		entries: make(map[string]*errorAggregatorEntry),
+		stopC:   make(chan struct{}),
+	}
+}
+
+// allow records an occurrence of the given error, and returns whether it should be emitted. A nil aggregator allows
+// all errors.
+func (a *errorAggregator) allow(ctx context.Context, err error) bool {
+	if a == nil {
+		return true
+	}
+
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	now := clkm.MustGet(ctx).Now()
+	fingerprint := GetErrorFingerprint(err)
+	entry, ok := a.entries[fingerprint]
+
+	if ok && now.Sub(entry.windowStart) >= a.window {
+		// the sweeper has not caught up with this entry yet
+		a.maybeEmitSummary(ctx, now, fingerprint, entry)
+		ok = false
+	}
+
+	if !ok {
+		entry = &errorAggregatorEntry{windowStart: now}
+		a.entries[fingerprint] = entry
+		a.maybeStartSweeping(ctx)
+	}
+
+	entry.count++
+	entry.name = getErrorName(err)
+	entry.message = err.Error()
+
+	if entry.count > a.burst {
+		entry.suppressed++
+		return false
+	}
+
+	return true
+}
+
+// flush emits summary events for all entries with suppressed occurrences, starting new windows for them.
+func (a *errorAggregator) flush(ctx context.Context) {
+	if a == nil {
+		return
+	}
+
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.sweep(ctx, clkm.MustGet(ctx).Now(), true)
+}
+
+// stop stops the sweeper goroutine and waits for it to exit. Suppressed occurrences are still reported by flush.
+func (a *errorAggregator) stop() {
+	if a == nil {
+		return
+	}
+
+	a.m.Lock()
+
+	if a.isStopped {
+		a.m.Unlock()
+		return
+	}
+
+	a.isStopped = true
+	close(a.stopC)
+	doneC := a.doneC
+	a.m.Unlock()
+
+	if doneC != nil {
+		<-doneC
+	}
+}
+
+// maybeStartSweeping starts a goroutine that sweeps expired windows until there are no entries left or the aggregator
+// is stopped, if not running.
+func (a *errorAggregator) maybeStartSweeping(ctx context.Context) {
+	if a.isSweeping || a.isStopped {
+		return
+	}
+
+	a.isSweeping = true
+	a.doneC = make(chan struct{})
+	ctx = context.WithoutCancel(ctx)
+	clk := clkm.MustGet(ctx)
+	timer := clk.Timer(a.window)
+
+	go func(doneC chan struct{}) {
+		defer close(doneC)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-a.stopC:
+				return
+			case <-timer.C:
+				a.m.Lock()
+				now := clk.Now()
+				a.sweep(ctx, now, false)
+
+				if len(a.entries) == 0 {
+					a.isSweeping = false
+					a.m.Unlock()
+					return
+				}
+
+				timer.Reset(a.getNextExpiry(now))
+				a.m.Unlock()
+			}
+		}
+	}(a.doneC)
+}
+
+// getNextExpiry returns the time until the earliest window expires.
+func (a *errorAggregator) getNextExpiry(now time.Time) time.Duration {
+	next := a.window
+
+	for _, entry := range a.entries {
+		next = min(next, entry.windowStart.Add(a.window).Sub(now))
+	}
+
+	return max(next, 0)
+}
+
+func (a *errorAggregator) sweep(ctx context.Context, now time.Time, force bool) {
+	for fingerprint, entry := range a.entries {
+		if now.Sub(entry.windowStart) < a.window && (!force || entry.suppressed == 0) {
+			continue
+		}
+
+		a.maybeEmitSummary(ctx, now, fingerprint, entry)
+	}
+}
+
+// maybeEmitSummary emits a summary event for the given entry if it has suppressed occurrences, and removes it.
+func (a *errorAggregator) maybeEmitSummary(ctx context.Context, now time.Time, fingerprint string, entry *errorAggregatorEntry) {
+	if entry.suppressed > 0 {
+		e := newAttachableEvent(ctx, a.ne, "", "error-summary")
+		e.Timestamp = entry.windowStart
+		e.AddField("error", entry.name)
+		e.AddField("error.message", entry.message)
+		e.AddField("error.fingerprint", fingerprint)
+		e.AddField("error.summary.count", entry.count)
+		e.AddField("error.summary.suppressed", entry.suppressed)
+		e.AddField("error.summary.window_ms", float64(now.Sub(entry.windowStart))/float64(time.Millisecond))
+		errorz.MaybeMustWrap(e.Send())
+	}
+
+	delete(a.entries, fingerprint)
+}
@@ -0,0 +1,53 @@
+package logm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+)
+
+type DedupInternalSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestDedupInternalSuite(t *testing.T) {
+	fixturez.RunSuite(t, &DedupInternalSuite{})
+}
+
+func (s *DedupInternalSuite) TestErrorAggregator_Stop(ctx context.Context, g *WithT) {
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		Transmission: &transmission.DiscardSender{},
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	a := newErrorAggregator(client, time.Minute, 2)
+	g.Expect(a.allow(ctx, errorz.Errorf("e1"))).To(BeTrue())
+	g.Expect(a.isSweeping).To(BeTrue())
+
+	a.stop()
+	g.Expect(a.doneC).To(BeClosed())
+	a.stop()
+
+	// The sweeper is not restarted once stopped.
+	doneC := a.doneC
+	s.CLK.GetMock().Add(time.Minute)
+	a.flush(ctx)
+	g.Expect(a.entries).To(BeEmpty())
+	g.Expect(a.allow(ctx, errorz.Errorf("e2"))).To(BeTrue())
+	g.Expect(a.entries).To(HaveLen(1))
+	g.Expect(a.doneC).To(Equal(doneC))
+
+	var nilAggregator *errorAggregator
+	g.Expect(nilAggregator.stop).ToNot(Panic())
+}
@@ -0,0 +1,102 @@
+package logm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type DedupSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestDedupSuite(t *testing.T) {
+	fixturez.RunSuite(t, &DedupSuite{})
+}
+
+func (s *DedupSuite) TestGetErrorFingerprint(g *WithT) {
+	newErr := func(msg string) error {
+		return errorz.Errorf("%v", msg)
+	}
+
+	g.Expect(logm.GetErrorFingerprint(newErr("e1"))).To(Equal(logm.GetErrorFingerprint(newErr("e2"))))
+	g.Expect(logm.GetErrorFingerprint(newErr("e1"))).ToNot(Equal(logm.GetErrorFingerprint(errorz.Errorf("e1"))))
+	g.Expect(logm.GetErrorFingerprint(newErr("e1"))).
+		ToNot(Equal(logm.GetErrorFingerprint(newTestCompleteError("e1", "name", 0))))
+}
+
+func (s *DedupSuite) TestErrorDedup(ctx context.Context, g *WithT) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	ctx = logm.NewSingletonInjector(logm.NewRawLogFromClient(client, logm.RawLogErrorDedup(time.Minute, 2)))(ctx)
+
+	emit := func(msg string) {
+		ctx, end := logm.MustGet(ctx).Begin("span")
+		defer end()
+		logm.MustGet(ctx).EmitError(errorz.Errorf("%v", msg))
+	}
+
+	errorEvents := func() []string {
+		events := make([]string, 0)
+		for _, e := range sender.GetEvents() {
+			if msg, ok := e.Data["error.message"].(string); ok {
+				events = append(events, e.Data["name"].(string)+":"+msg)
+			}
+		}
+		return events
+	}
+
+	for i := 0; i < 5; i++ {
+		emit(fmt.Sprintf("e%v", i))
+	}
+	g.Expect(errorEvents()).To(HaveExactElements("error:e0", "error:e1"))
+	g.Expect(sender.GetEvents()).To(ContainElement(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Data": And(
+			HaveKeyWithValue("name", "span"),
+			HaveKeyWithValue("error", true)),
+	}))))
+
+	// The summary is emitted when the window expires, without waiting for the next error.
+	s.CLK.GetMock().Add(time.Minute)
+	g.Eventually(errorEvents, "1s", "10ms").Should(HaveExactElements("error:e0", "error:e1", "error-summary:e4"))
+
+	emit("e5")
+	g.Expect(errorEvents()).To(HaveExactElements("error:e0", "error:e1", "error-summary:e4", "error:e5"))
+	g.Expect(sender.GetEvents()).To(ContainElement(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Data": And(
+			HaveKeyWithValue("name", "error-summary"),
+			HaveKeyWithValue("error", "generic"),
+			HaveKeyWithValue("error.fingerprint", sender.GetEvents()[0].Data["error.fingerprint"]),
+			HaveKeyWithValue("error.summary.count", 5),
+			HaveKeyWithValue("error.summary.suppressed", 3),
+			HaveKeyWithValue("error.summary.window_ms", float64(time.Minute/time.Millisecond))),
+	}))))
+
+	emit("e6")
+	emit("e7")
+	logm.MustGet(ctx).Flush()
+	g.Expect(errorEvents()).To(HaveExactElements("error:e0", "error:e1", "error-summary:e4", "error:e5", "error:e6", "error-summary:e7"))
+
+	emit("e8")
+	g.Expect(errorEvents()).To(HaveLen(7))
+}
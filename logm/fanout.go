@@ -0,0 +1,349 @@
+package logm
+
+import (
+	"encoding"
+	"hash/fnv"
+	"maps"
+	"math/rand/v2"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	fanOutSinkResponsesLen = 1024
+)
+
+var (
+	_ transmission.Sender      = (*FanOutSink)(nil)
+	_ SinkRoute                = (SinkRouteFunc)(nil)
+	_ encoding.TextUnmarshaler = (*SinkRouteSpec)(nil)
+)
+
+// SinkRoute describes a routing predicate for a [*SinkDestination].
+type SinkRoute interface {
+	Match(e *transmission.Event) bool
+}
+
+// SinkRouteFunc is a shorthand for SinkRoute.
+type SinkRouteFunc func(e *transmission.Event) bool
+
+// Match implements the SinkRoute interface.
+func (f SinkRouteFunc) Match(e *transmission.Event) bool {
+	return f(e)
+}
+
+// SinkRouteAll matches events that match all the given routes.
+func SinkRouteAll(routes ...SinkRoute) SinkRouteFunc {
+	return func(e *transmission.Event) bool {
+		for _, route := range routes {
+			if !route.Match(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// SinkRouteAny matches events that match at least one of the given routes.
+func SinkRouteAny(routes ...SinkRoute) SinkRouteFunc {
+	return func(e *transmission.Event) bool {
+		for _, route := range routes {
+			if route.Match(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SinkRouteLevel matches events whose level is at least as severe as the given one. Spans are considered debug level,
+// or error level if their error flag is set.
+func SinkRouteLevel(level logrus.Level) SinkRouteFunc {
+	return func(e *transmission.Event) bool {
+		return getEventLevel(e.Data) <= level
+	}
+}
+
+// SinkRouteName matches events whose name matches one of the given [path.Match] patterns.
+func SinkRouteName(patterns ...string) SinkRouteFunc {
+	return func(e *transmission.Event) bool {
+		name, _ := e.Data["name"].(string)
+
+		for _, pattern := range patterns {
+			if ok, err := path.Match(pattern, name); err == nil && ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SinkRouteHasFields matches events that have all the given fields.
+func SinkRouteHasFields(keys ...string) SinkRouteFunc {
+	return func(e *transmission.Event) bool {
+		for _, k := range keys {
+			if _, ok := e.Data[k]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// SinkRouteSample matches 1 in rate events. Events belonging to the same trace are sampled together.
+func SinkRouteSample(rate uint) SinkRouteFunc {
+	return func(e *transmission.Event) bool {
+		if rate <= 1 {
+			return true
+		}
+
+		if traceID, ok := e.Data["trace.trace_id"].(string); ok && traceID != "" {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(traceID))
+			return h.Sum32()%uint32(rate) == 0
+		}
+
+		return rand.UintN(rate) == 0
+	}
+}
+
+// SinkRouteSpec describes a [SinkRoute] as text, for configuration. It is a list of terms separated by ";", all of
+// which must match:
+//   - "level=<level>": see [SinkRouteLevel], e.g. "level=warning"
+//   - "name=<pattern>[,<pattern>...]": see [SinkRouteName], e.g. "name=pgm.*,http.*"
+//   - "fields=<key>[,<key>...]": see [SinkRouteHasFields], e.g. "fields=error"
+//   - "sample=<rate>": see [SinkRouteSample], e.g. "sample=10"
+//
+// An empty SinkRouteSpec matches all events.
+type SinkRouteSpec string
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (s *SinkRouteSpec) UnmarshalText(text []byte) error {
+	if _, err := parseSinkRouteSpec(string(text)); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	*s = SinkRouteSpec(text)
+	return nil
+}
+
+// String implements the [fmt.Stringer] interface.
+func (s *SinkRouteSpec) String() string {
+	return string(*s)
+}
+
+// MustGetRoute returns the described [SinkRoute], or nil if the spec is empty.
+func (s *SinkRouteSpec) MustGetRoute() SinkRoute {
+	route, err := parseSinkRouteSpec(string(*s))
+	errorz.MaybeMustWrap(err)
+	return route
+}
+
+func parseSinkRouteSpec(spec string) (SinkRoute, error) {
+	routes := make([]SinkRoute, 0)
+
+	for _, term := range strings.Split(spec, ";") {
+		if term = strings.TrimSpace(term); term == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(term, "=")
+		if !ok || v == "" {
+			return nil, errorz.Errorf("invalid sink route term: '%s'", term)
+		}
+
+		switch k = strings.TrimSpace(k); k {
+		case "level":
+			level, err := logrus.ParseLevel(strings.TrimSpace(v))
+			if err != nil {
+				return nil, errorz.Wrap(err, errorz.Errorf("invalid sink route term: '%s'", term))
+			}
+			routes = append(routes, SinkRouteLevel(level))
+		case "name":
+			routes = append(routes, SinkRouteName(splitSinkRouteValues(v)...))
+		case "fields":
+			routes = append(routes, SinkRouteHasFields(splitSinkRouteValues(v)...))
+		case "sample":
+			rate, err := strconv.ParseUint(strings.TrimSpace(v), 10, 32)
+			if err != nil {
+				return nil, errorz.Wrap(err, errorz.Errorf("invalid sink route term: '%s'", term))
+			}
+			routes = append(routes, SinkRouteSample(uint(rate)))
+		default:
+			return nil, errorz.Errorf("invalid sink route term: '%s'", term)
+		}
+	}
+
+	if len(routes) == 0 {
+		return nil, nil
+	}
+
+	return SinkRouteAll(routes...), nil
+}
+
+func splitSinkRouteValues(v string) []string {
+	values := make([]string, 0)
+
+	for _, value := range strings.Split(v, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// SinkDestination describes a destination of a [*FanOutSink].
+type SinkDestination struct {
+	Name   string
+	Sender transmission.Sender
+	Route  SinkRoute // nil matches all events
+}
+
+// FanOutSink is a [transmission.Sender] that forwards each event to all the destinations whose route matches it.
+// Any [transmission.Sender] can be used as a destination, e.g. a [*Sink] with a nil sender for logrus output, a
+// [*transmission.Honeycomb], a [*transmission.WriterSender] for JSON lines, or a tlogm.MockSender for test capture.
+// Responses from all destinations are merged, so a single event may produce more than one response. Each destination
+// receives its own shallow copy of the event, so destinations may set fields (e.g. Metadata) without affecting each
+// other.
+type FanOutSink struct {
+	destinations []*SinkDestination
+	c            chan transmission.Response
+	m            *sync.RWMutex
+	isStopped    bool
+	stopC        chan struct{}
+	wg           *sync.WaitGroup
+}
+
+// NewFanOutSink initializes a new [*FanOutSink].
+func NewFanOutSink(destinations ...*SinkDestination) *FanOutSink {
+	for _, d := range destinations {
+		errorz.Assertf(d != nil && d.Sender != nil, "destination or destination sender is nil")
+	}
+
+	return &FanOutSink{
+		destinations: destinations,
+		c:            make(chan transmission.Response, fanOutSinkResponsesLen),
+		m:            &sync.RWMutex{},
+		wg:           &sync.WaitGroup{},
+	}
+}
+
+// Add implements the [transmission.Sender] interface.
+func (s *FanOutSink) Add(e *transmission.Event) {
+	if e == nil {
+		return
+	}
+
+	for _, d := range s.destinations {
+		if d.Route == nil || d.Route.Match(e) {
+			dE := *e
+			dE.Data = maps.Clone(e.Data)
+			d.Sender.Add(&dE)
+		}
+	}
+}
+
+// Start implements the [transmission.Sender] interface.
+func (s *FanOutSink) Start() error {
+	for _, d := range s.destinations {
+		if err := d.Sender.Start(); err != nil {
+			return errorz.Wrap(err)
+		}
+	}
+
+	s.stopC = make(chan struct{})
+
+	for _, d := range s.destinations {
+		if c := d.Sender.TxResponses(); c != nil {
+			s.wg.Add(1)
+			go s.forwardResponses(c)
+		}
+	}
+
+	return nil
+}
+
+func (s *FanOutSink) forwardResponses(c chan transmission.Response) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.stopC:
+			return
+		case r, ok := <-c:
+			if !ok {
+				return
+			}
+			s.SendResponse(r)
+		}
+	}
+}
+
+// Stop implements the [transmission.Sender] interface. It closes the merged responses channel once all destinations
+// are stopped. Calling Stop more than once (or without Start) is a no-op.
+func (s *FanOutSink) Stop() error {
+	if s.stopC == nil {
+		return nil
+	}
+
+	var err error
+
+	for _, d := range s.destinations {
+		if dErr := d.Sender.Stop(); dErr != nil && err == nil {
+			err = dErr
+		}
+	}
+
+	close(s.stopC)
+	s.wg.Wait()
+	s.stopC = nil
+
+	s.m.Lock()
+	s.isStopped = true
+	close(s.c)
+	s.m.Unlock()
+
+	return errorz.MaybeWrap(err)
+}
+
+// Flush implements the [transmission.Sender] interface.
+func (s *FanOutSink) Flush() error {
+	var err error
+
+	for _, d := range s.destinations {
+		if dErr := d.Sender.Flush(); dErr != nil && err == nil {
+			err = dErr
+		}
+	}
+
+	return errorz.MaybeWrap(err)
+}
+
+// TxResponses implements the [transmission.Sender] interface.
+func (s *FanOutSink) TxResponses() chan transmission.Response {
+	return s.c
+}
+
+// SendResponse implements the [transmission.Sender] interface.
+func (s *FanOutSink) SendResponse(response transmission.Response) bool {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	if s.isStopped {
+		return true
+	}
+
+	select {
+	case s.c <- response:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,155 @@
+package logm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type FanOutSuite struct {
+	// intentionally empty
+}
+
+func TestFanOutSuite(t *testing.T) {
+	fixturez.RunSuite(t, &FanOutSuite{})
+}
+
+func (*FanOutSuite) TestFanOutSink(g *WithT) {
+	buf := &bytes.Buffer{}
+	errors := &transmission.WriterSender{W: buf}
+	all := tlogm.NewMockSender()
+	spans := tlogm.NewMockSender()
+
+	sink := logm.NewFanOutSink(
+		&logm.SinkDestination{
+			Name:   "errors",
+			Sender: errors,
+			Route:  logm.SinkRouteLevel(logrus.ErrorLevel),
+		},
+		&logm.SinkDestination{
+			Name:   "all",
+			Sender: all,
+		},
+		&logm.SinkDestination{
+			Name:   "spans",
+			Sender: spans,
+			Route: logm.SinkRouteAll(
+				logm.SinkRouteName("pgm.*"),
+				logm.SinkRouteHasFields("duration_ms")),
+		})
+	g.Expect(sink.Start()).To(Succeed())
+
+	sink.Add(&transmission.Event{Data: map[string]any{"name": "n1", "info": "info"}})
+	sink.Add(&transmission.Event{Data: map[string]any{"name": "n2", "error": "error"}})
+	sink.Add(&transmission.Event{Data: map[string]any{"name": "pgm.Query", "duration_ms": 1.0}})
+	sink.Add(&transmission.Event{Data: map[string]any{"name": "pgm.Exec"}})
+	sink.Add(nil)
+	g.Expect(sink.Flush()).To(Succeed())
+
+	g.Expect(all.GetEvents()).To(HaveLen(4))
+	g.Expect(spans.GetEvents()).To(HaveExactElements(HaveField("Data", HaveKeyWithValue("name", "pgm.Query"))))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	g.Expect(lines).To(HaveLen(1))
+	line := map[string]any{}
+	g.Expect(json.Unmarshal([]byte(lines[0]), &line)).To(Succeed())
+	g.Expect(line).To(HaveKeyWithValue("data", HaveKeyWithValue("name", "n2")))
+
+	g.Expect(sink.SendResponse(transmission.Response{Metadata: "m1"})).To(BeFalse())
+	g.Eventually(sink.TxResponses(), "1s", "10ms").Should(Receive(HaveField("Metadata", "m1")))
+	g.Expect(sink.Stop()).To(Succeed())
+	g.Eventually(sink.TxResponses(), "1s", "10ms").Should(BeClosed())
+	g.Expect(sink.SendResponse(transmission.Response{Metadata: "m2"})).To(BeTrue())
+	g.Expect(sink.Stop()).To(Succeed())
+}
+
+func (*FanOutSuite) TestFanOutSink_Copies(g *WithT) {
+	d1 := tlogm.NewMockSender()
+	d2 := tlogm.NewMockSender()
+
+	sink := logm.NewFanOutSink(
+		&logm.SinkDestination{Name: "d1", Sender: d1},
+		&logm.SinkDestination{Name: "d2", Sender: d2})
+	g.Expect(sink.Start()).To(Succeed())
+
+	e := &transmission.Event{Data: map[string]any{"k": "v"}, Metadata: "m"}
+	sink.Add(e)
+
+	d1.GetEvents()[0].Metadata = "d1"
+	d1.GetEvents()[0].Data["k"] = "d1"
+
+	g.Expect(e.Metadata).To(Equal("m"))
+	g.Expect(e.Data).To(HaveKeyWithValue("k", "v"))
+	g.Expect(d2.GetEvents()[0].Metadata).To(Equal("m"))
+	g.Expect(d2.GetEvents()[0].Data).To(HaveKeyWithValue("k", "v"))
+	g.Expect(sink.Stop()).To(Succeed())
+}
+
+func (*FanOutSuite) TestSinkRouteSpec(g *WithT) {
+	newEvent := func(data map[string]any) *transmission.Event {
+		return &transmission.Event{Data: data}
+	}
+
+	spec := logm.SinkRouteSpec("")
+	g.Expect(spec.UnmarshalText([]byte(""))).To(Succeed())
+	g.Expect(spec.MustGetRoute()).To(BeNil())
+
+	g.Expect(spec.UnmarshalText([]byte("level=warning; name=a.*,b ;fields=k;sample=1"))).To(Succeed())
+	g.Expect(spec.String()).To(Equal("level=warning; name=a.*,b ;fields=k;sample=1"))
+	route := spec.MustGetRoute()
+	g.Expect(route.Match(newEvent(map[string]any{"name": "a.x", "error": "e", "k": 1}))).To(BeTrue())
+	g.Expect(route.Match(newEvent(map[string]any{"name": "b", "warning": "w", "k": 1}))).To(BeTrue())
+	g.Expect(route.Match(newEvent(map[string]any{"name": "b", "info": "i", "k": 1}))).To(BeFalse())
+	g.Expect(route.Match(newEvent(map[string]any{"name": "c", "error": "e", "k": 1}))).To(BeFalse())
+	g.Expect(route.Match(newEvent(map[string]any{"name": "b", "error": "e"}))).To(BeFalse())
+
+	for _, invalid := range []string{"invalid", "level=", "level=invalid", "sample=x", "unknown=v"} {
+		g.Expect(spec.UnmarshalText([]byte(invalid))).To(MatchError(ContainSubstring("invalid sink route term")), invalid)
+	}
+
+	g.Expect(spec.String()).To(Equal("level=warning; name=a.*,b ;fields=k;sample=1"))
+	spec = "invalid"
+	g.Expect(func() { spec.MustGetRoute() }).To(Panic())
+}
+
+func (*FanOutSuite) TestSinkRoutes(g *WithT) {
+	newEvent := func(data map[string]any) *transmission.Event {
+		return &transmission.Event{Data: data}
+	}
+
+	g.Expect(logm.SinkRouteLevel(logrus.WarnLevel)(newEvent(map[string]any{"warning": "w"}))).To(BeTrue())
+	g.Expect(logm.SinkRouteLevel(logrus.WarnLevel)(newEvent(map[string]any{"error": "e"}))).To(BeTrue())
+	g.Expect(logm.SinkRouteLevel(logrus.WarnLevel)(newEvent(map[string]any{"info": "i"}))).To(BeFalse())
+	g.Expect(logm.SinkRouteLevel(logrus.WarnLevel)(newEvent(map[string]any{"name": "span"}))).To(BeFalse())
+
+	g.Expect(logm.SinkRouteName("a.*", "b")(newEvent(map[string]any{"name": "a.x"}))).To(BeTrue())
+	g.Expect(logm.SinkRouteName("a.*", "b")(newEvent(map[string]any{"name": "b"}))).To(BeTrue())
+	g.Expect(logm.SinkRouteName("a.*", "b")(newEvent(map[string]any{"name": "c"}))).To(BeFalse())
+	g.Expect(logm.SinkRouteName("a.*")(newEvent(map[string]any{}))).To(BeFalse())
+
+	g.Expect(logm.SinkRouteHasFields("k1", "k2")(newEvent(map[string]any{"k1": 1, "k2": 2}))).To(BeTrue())
+	g.Expect(logm.SinkRouteHasFields("k1", "k2")(newEvent(map[string]any{"k1": 1}))).To(BeFalse())
+
+	g.Expect(logm.SinkRouteAny(logm.SinkRouteName("a"), logm.SinkRouteName("b"))(newEvent(map[string]any{"name": "b"}))).To(BeTrue())
+	g.Expect(logm.SinkRouteAny(logm.SinkRouteName("a"), logm.SinkRouteName("b"))(newEvent(map[string]any{"name": "c"}))).To(BeFalse())
+
+	g.Expect(logm.SinkRouteSample(1)(newEvent(map[string]any{}))).To(BeTrue())
+
+	matched := 0
+	for i := 0; i < 1000; i++ {
+		e := newEvent(map[string]any{"trace.trace_id": "trace-id"})
+		if logm.SinkRouteSample(10)(e) {
+			matched++
+		}
+	}
+	g.Expect(matched).To(Or(Equal(0), Equal(1000)))
+}
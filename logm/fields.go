@@ -3,8 +3,10 @@ package logm
 import (
 	"context"
 	"fmt"
+	"maps"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,6 +28,8 @@ type errorMetadataKey int
 
 const (
 	isEmittedErrorMetadataKey errorMetadataKey = iota
+	panicErrorMetadataKey
+	errorReferenceErrorMetadataKey
 )
 
 var (
@@ -106,10 +110,14 @@ func maybeAddNumericField[T fieldValueNumeric](af AddField, key string, value T)
 	}
 }
 
-func addLocationFields(af AddField, framesSource error) {
-	frames := memz.FilterSlice(errorz.GetFrames(framesSource), func(f *errorz.Frame) bool {
+func getLocationFrames(framesSource error) []*errorz.Frame {
+	return memz.FilterSlice(errorz.GetFrames(framesSource), func(f *errorz.Frame) bool {
 		return f.ShortPackage != "logm"
 	})
+}
+
+func addLocationFields(af AddField, framesSource error) {
+	frames := getLocationFrames(framesSource)
 
 	if len(frames) > 0 {
 		maybeAddLenField(af, "", "location", frames[0].Summary)
@@ -139,7 +147,9 @@ func addMetadataFields(af AddField, prefix string, metadata map[string]any) {
 		}
 	}
 
-	for k, v := range metadata {
+	for _, k := range slices.Sorted(maps.Keys(metadata)) {
+		v := metadata[k]
+
 		if k == StandardKeyParams || k == StandardKeySecondaryParams {
 			k = StandardKeyParams
 
@@ -170,34 +180,44 @@ func maybeFlattenMetadataValue(v any) (map[string]any, bool) {
 	return m, true
 }
 
-func addDebugFields(af AddField, format string, o *emitOptions) {
-	addLocationFields(af, nil)
-	af.AddField("debug", true)
-	maybeAddLenField(af, "", "debug.message", fmt.Sprintf(format, o.args...))
-	addMetadataFields(af, "debug.metadata", o.metadata)
+func addDebugFields(af AddField, limits *PayloadLimits, format string, o *emitOptions) {
+	pl := newPayloadLimiter(af, limits)
+	rf := pl.required()
+	addLocationFields(rf, nil)
+	rf.AddField("debug", true)
+	maybeAddLenField(rf, "", "debug.message", fmt.Sprintf(format, o.args...))
+	addMetadataFields(pl, "debug.metadata", o.metadata)
+	pl.finish()
 }
 
-func addInfoFields(af AddField, format string, o *emitOptions) {
-	addLocationFields(af, nil)
-	af.AddField("info", true)
-	maybeAddLenField(af, "", "info.message", fmt.Sprintf(format, o.args...))
-	addMetadataFields(af, "info.metadata", o.metadata)
+func addInfoFields(af AddField, limits *PayloadLimits, format string, o *emitOptions) {
+	pl := newPayloadLimiter(af, limits)
+	rf := pl.required()
+	addLocationFields(rf, nil)
+	rf.AddField("info", true)
+	maybeAddLenField(rf, "", "info.message", fmt.Sprintf(format, o.args...))
+	addMetadataFields(pl, "info.metadata", o.metadata)
+	pl.finish()
 }
 
-func addWarningFields(af AddField, err error) {
-	addLocationFields(af, err)
-	af.AddField("warning", getWarningName(err))
-	af.AddField("warning.message", err.Error())
-	af.AddField("warning.dump", errorz.SDump(err))
-	maybeAddNumericField(af, "warning.status", errorz.GetHTTPStatus(err, 0))
+func addWarningFields(af AddField, limits *PayloadLimits, err error) {
+	rf := newPayloadLimiter(af, limits).required()
+	addLocationFields(rf, err)
+	rf.AddField("warning", getWarningName(err))
+	rf.AddField("warning.message", err.Error())
+	rf.AddField("warning.dump", errorz.SDump(err))
+	maybeAddNumericField(rf, "warning.status", errorz.GetHTTPStatus(err, 0))
 }
 
-func addErrorFields(af AddField, err error) {
-	addLocationFields(af, err)
-	af.AddField("error", getErrorName(err))
-	af.AddField("error.message", err.Error())
-	af.AddField("error.dump", errorz.SDump(err))
-	maybeAddNumericField(af, "error.status", errorz.GetHTTPStatus(err, 0))
+func addErrorFields(af AddField, limits *PayloadLimits, err error) {
+	rf := newPayloadLimiter(af, limits).required()
+	addLocationFields(rf, err)
+	rf.AddField("error", getErrorName(err))
+	rf.AddField("error.message", err.Error())
+	rf.AddField("error.dump", errorz.SDump(err))
+	rf.AddField("error.fingerprint", GetErrorFingerprint(err))
+	maybeAddNumericField(rf, "error.status", errorz.GetHTTPStatus(err, 0))
+	addPanicFields(rf, err)
 }
 
 func newAttachableEvent(ctx context.Context, ne newEvent, attachedSpanID, name string) *libhoney.Event {
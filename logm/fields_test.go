@@ -40,6 +40,10 @@ func (t *testAddField) AddField(k string, v any) {
 	t.fields[k] = v
 }
 
+func (t *testAddField) Fields() map[string]any {
+	return t.fields
+}
+
 type testNewEvent struct {
 	events []*libhoney.Event
 }
@@ -266,7 +270,7 @@ func (*FieldsSuite) TestMaybeFlattenMetadataValue(g *WithT) {
 
 func (*FieldsSuite) TestAddDebugFields(g *WithT) {
 	af := newTestAddField()
-	addDebugFields(af, "fmt: %v", newEmitOptions(EmitA(1), EmitM("k", "v")))
+	addDebugFields(af, nil, "fmt: %v", newEmitOptions(EmitA(1), EmitM("k", "v")))
 
 	g.Expect(af.fields).To(And(
 		HaveKeyWithValue("location", Not(BeEmpty())),
@@ -278,7 +282,7 @@ func (*FieldsSuite) TestAddDebugFields(g *WithT) {
 
 func (*FieldsSuite) TestAddInfoFields(g *WithT) {
 	af := newTestAddField()
-	addInfoFields(af, "fmt: %v", newEmitOptions(EmitA(1), EmitM("k", "v")))
+	addInfoFields(af, nil, "fmt: %v", newEmitOptions(EmitA(1), EmitM("k", "v")))
 
 	g.Expect(af.fields).To(And(
 		HaveKeyWithValue("location", Not(BeEmpty())),
@@ -291,7 +295,7 @@ func (*FieldsSuite) TestAddInfoFields(g *WithT) {
 func (*FieldsSuite) TestAddWarningFields(g *WithT) {
 	{
 		af := newTestAddField()
-		addWarningFields(af, errorz.Errorf("test error"))
+		addWarningFields(af, nil, errorz.Errorf("test error"))
 
 		g.Expect(af.fields).To(And(
 			HaveKeyWithValue("location", Not(BeEmpty())),
@@ -303,7 +307,7 @@ func (*FieldsSuite) TestAddWarningFields(g *WithT) {
 	}
 	{
 		af := newTestAddField()
-		addWarningFields(af, newTestCompleteError("test error", "name", http.StatusBadRequest))
+		addWarningFields(af, nil, newTestCompleteError("test error", "name", http.StatusBadRequest))
 
 		g.Expect(af.fields).To(And(
 			HaveKeyWithValue("location", Not(BeEmpty())),
@@ -318,19 +322,20 @@ func (*FieldsSuite) TestAddWarningFields(g *WithT) {
 func (*FieldsSuite) TestAddErrorFields(g *WithT) {
 	{
 		af := newTestAddField()
-		addErrorFields(af, errorz.Errorf("test error"))
+		addErrorFields(af, nil, errorz.Errorf("test error"))
 
 		g.Expect(af.fields).To(And(
 			HaveKeyWithValue("location", Not(BeEmpty())),
 			HaveKeyWithValue("error", "generic"),
 			HaveKeyWithValue("error.message", "test error"),
 			HaveKeyWithValue("error.dump", HavePrefix("(errorz.dump)")),
+			HaveKeyWithValue("error.fingerprint", HaveLen(32)),
 			Not(HaveKey("error.status")),
 		))
 	}
 	{
 		af := newTestAddField()
-		addErrorFields(af, newTestCompleteError("test error", "name", http.StatusBadRequest))
+		addErrorFields(af, nil, newTestCompleteError("test error", "name", http.StatusBadRequest))
 
 		g.Expect(af.fields).To(And(
 			HaveKeyWithValue("location", Not(BeEmpty())),
@@ -340,6 +345,17 @@ func (*FieldsSuite) TestAddErrorFields(g *WithT) {
 			HaveKeyWithValue("error.status", http.StatusBadRequest),
 		))
 	}
+	{
+		af := newTestAddField()
+		addErrorFields(af, &PayloadLimits{MaxStringBytes: 8}, errorz.Errorf("test error"))
+
+		g.Expect(af.fields).To(And(
+			HaveKeyWithValue("error.message", "test err"),
+			HaveKeyWithValue("error.message.truncated", true),
+			HaveKeyWithValue("error.dump", "(errorz."),
+			HaveKeyWithValue("error.dump.truncated", true),
+		))
+	}
 }
 
 func (*FieldsSuite) TestNewAttachableEvent(ctx context.Context, g *WithT) {
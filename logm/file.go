@@ -0,0 +1,506 @@
+package logm
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/cfgm"
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+const (
+	fileSenderResponsesLen = 1024
+	rotatingFileTimeFormat = "20060102T150405.000000000"
+	rotatingFileGzipExt    = ".gz"
+)
+
+var (
+	_ io.WriteCloser           = (*RotatingFile)(nil)
+	_ transmission.Sender      = (*FileSender)(nil)
+	_ encoding.TextUnmarshaler = (*FileSyncPolicy)(nil)
+)
+
+// FileSyncPolicy describes when a [*RotatingFile] is synced to disk.
+type FileSyncPolicy string
+
+// Known FileSyncPolicy values.
+const (
+	FileSyncPolicyNever    FileSyncPolicy = "never"
+	FileSyncPolicyAlways   FileSyncPolicy = "always"
+	FileSyncPolicyInterval FileSyncPolicy = "interval"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (p *FileSyncPolicy) UnmarshalText(text []byte) error {
+	switch v := FileSyncPolicy(text); v {
+	case "":
+		*p = FileSyncPolicyNever
+		return nil
+	case FileSyncPolicyNever, FileSyncPolicyAlways, FileSyncPolicyInterval:
+		*p = v
+		return nil
+	default:
+		return errorz.Errorf("invalid value for FileSyncPolicy: '%s'", v)
+	}
+}
+
+// String implements the [fmt.Stringer] interface.
+func (p *FileSyncPolicy) String() string {
+	return string(*p)
+}
+
+// Default values for [RotatingFileConfig].
+const (
+	DefaultRotatingFileSyncInterval = time.Second
+)
+
+// RotatingFileConfig describes the configuration for a [*RotatingFile].
+type RotatingFileConfig struct {
+	// Path is the path of the active file. Rotated files are stored next to it, with a timestamp suffix.
+	Path string
+	// MaxBytes is the size after which the file is rotated, zero disables size-based rotation.
+	MaxBytes int64
+	// RotateInterval is the age after which the file is rotated, zero disables time-based rotation.
+	RotateInterval time.Duration
+	// Compress determines whether rotated files are compressed using gzip. Compression happens in the background.
+	Compress bool
+	// MaxBackups is the maximum number of rotated files to retain, zero retains all of them.
+	MaxBackups int
+	// SyncPolicy determines when the file is synced to disk.
+	SyncPolicy FileSyncPolicy
+	// SyncInterval is used by [FileSyncPolicyInterval]: the file is synced on write if this much time has elapsed.
+	SyncInterval time.Duration
+}
+
+// RotatingFile is an [io.WriteCloser] that appends to a file, rotating it based on size and age. Each write is
+// guaranteed to end up in a single file. Rotation is evaluated on write using the [clkm.Clock] from context. Rotated
+// files are compressed (if enabled) and pruned in the background, so that writes are not blocked.
+type RotatingFile struct {
+	clk         clkm.Clock
+	cfg         RotatingFileConfig
+	backupRegex *regexp.Regexp
+	m           *sync.Mutex
+	file        *os.File
+	size        int64
+	openedAt    time.Time
+	syncedAt    time.Time
+	bm          *sync.Mutex
+	bwg         *sync.WaitGroup
+	bErr        error
+}
+
+// NewRotatingFile initializes a new [*RotatingFile]. The file is opened lazily on first write.
+func NewRotatingFile(ctx context.Context, cfg *RotatingFileConfig) *RotatingFile {
+	errorz.Assertf(cfg != nil && cfg.Path != "", "path is empty")
+
+	f := &RotatingFile{
+		clk: clkm.MustGet(ctx),
+		cfg: *cfg,
+		backupRegex: regexp.MustCompile(
+			`^` + regexp.QuoteMeta(filepath.Base(cfg.Path)) + `\.\d{8}T\d{6}\.\d{9}\.\d{3,}(` + regexp.QuoteMeta(rotatingFileGzipExt) + `)?$`),
+		m:   &sync.Mutex{},
+		bm:  &sync.Mutex{},
+		bwg: &sync.WaitGroup{},
+	}
+
+	if f.cfg.SyncPolicy == "" {
+		f.cfg.SyncPolicy = FileSyncPolicyNever
+	}
+
+	if f.cfg.SyncInterval <= 0 {
+		f.cfg.SyncInterval = DefaultRotatingFileSyncInterval
+	}
+
+	return f
+}
+
+// Write implements the [io.Writer] interface.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	now := f.clk.Now()
+
+	if f.file != nil && f.mustRotate(now, int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, errorz.Wrap(err)
+		}
+	}
+
+	if f.file == nil {
+		if err := f.open(now); err != nil {
+			return 0, errorz.Wrap(err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, errorz.Wrap(err)
+	}
+
+	switch f.cfg.SyncPolicy {
+	case FileSyncPolicyAlways:
+		return n, errorz.MaybeWrap(f.sync(now))
+	case FileSyncPolicyInterval:
+		if now.Sub(f.syncedAt) >= f.cfg.SyncInterval {
+			return n, errorz.MaybeWrap(f.sync(now))
+		}
+	}
+
+	return n, nil
+}
+
+// Rotate rotates the file, if open.
+func (f *RotatingFile) Rotate() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	return errorz.MaybeWrap(f.rotate())
+}
+
+// Sync syncs the file to disk, if open.
+func (f *RotatingFile) Sync() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	return errorz.MaybeWrap(f.sync(f.clk.Now()))
+}
+
+// Close implements the [io.Closer] interface. The file is synced first unless the sync policy is
+// [FileSyncPolicyNever]. It waits for background compression and pruning to complete, returning their first error (if
+// any) since the previous call. Subsequent writes reopen it.
+func (f *RotatingFile) Close() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	var err error
+
+	if f.file != nil {
+		err = f.close()
+	}
+
+	f.bwg.Wait()
+	f.bm.Lock()
+	defer f.bm.Unlock()
+
+	if err == nil {
+		err = f.bErr
+	}
+
+	f.bErr = nil
+	return errorz.MaybeWrap(err)
+}
+
+func (f *RotatingFile) mustRotate(now time.Time, n int64) bool {
+	if f.cfg.MaxBytes > 0 && f.size > 0 && f.size+n > f.cfg.MaxBytes {
+		return true
+	}
+
+	return f.cfg.RotateInterval > 0 && now.Sub(f.openedAt) >= f.cfg.RotateInterval
+}
+
+func (f *RotatingFile) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(f.cfg.Path), 0700); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	file, err := os.OpenFile(f.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return errorz.Wrap(err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = now
+	f.syncedAt = now
+	return nil
+}
+
+func (f *RotatingFile) sync(now time.Time) error {
+	f.syncedAt = now
+	return errorz.MaybeWrap(f.file.Sync())
+}
+
+func (f *RotatingFile) close() error {
+	var err error
+
+	if f.cfg.SyncPolicy != FileSyncPolicyNever {
+		err = f.file.Sync()
+	}
+
+	if closeErr := f.file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	f.file = nil
+	f.size = 0
+	return errorz.MaybeWrap(err)
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.close(); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	backupPath := f.getBackupPath()
+
+	if err := os.Rename(f.cfg.Path, backupPath); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	if !f.cfg.Compress && f.cfg.MaxBackups <= 0 {
+		return nil
+	}
+
+	f.bwg.Add(1)
+
+	go func() {
+		defer f.bwg.Done()
+
+		f.bm.Lock()
+		defer f.bm.Unlock()
+
+		var err error
+
+		if f.cfg.Compress {
+			err = compressRotatedFile(backupPath)
+		}
+
+		if err == nil {
+			err = f.prune()
+		}
+
+		if err != nil && f.bErr == nil {
+			f.bErr = errorz.Wrap(err)
+		}
+	}()
+
+	return nil
+}
+
+func (f *RotatingFile) getBackupPath() string {
+	prefix := f.cfg.Path + "." + f.clk.Now().UTC().Format(rotatingFileTimeFormat)
+
+	for i := 0; ; i++ {
+		backupPath := fmt.Sprintf("%v.%03d", prefix, i)
+
+		if !fileExists(backupPath) && !fileExists(backupPath+rotatingFileGzipExt) {
+			return backupPath
+		}
+	}
+}
+
+func (f *RotatingFile) prune() error {
+	if f.cfg.MaxBackups <= 0 {
+		return nil
+	}
+
+	backupPaths, err := f.GetBackupPaths()
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+
+	for len(backupPaths) > f.cfg.MaxBackups {
+		if err := os.Remove(backupPaths[0]); err != nil {
+			return errorz.Wrap(err)
+		}
+		backupPaths = backupPaths[1:]
+	}
+
+	return nil
+}
+
+// GetBackupPaths returns the paths of the rotated files, from oldest to newest. Files compressed in the background may
+// temporarily be listed both with and without the gzip extension.
+func (f *RotatingFile) GetBackupPaths() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(f.cfg.Path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, errorz.Wrap(err)
+	}
+
+	backupPaths := make([]string, 0)
+
+	for _, entry := range entries {
+		if !entry.IsDir() && f.backupRegex.MatchString(entry.Name()) {
+			backupPaths = append(backupPaths, filepath.Join(filepath.Dir(f.cfg.Path), entry.Name()))
+		}
+	}
+
+	slices.Sort(backupPaths)
+	return backupPaths, nil
+}
+
+func compressRotatedFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.OpenFile(path+rotatingFileGzipExt, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	w := gzip.NewWriter(out)
+
+	if _, err := io.Copy(w, in); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	if err := w.Close(); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return errorz.Wrap(err)
+	}
+
+	return errorz.MaybeWrap(os.Remove(path))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// MustNewDefaultFileSender initializes a default [*FileSender] using the [LogConfigMixin] from context. It returns nil
+// if no file path is configured.
+func MustNewDefaultFileSender(ctx context.Context) *FileSender {
+	logCfg := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig()
+
+	if path := logCfg.FilePath; path != "" && path != cfgm.DisabledValue {
+		return NewFileSender(ctx, &RotatingFileConfig{
+			Path:           path,
+			MaxBytes:       logCfg.FileMaxBytes,
+			RotateInterval: logCfg.FileRotateInterval,
+			Compress:       logCfg.FileCompress,
+			MaxBackups:     logCfg.FileMaxBackups,
+			SyncPolicy:     logCfg.FileSyncPolicy,
+			SyncInterval:   logCfg.FileSyncInterval,
+		})
+	}
+
+	return nil
+}
+
+// FileSender is a [transmission.Sender] that writes events as JSON lines to a [*RotatingFile]. Lines use the same
+// format as [transmission.WriterSender].
+type FileSender struct {
+	f *RotatingFile
+	c chan transmission.Response
+}
+
+// NewFileSender initializes a new [*FileSender].
+func NewFileSender(ctx context.Context, cfg *RotatingFileConfig) *FileSender {
+	return &FileSender{
+		f: NewRotatingFile(ctx, cfg),
+		c: make(chan transmission.Response, fileSenderResponsesLen),
+	}
+}
+
+// GetFile returns the underlying [*RotatingFile].
+func (s *FileSender) GetFile() *RotatingFile {
+	return s.f
+}
+
+// Add implements the [transmission.Sender] interface.
+func (s *FileSender) Add(e *transmission.Event) {
+	if e == nil {
+		return
+	}
+
+	var ts *time.Time
+	if !e.Timestamp.IsZero() {
+		ts = &e.Timestamp
+	}
+
+	buf, err := json.Marshal(&fileSenderLine{
+		Data:       e.Data,
+		SampleRate: e.SampleRate,
+		Timestamp:  ts,
+		Dataset:    e.Dataset,
+	})
+
+	if err == nil {
+		_, err = s.f.Write(append(buf, '\n'))
+	}
+
+	s.SendResponse(transmission.Response{
+		Err:      errorz.MaybeWrap(err),
+		Metadata: e.Metadata,
+	})
+}
+
+// Start implements the [transmission.Sender] interface.
+func (s *FileSender) Start() error {
+	return nil
+}
+
+// Stop implements the [transmission.Sender] interface.
+func (s *FileSender) Stop() error {
+	return errorz.MaybeWrap(s.f.Close())
+}
+
+// Flush implements the [transmission.Sender] interface.
+func (s *FileSender) Flush() error {
+	return errorz.MaybeWrap(s.f.Sync())
+}
+
+// TxResponses implements the [transmission.Sender] interface.
+func (s *FileSender) TxResponses() chan transmission.Response {
+	return s.c
+}
+
+// SendResponse implements the [transmission.Sender] interface.
+func (s *FileSender) SendResponse(response transmission.Response) bool {
+	select {
+	case s.c <- response:
+		return false
+	default:
+		return true
+	}
+}
+
+type fileSenderLine struct {
+	Data       map[string]any `json:"data"`
+	SampleRate uint           `json:"samplerate,omitempty"`
+	Timestamp  *time.Time     `json:"time,omitempty"`
+	Dataset    string         `json:"dataset,omitempty"`
+}
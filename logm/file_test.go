@@ -0,0 +1,159 @@
+package logm_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/filez"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/cfgm"
+	"github.com/ibrt/golang-modules/clkm"
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+)
+
+type FileSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestFileSuite(t *testing.T) {
+	fixturez.RunSuite(t, &FileSuite{})
+}
+
+func (s *FileSuite) TestRotatingFile_Size(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	// Files that merely share the prefix are not considered backups, and are never pruned.
+	for _, name := range []string{"events.jsonl.bak", "events.jsonl.20060102T150405.000000000.000.tmp", "events.jsonl2"} {
+		filez.MustWriteFile(filepath.Join(dir, "sub", name), 0777, 0666, []byte("other"))
+	}
+
+	f := logm.NewRotatingFile(ctx, &logm.RotatingFileConfig{
+		Path:       filepath.Join(dir, "sub", "events.jsonl"),
+		MaxBytes:   10,
+		MaxBackups: 2,
+		SyncPolicy: logm.FileSyncPolicyAlways,
+	})
+
+	for i := 0; i < 10; i++ {
+		_, err := fmt.Fprintf(f, "l%v\n", i)
+		g.Expect(err).To(Succeed())
+	}
+	g.Expect(f.Close()).To(Succeed())
+
+	backupPaths, err := f.GetBackupPaths()
+	g.Expect(err).To(Succeed())
+	g.Expect(backupPaths).To(HaveLen(2))
+	g.Expect(filez.MustReadFileString(backupPaths[0])).To(Equal("l3\nl4\nl5\n"))
+	g.Expect(filez.MustReadFileString(backupPaths[1])).To(Equal("l6\nl7\nl8\n"))
+	g.Expect(filez.MustReadFileString(filepath.Join(dir, "sub", "events.jsonl"))).To(Equal("l9\n"))
+	g.Expect(filez.MustReadFileString(filepath.Join(dir, "sub", "events.jsonl.bak"))).To(Equal("other"))
+}
+
+func (s *FileSuite) TestRotatingFile_Interval(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	f := logm.NewRotatingFile(ctx, &logm.RotatingFileConfig{
+		Path:           filepath.Join(dir, "events.jsonl"),
+		RotateInterval: time.Hour,
+		Compress:       true,
+		SyncPolicy:     logm.FileSyncPolicyInterval,
+	})
+
+	_, err := f.Write([]byte("l1\n"))
+	g.Expect(err).To(Succeed())
+	s.CLK.GetMock().Add(30 * time.Minute)
+	_, err = f.Write([]byte("l2\n"))
+	g.Expect(err).To(Succeed())
+	s.CLK.GetMock().Add(30 * time.Minute)
+	_, err = f.Write([]byte("l3\n"))
+	g.Expect(err).To(Succeed())
+	g.Expect(f.Sync()).To(Succeed())
+
+	// Compression happens in the background.
+	g.Eventually(f.GetBackupPaths, "1s", "10ms").Should(HaveExactElements(filepath.Join(dir,
+		"events.jsonl."+clkm.MustGet(ctx).Now().UTC().Format("20060102T150405.000000000")+".000.gz")))
+
+	backupPaths, err := f.GetBackupPaths()
+	g.Expect(err).To(Succeed())
+
+	gf, err := os.Open(backupPaths[0])
+	g.Expect(err).To(Succeed())
+	defer func() { _ = gf.Close() }()
+	gr, err := gzip.NewReader(gf)
+	g.Expect(err).To(Succeed())
+	buf, err := io.ReadAll(gr)
+	g.Expect(err).To(Succeed())
+	g.Expect(string(buf)).To(Equal("l1\nl2\n"))
+
+	g.Expect(f.Rotate()).To(Succeed())
+	g.Expect(f.Rotate()).To(Succeed())
+	g.Expect(f.Close()).To(Succeed())
+
+	backupPaths, err = f.GetBackupPaths()
+	g.Expect(err).To(Succeed())
+	g.Expect(backupPaths).To(HaveLen(2))
+	g.Expect(backupPaths[1]).To(HaveSuffix(".001.gz"))
+}
+
+func (s *FileSuite) TestFileSender(ctx context.Context, g *WithT) {
+	dir := filez.MustCreateTempDir()
+	defer filez.MustRemoveAll(dir)
+
+	sender := logm.MustNewDefaultFileSender(
+		cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{
+			FilePath: filepath.Join(dir, "events.jsonl"),
+		})(ctx))
+	g.Expect(sender).ToNot(BeNil())
+	g.Expect(sender.Start()).To(Succeed())
+
+	sender.Add(&transmission.Event{
+		Timestamp: clkm.MustGet(ctx).Now(),
+		Dataset:   "test-dataset",
+		Metadata:  "m1",
+		Data:      map[string]any{"k": "v"},
+	})
+	sender.Add(nil)
+	g.Expect(sender.Flush()).To(Succeed())
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(And(
+		HaveField("Metadata", "m1"),
+		HaveField("Err", BeNil()))))
+	g.Expect(sender.Stop()).To(Succeed())
+
+	lines := strings.Split(strings.TrimSpace(filez.MustReadFileString(filepath.Join(dir, "events.jsonl"))), "\n")
+	g.Expect(lines).To(HaveLen(1))
+
+	line := map[string]any{}
+	g.Expect(json.Unmarshal([]byte(lines[0]), &line)).To(Succeed())
+	g.Expect(line).To(And(
+		HaveKeyWithValue("data", HaveKeyWithValue("k", "v")),
+		HaveKeyWithValue("dataset", "test-dataset"),
+		HaveKeyWithValue("time", clkm.MustGet(ctx).Now().Format(time.RFC3339Nano))))
+
+	g.Expect(logm.MustNewDefaultFileSender(
+		cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{
+			FilePath: cfgm.DisabledValue,
+		})(ctx))).To(BeNil())
+}
+
+func (s *FileSuite) TestFileSyncPolicy(g *WithT) {
+	p := logm.FileSyncPolicy("")
+	g.Expect(p.UnmarshalText([]byte(""))).To(Succeed())
+	g.Expect(p.String()).To(Equal("never"))
+	g.Expect(p.UnmarshalText([]byte("always"))).To(Succeed())
+	g.Expect(p).To(Equal(logm.FileSyncPolicyAlways))
+	g.Expect(p.UnmarshalText([]byte("invalid"))).To(MatchError("invalid value for FileSyncPolicy: 'invalid'"))
+}
@@ -25,13 +25,16 @@ type Log interface {
 	EmitWarning(err error)
 	EmitError(err error)
 	EmitTraceLink(traceLink *TraceLink)
+	EmitAudit(event *AuditEvent)
 	Begin(name string, options ...BeginOption) (context.Context, func())
 	SetUser(user *User)
 	SetPropagatingField(k string, v any)
 	SetMetadataKey(k string, v any)
 	SetErrorMetadataKey(k string, v any)
 	SetErrorFlag()
+	SetStatus(status SpanStatus, message string)
 	GetCurrentTraceLink() *TraceLink
+	GetCurrentBaggage() Baggage
 	Flush()
 }
 
@@ -42,44 +45,99 @@ type RawLog interface {
 	EmitWarning(ctx context.Context, err error)
 	EmitError(ctx context.Context, err error)
 	EmitTraceLink(ctx context.Context, linkAnnotation *TraceLink)
+	EmitAudit(ctx context.Context, event *AuditEvent)
 	Begin(ctx context.Context, name string, options ...BeginOption) (context.Context, func())
 	SetErrorFlag(ctx context.Context)
+	SetStatus(ctx context.Context, status SpanStatus, message string)
 	SetUser(ctx context.Context, user *User)
 	SetPropagatingField(ctx context.Context, k string, v any)
 	SetMetadataKey(ctx context.Context, k string, v any)
 	SetErrorMetadataKey(ctx context.Context, k string, v any)
+	SetAttributes(ctx context.Context, attrs ...*Attribute)
+	SetPropagatingAttributes(ctx context.Context, attrs ...*Attribute)
 	GetCurrentTraceLink(ctx context.Context) *TraceLink
+	GetCurrentBaggage(ctx context.Context) Baggage
 	Flush(ctx context.Context)
 }
 
-// NewInitializer returns a new [injectz.Initializer] that configures the given client-level fields.
+// NewInitializer returns a new [injectz.Initializer]. The fields of the [*Resource] returned by [NewDefaultResource]
+// are attached to all events, followed by the given client-level fields.
 func NewInitializer(addClientFields func(context.Context, AddField)) injectz.Initializer {
 	return func(ctx context.Context) (injectz.Injector, injectz.Releaser) {
 		clkm.MustGet(ctx)
 		logCfg := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig()
 
+		sink := MustNewDefaultSink(ctx)
+
 		client, err := libhoney.NewClient(libhoney.ClientConfig{
-			APIKey:     logCfg.HoneycombAPIKey,
-			Dataset:    logCfg.HoneycombDataset,
-			SampleRate: logCfg.HoneycombSampleRate,
-			Transmission: NewSink(
-				MustNewDefaultLogrusLogger(ctx),
-				MustNewDefaultHoneycombSender(ctx)),
+			APIKey:       logCfg.HoneycombAPIKey,
+			Dataset:      logCfg.HoneycombDataset,
+			SampleRate:   logCfg.HoneycombSampleRate,
+			Transmission: sink,
 		})
 		errorz.MaybeMustWrap(err)
+		NewDefaultResource(ctx).AddFields(client)
+		auditLog := MustNewDefaultAuditLog(ctx)
 
 		if addClientFields != nil {
 			addClientFields(ctx, client)
 		}
 
-		return NewSingletonInjector(NewRawLogFromClient(client)), func() { client.Close() }
+		rawLog := NewRawLogFromClient(client,
+			RawLogErrorDedup(logCfg.ErrorDedupWindow, logCfg.ErrorDedupBurst),
+			RawLogAttributes(DefaultAttributeRegistry, logCfg.AttributeRegistryMode),
+			RawLogPanics(logCfg.PanicPolicy, logCfg.PanicGoroutineDumpMaxBytes),
+			RawLogBaggage(logCfg.BaggageAllowlist, logCfg.BaggageMaxBytes),
+			RawLogSpanTracking(logCfg.SpanTracking, logCfg.SpanAutoCloseChildren),
+			RawLogSpanBudget(logCfg.SpanBudget),
+			RawLogAudit(auditLog),
+			RawLogRateLimit(logCfg.RateLimitPolicy, logCfg.RateLimitWindow, logCfg.RateLimitBurst, logCfg.RateLimitSampleEvery),
+			RawLogPayloadLimits(&PayloadLimits{
+				MaxFields:      logCfg.PayloadMaxFields,
+				MaxStringBytes: logCfg.PayloadMaxStringBytes,
+				MaxTotalBytes:  logCfg.PayloadMaxTotalBytes,
+				MaxDepth:       logCfg.PayloadMaxDepth,
+				MaxBlobBytes:   logCfg.PayloadMaxBlobBytes,
+			}))
+
+		stopRuntimeStats := func() {}
+
+		if logCfg.RuntimeStatsInterval > 0 {
+			stopRuntimeStats = StartRuntimeStats(NewSingletonInjector(rawLog)(ctx), logCfg.RuntimeStatsInterval)
+		}
+
+		stopAsyncSinkStats := func() {}
+
+		if asyncSink, ok := sink.(*AsyncSink); ok {
+			stopAsyncSinkStats = StartAsyncSinkStats(NewSingletonInjector(rawLog)(ctx), asyncSink, DefaultAsyncSinkStatsInterval)
+		}
+
+		return NewSingletonInjector(rawLog), func() {
+			stopRuntimeStats()
+			stopAsyncSinkStats()
+			ReportOpenSpans(NewSingletonInjector(rawLog)(ctx))
+			rawLog.(*backgroundLogImpl).stopSweeping()
+			rawLog.Flush(ctx)
+			client.Close()
+
+			if auditLog != nil {
+				errorz.MaybeMustWrap(auditLog.Close())
+			}
+		}
 	}
 }
 
 // NewRawLogFromClient initializes a new [RawLog] using the given [*libhoney.Client].
-func NewRawLogFromClient(client *libhoney.Client) RawLog {
+func NewRawLogFromClient(client *libhoney.Client, options ...RawLogOption) RawLog {
+	o := newRawLogOptions(options...)
+
 	return &backgroundLogImpl{
-		client: client,
+		client:  client,
+		o:       o,
+		errAgg:  newErrorAggregator(client, o.errorDedupWindow, o.errorDedupBurst),
+		limiter: newRateLimiter(client, o),
+		refs:    newErrorReferences(o.errorReferencesMaxSize),
+		tracker: newSpanTracker(o),
 	}
 }
 
@@ -95,3 +153,15 @@ func MustGet(ctx context.Context) Log {
 		rawLog: ctx.Value(logContextKey).(RawLog),
 	}
 }
+
+// MaybeGet extracts, returns nil if not found.
+func MaybeGet(ctx context.Context) Log {
+	if rawLog, ok := ctx.Value(logContextKey).(RawLog); ok {
+		return &adapterLogImpl{
+			ctx:    ctx,
+			rawLog: rawLog,
+		}
+	}
+
+	return nil
+}
@@ -1,5 +1,9 @@
 package logm
 
+import (
+	"time"
+)
+
 var (
 	_ EmitOption = (EmitOptionFunc)(nil)
 	_ EmitOption = (EmitArgs)(nil)
@@ -12,8 +16,9 @@ type EmitOption interface {
 }
 
 type emitOptions struct {
-	args     []any
-	metadata EmitMetadata
+	args         []any
+	metadata     EmitMetadata
+	rateLimitKey string
 }
 
 func newEmitOptions(options ...EmitOption) *emitOptions {
@@ -65,6 +70,22 @@ func EmitM(k string, v any) EmitOptionFunc {
 	}
 }
 
+// EmitPayload attaches a structured value (e.g. a struct or map) as metadata. Unlike [EmitM], the value is flattened
+// into one field per leaf, subject to the limits set using [RawLogPayloadLimits].
+func EmitPayload(k string, v any) EmitOptionFunc {
+	return func(o *emitOptions) {
+		o.metadata[k] = &payloadValue{v: v}
+	}
+}
+
+// EmitRateLimitKey sets the key used to rate-limit debug and info events, instead of the location of the caller. It
+// has no effect unless rate limiting is enabled, see [RawLogRateLimit].
+func EmitRateLimitKey(key string) EmitOptionFunc {
+	return func(o *emitOptions) {
+		o.rateLimitKey = key
+	}
+}
+
 var (
 	_ BeginOption = (BeginOptionFunc)(nil)
 	_ BeginOption = (BeginMetadata)(nil)
@@ -78,12 +99,19 @@ type BeginOption interface {
 type beginOptions struct {
 	metadata    BeginMetadata
 	errMetadata BeginErrMetadata
+	links       []*TraceLink
+	isRoot      bool
+	attributes  []*Attribute
+	baggage     Baggage
+	kind        SpanKind
+	budget      time.Duration
 }
 
 func newBeginOptions(options ...BeginOption) *beginOptions {
 	o := &beginOptions{
 		metadata:    BeginMetadata{},
 		errMetadata: BeginErrMetadata{},
+		kind:        SpanKindInternal,
 	}
 
 	for _, option := range options {
@@ -133,3 +161,230 @@ func BeginErrM(k string, v any) BeginOptionFunc {
 		o.errMetadata[k] = v
 	}
 }
+
+// BeginPayload attaches a structured value (e.g. a struct or map) as span metadata. Unlike [BeginM], the value is
+// flattened into one field per leaf, subject to the limits set using [RawLogPayloadLimits].
+func BeginPayload(k string, v any) BeginOptionFunc {
+	return func(o *beginOptions) {
+		o.metadata[k] = &payloadValue{v: v}
+	}
+}
+
+// BeginLinks links the span to the given traces, e.g. the upstream traces of the messages processed by a batch job.
+// Nil and empty links are ignored.
+func BeginLinks(links ...*TraceLink) BeginOptionFunc {
+	return func(o *beginOptions) {
+		for _, link := range links {
+			if link.Serialize() != "" {
+				o.links = append(o.links, link)
+			}
+		}
+	}
+}
+
+// BeginAttributes sets typed attributes on the span, see [SetAttributes].
+func BeginAttributes(attrs ...*Attribute) BeginOptionFunc {
+	return func(o *beginOptions) {
+		o.attributes = append(o.attributes, attrs...)
+	}
+}
+
+// BeginBaggage restores the given [Baggage] on the span, e.g. parsed from an incoming request or message using
+// [MaybeParseBaggage]. The fields are propagated to child spans as if set by [Log.SetPropagatingField]. See
+// [RawLogBaggage] for the keys that are restored.
+func BeginBaggage(baggage Baggage) BeginOptionFunc {
+	return func(o *beginOptions) {
+		if len(baggage) > 0 && o.baggage == nil {
+			o.baggage = Baggage{}
+		}
+
+		for k, v := range baggage {
+			o.baggage[k] = v
+		}
+	}
+}
+
+// BeginKind sets the [SpanKind] of the span. Spans default to [SpanKindInternal].
+func BeginKind(kind SpanKind) BeginOptionFunc {
+	return func(o *beginOptions) {
+		if kind != "" {
+			o.kind = kind
+		}
+	}
+}
+
+// BeginBudget sets the time budget of the span, overriding the default set by [RawLogSpanBudget]. If the span is still
+// running after the budget, a warning is emitted on it.
+func BeginBudget(budget time.Duration) BeginOptionFunc {
+	return func(o *beginOptions) {
+		o.budget = budget
+	}
+}
+
+// BeginRoot starts the span as the root of a new trace, even if the context already contains a span. It is typically
+// combined with [BeginLinks] to fan in multiple upstream traces.
+func BeginRoot() BeginOptionFunc {
+	return func(o *beginOptions) {
+		o.isRoot = true
+	}
+}
+
+var (
+	_ RawLogOption = (RawLogOptionFunc)(nil)
+)
+
+// RawLogOption describes an option.
+type RawLogOption interface {
+	Apply(o *rawLogOptions)
+}
+
+type rawLogOptions struct {
+	errorDedupWindow           time.Duration
+	errorDedupBurst            int
+	attrRegistry               *AttributeRegistry
+	attrMode                   AttributeRegistryMode
+	panicPolicy                PanicPolicy
+	panicGoroutineDumpMaxBytes int
+	baggageAllowlist           []string
+	baggageMaxBytes            int
+	spanTracking               bool
+	spanAutoCloseChildren      bool
+	spanBudget                 time.Duration
+	auditLog                   *AuditLog
+	rateLimitPolicy            RateLimitPolicy
+	rateLimitWindow            time.Duration
+	rateLimitBurst             int
+	rateLimitSampleEvery       int
+	payloadLimits              *PayloadLimits
+	errorReferencesMaxSize     int
+}
+
+func newRawLogOptions(options ...RawLogOption) *rawLogOptions {
+	o := &rawLogOptions{
+		attrRegistry:           DefaultAttributeRegistry,
+		attrMode:               AttributeRegistryModeWarn,
+		panicPolicy:            PanicPolicyConvert,
+		baggageMaxBytes:        DefaultBaggageMaxBytes,
+		rateLimitPolicy:        RateLimitPolicyTokenBucket,
+		errorReferencesMaxSize: DefaultErrorReferencesMaxSize,
+	}
+
+	for _, option := range options {
+		option.Apply(o)
+	}
+	return o
+}
+
+// RawLogOptionFunc is a shorthand for RawLogOption.
+type RawLogOptionFunc func(o *rawLogOptions)
+
+// Apply implements the RawLogOption interface.
+func (f RawLogOptionFunc) Apply(o *rawLogOptions) {
+	f(o)
+}
+
+// RawLogErrorDedup enables error deduplication: within each window, only the first burst errors with the same
+// fingerprint are emitted, the others are reported in a summary event. A zero window disables deduplication.
+func RawLogErrorDedup(window time.Duration, burst int) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		o.errorDedupWindow = window
+		o.errorDedupBurst = burst
+	}
+}
+
+// RawLogAttributes sets the registry used to validate typed attributes, and how unknown keys are handled.
+func RawLogAttributes(registry *AttributeRegistry, mode AttributeRegistryMode) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		if registry != nil {
+			o.attrRegistry = registry
+		}
+
+		if mode != "" {
+			o.attrMode = mode
+		}
+	}
+}
+
+// RawLogPanics sets the [PanicPolicy] used by Wrap*Panic helpers, and the maximum size of the all-goroutine stack dump
+// attached to errors recovered from panics. A zero size disables the dump.
+func RawLogPanics(policy PanicPolicy, goroutineDumpMaxBytes int) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		if policy != "" {
+			o.panicPolicy = policy
+		}
+
+		o.panicGoroutineDumpMaxBytes = goroutineDumpMaxBytes
+	}
+}
+
+// RawLogBaggage configures [Baggage]: only keys matching one of the allowlist patterns (see [path.Match]) are
+// propagated, or all keys if the allowlist is empty, and entries are dropped to keep the serialized size under
+// maxBytes. A zero maxBytes uses [DefaultBaggageMaxBytes]. Limits are enforced both when getting and restoring baggage.
+// Inbound baggage is only restored if the allowlist is not empty, and the user fields ("scope.user" and
+// "scope.user.email") only if the allowlist contains them verbatim.
+func RawLogBaggage(allowlist []string, maxBytes int) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		o.baggageAllowlist = allowlist
+
+		if maxBytes > 0 {
+			o.baggageMaxBytes = maxBytes
+		}
+	}
+}
+
+// RawLogSpanTracking enables tracking of open spans, meant for development and tests. Leaked spans can be inspected
+// using [GetOpenSpans] and [ReportOpenSpans]. If autoCloseChildren is true, spans still open when their parent ends are
+// ended as well, and marked as truncated.
+func RawLogSpanTracking(enabled, autoCloseChildren bool) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		o.spanTracking = enabled
+		o.spanAutoCloseChildren = enabled && autoCloseChildren
+	}
+}
+
+// RawLogSpanBudget sets the default time budget of spans, see [BeginBudget]. A zero budget disables the watchdog.
+func RawLogSpanBudget(budget time.Duration) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		o.spanBudget = budget
+	}
+}
+
+// RawLogAudit sets the [*AuditLog] that records events emitted using EmitAudit. Audit records bypass sampling and are
+// written to their own destination.
+func RawLogAudit(auditLog *AuditLog) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		o.auditLog = auditLog
+	}
+}
+
+// RawLogRateLimit enables rate limiting of debug and info events, per call site (see [EmitRateLimitKey]) and level.
+// Using [RateLimitPolicyTokenBucket], up to burst events are allowed, refilled at a rate of burst events per window.
+// Using [RateLimitPolicySample], the first burst events in each window are allowed, then one every sampleEvery events
+// (none if sampleEvery is zero). Suppressed events are reported in a summary event at the end of each window. A zero
+// window disables rate limiting.
+func RawLogRateLimit(policy RateLimitPolicy, window time.Duration, burst, sampleEvery int) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		if policy != "" {
+			o.rateLimitPolicy = policy
+		}
+
+		o.rateLimitWindow = window
+		o.rateLimitBurst = burst
+		o.rateLimitSampleEvery = sampleEvery
+	}
+}
+
+// RawLogPayloadLimits sets the limits enforced on the fields attached to each event. A nil value disables them.
+func RawLogPayloadLimits(limits *PayloadLimits) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		o.payloadLimits = limits
+	}
+}
+
+// RawLogErrorReferences sets how many recent error references can be resolved using [ResolveErrorReference]. A zero
+// size disables in-process resolution, references are still attached to errors and events.
+func RawLogErrorReferences(maxSize int) RawLogOptionFunc {
+	return func(o *rawLogOptions) {
+		o.errorReferencesMaxSize = maxSize
+	}
+}
@@ -2,6 +2,7 @@ package logm
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ibrt/golang-utils/fixturez"
 	. "github.com/onsi/gomega"
@@ -19,13 +20,17 @@ func (*OptionsSuite) TestEmitOptions(g *WithT) {
 	g.Expect(newEmitOptions(
 		EmitA(1, 2, 3),
 		EmitM("k1", "v1"),
-		EmitMetadata{"k2": "v2"})).
+		EmitMetadata{"k2": "v2"},
+		EmitRateLimitKey("key"),
+		EmitPayload("p", 1))).
 		To(Equal(&emitOptions{
 			args: []any{1, 2, 3},
 			metadata: map[string]any{
 				"k1": "v1",
 				"k2": "v2",
+				"p":  &payloadValue{v: 1},
 			},
+			rateLimitKey: "key",
 		}))
 }
 
@@ -33,16 +38,32 @@ func (*OptionsSuite) TestBeginOptions(g *WithT) {
 	g.Expect(newBeginOptions(
 		BeginM("k1", "v1"),
 		BeginMetadata{"k2": "v2"},
+		BeginPayload("p", 1),
 		BeginErrM("ek1", "ev1"),
-		BeginErrMetadata{"ek2": "ev2"})).
+		BeginErrMetadata{"ek2": "ev2"},
+		BeginLinks(&TraceLink{TraceID: "t1", SpanID: "s1"}, nil, &TraceLink{}),
+		BeginAttributes(AttrHTTPMethod.V("GET")),
+		BeginBaggage(Baggage{"scope.k": "v"}),
+		BeginBaggage(nil),
+		BeginKind(SpanKindServer),
+		BeginKind(""),
+		BeginBudget(time.Second),
+		BeginRoot())).
 		To(Equal(&beginOptions{
 			metadata: map[string]any{
 				"k1": "v1",
 				"k2": "v2",
+				"p":  &payloadValue{v: 1},
 			},
 			errMetadata: map[string]any{
 				"ek1": "ev1",
 				"ek2": "ev2",
 			},
+			links:      []*TraceLink{{TraceID: "t1", SpanID: "s1"}},
+			isRoot:     true,
+			attributes: []*Attribute{{Key: "http.method", Type: AttributeTypeString, Value: "GET"}},
+			baggage:    Baggage{"scope.k": "v"},
+			kind:       SpanKindServer,
+			budget:     time.Second,
 		}))
 }
@@ -0,0 +1,733 @@
+package logm
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ibrt/golang-modules/cfgm"
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+// Default values for [OTLPSenderConfig].
+const (
+	DefaultOTLPSenderMaxBatchSize     = 512
+	DefaultOTLPSenderMaxQueuedBatches = 16
+	DefaultOTLPSenderBatchTimeout     = 5 * time.Second
+	DefaultOTLPSenderTimeout          = 10 * time.Second
+)
+
+const (
+	otlpSenderResponsesLen    = 1024
+	otlpSenderMaxPendingLinks = 1024
+	otlpScopeName             = "github.com/ibrt/golang-modules/logm"
+)
+
+var (
+	_ transmission.Sender = (*OTLPSender)(nil)
+)
+
+var (
+	// otlpResourcePrefixes are the prefixes of the fields added by [*Resource], sent as OTLP resource attributes.
+	otlpResourcePrefixes = []string{
+		"service.",
+		"deployment.",
+		"host.",
+		"process.",
+		"build.",
+		"container.",
+	}
+
+	otlpSpanKinds = map[SpanKind]int{
+		SpanKindInternal: 1,
+		SpanKindServer:   2,
+		SpanKindClient:   3,
+		SpanKindProducer: 4,
+		SpanKindConsumer: 5,
+	}
+
+	otlpSeverityNumbers = map[logrus.Level]int{
+		logrus.DebugLevel: 5,
+		logrus.InfoLevel:  9,
+		logrus.WarnLevel:  13,
+		logrus.ErrorLevel: 17,
+	}
+)
+
+// MustNewDefaultOTLPSender initializes a default [*OTLPSender] using the [LogConfigMixin] from context. It returns nil
+// if no OTLP endpoint is configured.
+func MustNewDefaultOTLPSender(ctx context.Context) *OTLPSender {
+	logCfg := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig()
+
+	if endpoint := logCfg.OTLPEndpoint; endpoint != "" && endpoint != cfgm.DisabledValue {
+		return NewOTLPSender(ctx, &OTLPSenderConfig{
+			Endpoint: endpoint,
+			Headers:  logCfg.OTLPHeaders,
+		})
+	}
+
+	return nil
+}
+
+// OTLPSenderConfig describes the configuration for an [*OTLPSender].
+type OTLPSenderConfig struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver, e.g. "http://localhost:4318".
+	Endpoint string
+	// Headers are added to each request, e.g. for authentication.
+	Headers map[string]string
+	// MaxBatchSize is the number of events that triggers an export.
+	MaxBatchSize int
+	// MaxQueuedBatches is the maximum number of full batches waiting to be exported, past which batches are dropped.
+	MaxQueuedBatches int
+	// BatchTimeout is how often pending events are exported.
+	BatchTimeout time.Duration
+	// Timeout is the timeout of each request.
+	Timeout time.Duration
+	// Client is used to send requests. It defaults to [http.DefaultClient].
+	Client *http.Client
+}
+
+// OTLPSender is a [transmission.Sender] that exports events to an OpenTelemetry collector using OTLP/HTTP with JSON
+// encoding. Spans are exported as OTLP spans, link annotations as links of their parent span, and other events (including
+// span events) as OTLP log records. Trace and span IDs are derived from the logm ones, and resource fields (see
+// [*Resource]) are exported as resource attributes.
+//
+// Batches are exported by a background goroutine, so that callers never wait on the collector: if MaxQueuedBatches
+// batches are already waiting to be exported, further full batches are dropped.
+type OTLPSender struct {
+	clk    clkm.Clock
+	cfg    *OTLPSenderConfig
+	m      *sync.Mutex
+	spans  []*transmission.Event
+	logs   []*transmission.Event
+	links  map[string][]*transmission.Event
+	nLinks int
+	batchC chan *otlpBatch
+	stopC  chan struct{}
+	doneC  chan struct{}
+	c      chan transmission.Response
+}
+
+type otlpBatch struct {
+	spans []*transmission.Event
+	logs  []*transmission.Event
+	links map[string][]*transmission.Event
+	errC  chan error
+}
+
+// NewOTLPSender initializes a new [*OTLPSender].
+func NewOTLPSender(ctx context.Context, cfg *OTLPSenderConfig) *OTLPSender {
+	errorz.Assertf(cfg.Endpoint != "", "OTLP endpoint is empty")
+
+	cfg = &OTLPSenderConfig{
+		Endpoint:         strings.TrimSuffix(cfg.Endpoint, "/"),
+		Headers:          cfg.Headers,
+		MaxBatchSize:     cfg.MaxBatchSize,
+		MaxQueuedBatches: cfg.MaxQueuedBatches,
+		BatchTimeout:     cfg.BatchTimeout,
+		Timeout:          cfg.Timeout,
+		Client:           cfg.Client,
+	}
+
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = DefaultOTLPSenderMaxBatchSize
+	}
+
+	if cfg.MaxQueuedBatches <= 0 {
+		cfg.MaxQueuedBatches = DefaultOTLPSenderMaxQueuedBatches
+	}
+
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = DefaultOTLPSenderBatchTimeout
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultOTLPSenderTimeout
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	return &OTLPSender{
+		clk:    clkm.MustGet(ctx),
+		cfg:    cfg,
+		m:      &sync.Mutex{},
+		links:  map[string][]*transmission.Event{},
+		batchC: make(chan *otlpBatch, cfg.MaxQueuedBatches),
+		c:      make(chan transmission.Response, otlpSenderResponsesLen),
+	}
+}
+
+// Add implements the [transmission.Sender] interface. It never blocks: full batches are handed to the background
+// goroutine, or dropped if too many are already waiting to be exported.
+func (s *OTLPSender) Add(e *transmission.Event) {
+	if e == nil {
+		return
+	}
+
+	s.m.Lock()
+
+	if parentID, ok := getOTLPLinkParentID(e); ok {
+		// links are held until their parent span is added, as they are emitted before it ends
+		if s.nLinks >= otlpSenderMaxPendingLinks {
+			s.m.Unlock()
+			s.SendResponse(transmission.Response{
+				Err:      errorz.Errorf("OTLP pending links limit exceeded"),
+				Metadata: e.Metadata,
+			})
+			return
+		}
+
+		s.links[parentID] = append(s.links[parentID], e)
+		s.nLinks++
+		s.m.Unlock()
+		return
+	}
+
+	if isOTLPSpan(e) {
+		s.spans = append(s.spans, e)
+	} else {
+		s.logs = append(s.logs, e)
+	}
+
+	var batch *otlpBatch
+	if len(s.spans)+len(s.logs) >= s.cfg.MaxBatchSize {
+		batch = s.takeBatch()
+	}
+
+	s.m.Unlock()
+
+	if batch != nil {
+		select {
+		case s.batchC <- batch:
+		default:
+			s.respond(batch, errorz.Errorf("OTLP export queue is full"))
+		}
+	}
+}
+
+// Start implements the [transmission.Sender] interface. It starts exporting batches in the background, and pending
+// events every batch timeout.
+func (s *OTLPSender) Start() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.stopC = make(chan struct{})
+	s.doneC = make(chan struct{})
+
+	go s.run(s.stopC, s.doneC, s.clk.Ticker(s.cfg.BatchTimeout))
+	return nil
+}
+
+// Stop implements the [transmission.Sender] interface. It exports queued and pending events.
+func (s *OTLPSender) Stop() error {
+	s.m.Lock()
+	stopC, doneC := s.stopC, s.doneC
+	s.stopC, s.doneC = nil, nil
+	s.m.Unlock()
+
+	if stopC != nil {
+		close(stopC)
+		<-doneC
+	}
+
+	err := s.Flush()
+
+	s.m.Lock()
+	orphans := &otlpBatch{links: s.links}
+	s.links, s.nLinks = map[string][]*transmission.Event{}, 0
+	s.m.Unlock()
+
+	s.respond(orphans, errorz.Errorf("OTLP link parent span was not exported"))
+	return errorz.MaybeWrap(err)
+}
+
+// Flush implements the [transmission.Sender] interface. It exports queued and pending events, and waits for them to be
+// exported.
+func (s *OTLPSender) Flush() error {
+	s.m.Lock()
+	batch := s.takeBatch()
+	stopC := s.stopC
+	s.m.Unlock()
+
+	if stopC != nil {
+		batch.errC = make(chan error, 1)
+
+		select {
+		case s.batchC <- batch:
+			return errorz.MaybeWrap(<-batch.errC)
+		case <-stopC:
+			// the sender is stopping: export the batch on this goroutine
+		}
+	}
+
+	var err error
+
+	for {
+		select {
+		case queued := <-s.batchC:
+			if queuedErr := s.export(queued); queuedErr != nil && err == nil {
+				err = queuedErr
+			}
+		default:
+			if batchErr := s.export(batch); batchErr != nil && err == nil {
+				err = batchErr
+			}
+			return errorz.MaybeWrap(err)
+		}
+	}
+}
+
+// TxResponses implements the [transmission.Sender] interface.
+func (s *OTLPSender) TxResponses() chan transmission.Response {
+	return s.c
+}
+
+// SendResponse implements the [transmission.Sender] interface.
+func (s *OTLPSender) SendResponse(response transmission.Response) bool {
+	select {
+	case s.c <- response:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *OTLPSender) run(stopC, doneC chan struct{}, ticker *clock.Ticker) {
+	defer close(doneC)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopC:
+			return
+		case batch := <-s.batchC:
+			_ = s.export(batch)
+		case <-ticker.C:
+			s.m.Lock()
+			batch := s.takeBatch()
+			s.m.Unlock()
+			_ = s.export(batch)
+		}
+	}
+}
+
+// takeBatch takes the pending events. The caller must hold the lock.
+func (s *OTLPSender) takeBatch() *otlpBatch {
+	batch := &otlpBatch{
+		spans: s.spans,
+		logs:  s.logs,
+		links: map[string][]*transmission.Event{},
+	}
+
+	for _, e := range s.spans {
+		if spanID, ok := e.Data["trace.span_id"].(string); ok {
+			if links, ok := s.links[spanID]; ok {
+				batch.links[spanID] = links
+				s.nLinks -= len(links)
+				delete(s.links, spanID)
+			}
+		}
+	}
+
+	s.spans, s.logs = nil, nil
+	return batch
+}
+
+// export exports the given batch, and sends its error (if any) to the batch error channel, if set.
+func (s *OTLPSender) export(batch *otlpBatch) error {
+	var err error
+
+	if len(batch.spans) > 0 {
+		events := slices.Clone(batch.spans)
+		for _, links := range batch.links {
+			events = append(events, links...)
+		}
+
+		err = s.exportEvents("/v1/traces", newOTLPTracesRequest(batch.spans, batch.links), events)
+	}
+
+	if len(batch.logs) > 0 {
+		if logsErr := s.exportEvents("/v1/logs", newOTLPLogsRequest(batch.logs), batch.logs); logsErr != nil && err == nil {
+			err = logsErr
+		}
+	}
+
+	if batch.errC != nil {
+		batch.errC <- err
+	}
+
+	return err
+}
+
+func (s *OTLPSender) exportEvents(path string, body any, events []*transmission.Event) error {
+	startTime := s.clk.Now()
+	statusCode, err := s.post(path, body)
+
+	for _, e := range events {
+		s.SendResponse(transmission.Response{
+			Err:        err,
+			StatusCode: statusCode,
+			Duration:   s.clk.Since(startTime),
+			Metadata:   e.Metadata,
+		})
+	}
+
+	return err
+}
+
+// respond sends an error response for each event in the given batch, e.g. if it is dropped.
+func (s *OTLPSender) respond(batch *otlpBatch, err error) {
+	events := slices.Concat(batch.spans, batch.logs)
+	for _, links := range batch.links {
+		events = append(events, links...)
+	}
+
+	for _, e := range events {
+		s.SendResponse(transmission.Response{
+			Err:      err,
+			Metadata: e.Metadata,
+		})
+	}
+}
+
+func (s *OTLPSender) post(path string, body any) (int, error) {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return 0, errorz.Wrap(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint+path, bytes.NewReader(buf))
+	if err != nil {
+		return 0, errorz.Wrap(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return 0, errorz.Wrap(err)
+	}
+	defer errorz.IgnoreClose(resp.Body)
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, errorz.Errorf("OTLP export failed with status %v", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func getOTLPLinkParentID(e *transmission.Event) (string, bool) {
+	if annotationType, _ := e.Data["meta.annotation_type"].(string); annotationType != "link" {
+		return "", false
+	}
+
+	parentID, ok := e.Data["trace.parent_id"].(string)
+	return parentID, ok
+}
+
+func isOTLPSpan(e *transmission.Event) bool {
+	_, isAnnotation := e.Data["meta.annotation_type"]
+	_, hasSpanID := e.Data["trace.span_id"]
+	_, hasDuration := e.Data["duration_ms"]
+	return !isAnnotation && hasSpanID && hasDuration
+}
+
+type otlpResource struct {
+	Attributes []*otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpKeyValue struct {
+	Key   string        `json:"key"`
+	Value *otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []*otlpAnyValue `json:"values"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   *otlpResource     `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope *otlpScope  `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []*otlpKeyValue `json:"attributes"`
+	Links             []*otlpSpanLink `json:"links,omitempty"`
+	Status            *otlpStatus     `json:"status"`
+}
+
+type otlpSpanLink struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []*otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  *otlpResource    `json:"resource"`
+	ScopeLogs []*otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpScopeLogs struct {
+	Scope      *otlpScope       `json:"scope"`
+	LogRecords []*otlpLogRecord `json:"logRecords"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string          `json:"timeUnixNano"`
+	SeverityNumber int             `json:"severityNumber"`
+	SeverityText   string          `json:"severityText"`
+	Body           *otlpAnyValue   `json:"body"`
+	Attributes     []*otlpKeyValue `json:"attributes"`
+	TraceID        string          `json:"traceId,omitempty"`
+	SpanID         string          `json:"spanId,omitempty"`
+}
+
+func newOTLPTracesRequest(events []*transmission.Event, links map[string][]*transmission.Event) *otlpTracesRequest {
+	req := &otlpTracesRequest{ResourceSpans: make([]*otlpResourceSpans, 0)}
+	index := map[string]*otlpScopeSpans{}
+
+	for _, e := range events {
+		resource, attrs := splitOTLPAttributes(e.Data, "name", "duration_ms", "trace.trace_id", "trace.span_id",
+			"trace.parent_id", "span.kind", "span.status", "span.status.message")
+
+		key := getOTLPResourceKey(resource)
+		scopeSpans, ok := index[key]
+		if !ok {
+			scopeSpans = &otlpScopeSpans{Scope: &otlpScope{Name: otlpScopeName}, Spans: make([]*otlpSpan, 0)}
+			index[key] = scopeSpans
+			req.ResourceSpans = append(req.ResourceSpans, &otlpResourceSpans{
+				Resource:   &otlpResource{Attributes: resource},
+				ScopeSpans: []*otlpScopeSpans{scopeSpans},
+			})
+		}
+
+		durationMS, _ := e.Data["duration_ms"].(float64)
+		kind, _ := e.Data["span.kind"].(string)
+		status, _ := e.Data["span.status"].(string)
+		statusMessage, _ := e.Data["span.status.message"].(string)
+		name, _ := e.Data["name"].(string)
+
+		span := &otlpSpan{
+			TraceID:           getOTLPTraceID(e.Data["trace.trace_id"]),
+			SpanID:            getOTLPSpanID(e.Data["trace.span_id"]),
+			ParentSpanID:      getOTLPSpanID(e.Data["trace.parent_id"]),
+			Name:              name,
+			Kind:              max(otlpSpanKinds[SpanKind(kind)], 1),
+			StartTimeUnixNano: strconv.FormatInt(e.Timestamp.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(e.Timestamp.Add(time.Duration(durationMS*float64(time.Millisecond))).UnixNano(), 10),
+			Attributes:        attrs,
+			Status:            &otlpStatus{Code: 0},
+		}
+
+		spanID, _ := e.Data["trace.span_id"].(string)
+		for _, link := range links[spanID] {
+			span.Links = append(span.Links, &otlpSpanLink{
+				TraceID: getOTLPTraceID(link.Data["trace.link.trace_id"]),
+				SpanID:  getOTLPSpanID(link.Data["trace.link.span_id"]),
+			})
+		}
+
+		switch SpanStatus(status) {
+		case "":
+			// intentionally empty
+		case SpanStatusOK:
+			span.Status.Code = 1
+		default:
+			span.Status.Code = 2
+			span.Status.Message = statusMessage
+		}
+
+		scopeSpans.Spans = append(scopeSpans.Spans, span)
+	}
+
+	return req
+}
+
+func newOTLPLogsRequest(events []*transmission.Event) *otlpLogsRequest {
+	req := &otlpLogsRequest{ResourceLogs: make([]*otlpResourceLogs, 0)}
+	index := map[string]*otlpScopeLogs{}
+
+	for _, e := range events {
+		resource, attrs := splitOTLPAttributes(e.Data, "trace.trace_id", "trace.parent_id")
+
+		key := getOTLPResourceKey(resource)
+		scopeLogs, ok := index[key]
+		if !ok {
+			scopeLogs = &otlpScopeLogs{Scope: &otlpScope{Name: otlpScopeName}, LogRecords: make([]*otlpLogRecord, 0)}
+			index[key] = scopeLogs
+			req.ResourceLogs = append(req.ResourceLogs, &otlpResourceLogs{
+				Resource:  &otlpResource{Attributes: resource},
+				ScopeLogs: []*otlpScopeLogs{scopeLogs},
+			})
+		}
+
+		level := getEventLevel(e.Data)
+		body := getEventMessage(e.Data)
+
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, &otlpLogRecord{
+			TimeUnixNano:   strconv.FormatInt(e.Timestamp.UnixNano(), 10),
+			SeverityNumber: otlpSeverityNumbers[level],
+			SeverityText:   strings.ToUpper(level.String()),
+			Body:           &otlpAnyValue{StringValue: &body},
+			Attributes:     attrs,
+			TraceID:        getOTLPTraceID(e.Data["trace.trace_id"]),
+			SpanID:         getOTLPSpanID(e.Data["trace.parent_id"]),
+		})
+	}
+
+	return req
+}
+
+// splitOTLPAttributes splits the given event data into resource attributes and other attributes, sorted by key and
+// skipping the given keys.
+func splitOTLPAttributes(data map[string]any, skipKeys ...string) ([]*otlpKeyValue, []*otlpKeyValue) {
+	keys := make([]string, 0, len(data))
+
+	for k := range data {
+		if !slices.Contains(skipKeys, k) {
+			keys = append(keys, k)
+		}
+	}
+
+	slices.Sort(keys)
+	resource := make([]*otlpKeyValue, 0)
+	attrs := make([]*otlpKeyValue, 0, len(keys))
+
+	for _, k := range keys {
+		kv := &otlpKeyValue{Key: k, Value: newOTLPAnyValue(data[k])}
+
+		if slices.ContainsFunc(otlpResourcePrefixes, func(prefix string) bool { return strings.HasPrefix(k, prefix) }) {
+			resource = append(resource, kv)
+		} else {
+			attrs = append(attrs, kv)
+		}
+	}
+
+	return resource, attrs
+}
+
+func getOTLPResourceKey(resource []*otlpKeyValue) string {
+	buf, _ := json.Marshal(resource)
+	return string(buf)
+}
+
+func newOTLPAnyValue(v any) *otlpAnyValue {
+	switch v := v.(type) {
+	case string:
+		return &otlpAnyValue{StringValue: &v}
+	case bool:
+		return &otlpAnyValue{BoolValue: &v}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s := fmt.Sprintf("%v", v)
+		return &otlpAnyValue{IntValue: &s}
+	case float32:
+		f := float64(v)
+		return &otlpAnyValue{DoubleValue: &f}
+	case float64:
+		return &otlpAnyValue{DoubleValue: &v}
+	case []string:
+		values := make([]*otlpAnyValue, 0, len(v))
+		for _, s := range v {
+			values = append(values, newOTLPAnyValue(s))
+		}
+		return &otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	default:
+		buf, err := json.Marshal(v)
+		s := string(buf)
+		if err != nil {
+			s = fmt.Sprintf("%v", v)
+		}
+		return &otlpAnyValue{StringValue: &s}
+	}
+}
+
+// getOTLPTraceID converts a logm trace ID (a UUID) to an OTLP trace ID (16 bytes, hex-encoded).
+func getOTLPTraceID(v any) string {
+	id, _ := v.(string)
+	if id == "" {
+		return ""
+	}
+
+	if raw := strings.ReplaceAll(id, "-", ""); len(raw) == 32 {
+		if _, err := hex.DecodeString(raw); err == nil {
+			return strings.ToLower(raw)
+		}
+	}
+
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(id))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// getOTLPSpanID converts a logm span ID (a UUID) to an OTLP span ID (8 bytes, hex-encoded).
+func getOTLPSpanID(v any) string {
+	id, _ := v.(string)
+	if id == "" {
+		return ""
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	return hex.EncodeToString(h.Sum(nil))
+}
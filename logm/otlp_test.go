@@ -0,0 +1,314 @@
+package logm_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/cfgm"
+	"github.com/ibrt/golang-modules/clkm"
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+)
+
+type OTLPSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestOTLPSuite(t *testing.T) {
+	fixturez.RunSuite(t, &OTLPSuite{})
+}
+
+type testOTLPRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]any
+}
+
+type testOTLPServer struct {
+	*httptest.Server
+	m          *sync.Mutex
+	requests   []*testOTLPRequest
+	statusCode int
+	gate       chan struct{}
+	received   int
+}
+
+func newTestOTLPServer() *testOTLPServer {
+	s := &testOTLPServer{
+		m:          &sync.Mutex{},
+		statusCode: http.StatusOK,
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		body := map[string]any{}
+		_ = json.Unmarshal(buf, &body)
+
+		s.m.Lock()
+		s.received++
+		s.m.Unlock()
+
+		if s.gate != nil {
+			<-s.gate
+		}
+
+		s.m.Lock()
+		defer s.m.Unlock()
+		s.requests = append(s.requests, &testOTLPRequest{Path: r.URL.Path, Header: r.Header, Body: body})
+		w.WriteHeader(s.statusCode)
+	}))
+
+	return s
+}
+
+func (s *testOTLPServer) getReceived() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.received
+}
+
+func (s *testOTLPServer) getRequests() []*testOTLPRequest {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]*testOTLPRequest{}, s.requests...)
+}
+
+func (*OTLPSuite) TestOTLPSender(ctx context.Context, g *WithT) {
+	srv := newTestOTLPServer()
+	defer srv.Close()
+
+	sender := logm.NewOTLPSender(ctx, &logm.OTLPSenderConfig{
+		Endpoint: srv.URL + "/",
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+	g.Expect(sender.Start()).To(Succeed())
+
+	now := clkm.MustGet(ctx).Now()
+
+	sender.Add(&transmission.Event{
+		Timestamp: now,
+		Metadata:  "span",
+		Data: map[string]any{
+			"name":                "pgm.Query",
+			"duration_ms":         1.5,
+			"trace.trace_id":      "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			"trace.span_id":       "span-id",
+			"trace.parent_id":     "parent-id",
+			"span.kind":           "client",
+			"span.status":         "error",
+			"span.status.message": "failed",
+			"service.name":        "svc",
+			"db.statement":        "SELECT ?",
+			"db.rows_affected":    int64(3),
+			"flag":                true,
+		},
+	})
+
+	sender.Add(&transmission.Event{
+		Timestamp: now,
+		Metadata:  "log",
+		Data: map[string]any{
+			"name":                 "pgm.Query",
+			"meta.annotation_type": "span_event",
+			"trace.trace_id":       "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			"trace.parent_id":      "span-id",
+			"warning":              "generic",
+			"warning.message":      "msg",
+			"service.name":         "svc",
+		},
+	})
+
+	sender.Add(nil)
+	g.Expect(sender.Flush()).To(Succeed())
+
+	requests := srv.getRequests()
+	g.Expect(requests).To(HaveLen(2))
+	g.Expect(requests[0].Path).To(Equal("/v1/traces"))
+	g.Expect(requests[0].Header.Get("Authorization")).To(Equal("Bearer token"))
+	g.Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/json"))
+	g.Expect(requests[1].Path).To(Equal("/v1/logs"))
+
+	resourceSpans := requests[0].Body["resourceSpans"].([]any)[0].(map[string]any)
+	g.Expect(resourceSpans["resource"]).To(HaveKeyWithValue("attributes", ConsistOf(
+		map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "svc"}})))
+
+	span := resourceSpans["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	g.Expect(span).To(HaveKeyWithValue("traceId", "6ba7b8109dad11d180b400c04fd430c8"))
+	g.Expect(span).To(HaveKeyWithValue("spanId", HaveLen(16)))
+	g.Expect(span).To(HaveKeyWithValue("parentSpanId", HaveLen(16)))
+	g.Expect(span).To(HaveKeyWithValue("name", "pgm.Query"))
+	g.Expect(span).To(HaveKeyWithValue("kind", 3.0))
+	g.Expect(span).To(HaveKeyWithValue("startTimeUnixNano", BeAssignableToTypeOf("")))
+	g.Expect(span).To(HaveKeyWithValue("status", map[string]any{"code": 2.0, "message": "failed"}))
+	g.Expect(span).To(HaveKeyWithValue("attributes", ConsistOf(
+		map[string]any{"key": "db.rows_affected", "value": map[string]any{"intValue": "3"}},
+		map[string]any{"key": "db.statement", "value": map[string]any{"stringValue": "SELECT ?"}},
+		map[string]any{"key": "flag", "value": map[string]any{"boolValue": true}})))
+
+	logRecord := requests[1].Body["resourceLogs"].([]any)[0].(map[string]any)["scopeLogs"].([]any)[0].(map[string]any)["logRecords"].([]any)[0].(map[string]any)
+	g.Expect(logRecord).To(HaveKeyWithValue("severityNumber", 13.0))
+	g.Expect(logRecord).To(HaveKeyWithValue("severityText", "WARNING"))
+	g.Expect(logRecord).To(HaveKeyWithValue("body", map[string]any{"stringValue": "pgm.Query: msg"}))
+	g.Expect(logRecord).To(HaveKeyWithValue("traceId", span["traceId"]))
+	g.Expect(logRecord).To(HaveKeyWithValue("spanId", span["spanId"]))
+
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(And(
+		HaveField("Metadata", "span"),
+		HaveField("StatusCode", http.StatusOK))))
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(HaveField("Metadata", "log")))
+
+	g.Expect(sender.Stop()).To(Succeed())
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (s *OTLPSuite) TestOTLPSender_Batching(ctx context.Context, g *WithT) {
+	srv := newTestOTLPServer()
+	defer srv.Close()
+
+	sender := logm.NewOTLPSender(ctx, &logm.OTLPSenderConfig{
+		Endpoint:     srv.URL,
+		MaxBatchSize: 2,
+		BatchTimeout: time.Second,
+	})
+	g.Expect(sender.Start()).To(Succeed())
+
+	sender.Add(&transmission.Event{Data: map[string]any{"info": "i1"}})
+	g.Expect(srv.getRequests()).To(BeEmpty())
+	sender.Add(&transmission.Event{Data: map[string]any{"info": "i2"}})
+	g.Eventually(srv.getRequests, "1s", "10ms").Should(HaveLen(1))
+
+	sender.Add(&transmission.Event{Data: map[string]any{"info": "i3"}})
+
+	g.Eventually(func() []*testOTLPRequest {
+		s.CLK.GetMock().Add(time.Second)
+		return srv.getRequests()
+	}, "1s", "10ms").Should(HaveLen(2))
+
+	g.Expect(sender.Stop()).To(Succeed())
+}
+
+func (*OTLPSuite) TestOTLPSender_Links(ctx context.Context, g *WithT) {
+	srv := newTestOTLPServer()
+	defer srv.Close()
+
+	sender := logm.NewOTLPSender(ctx, &logm.OTLPSenderConfig{Endpoint: srv.URL})
+	g.Expect(sender.Start()).To(Succeed())
+
+	newLink := func(metadata, parentID string) *transmission.Event {
+		return &transmission.Event{
+			Metadata: metadata,
+			Data: map[string]any{
+				"name":                 "link-annotation",
+				"meta.annotation_type": "link",
+				"trace.trace_id":       "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				"trace.parent_id":      parentID,
+				"trace.link.trace_id":  "6ba7b811-9dad-11d1-80b4-00c04fd430c8",
+				"trace.link.span_id":   "linked-span-id",
+			},
+		}
+	}
+
+	// links are emitted before their parent span ends, and may be exported in a later batch.
+	sender.Add(newLink("link", "span-id"))
+	sender.Add(newLink("orphan", "other-span-id"))
+	g.Expect(sender.Flush()).To(Succeed())
+	g.Expect(srv.getRequests()).To(BeEmpty())
+
+	sender.Add(&transmission.Event{
+		Metadata: "span",
+		Data: map[string]any{
+			"name":           "span",
+			"duration_ms":    1.0,
+			"trace.trace_id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			"trace.span_id":  "span-id",
+		},
+	})
+	g.Expect(sender.Flush()).To(Succeed())
+
+	requests := srv.getRequests()
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Path).To(Equal("/v1/traces"))
+
+	span := requests[0].Body["resourceSpans"].([]any)[0].(map[string]any)["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	g.Expect(span).To(HaveKeyWithValue("links", ConsistOf(And(
+		HaveKeyWithValue("traceId", "6ba7b8119dad11d180b400c04fd430c8"),
+		HaveKeyWithValue("spanId", HaveLen(16))))))
+
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(HaveField("Metadata", "span")))
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(And(
+		HaveField("Metadata", "link"),
+		HaveField("StatusCode", http.StatusOK))))
+
+	g.Expect(sender.Stop()).To(Succeed())
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(And(
+		HaveField("Metadata", "orphan"),
+		HaveField("Err", MatchError("OTLP link parent span was not exported")))))
+}
+
+func (*OTLPSuite) TestOTLPSender_QueueFull(ctx context.Context, g *WithT) {
+	srv := newTestOTLPServer()
+	srv.gate = make(chan struct{})
+	defer srv.Close()
+
+	sender := logm.NewOTLPSender(ctx, &logm.OTLPSenderConfig{
+		Endpoint:         srv.URL,
+		MaxBatchSize:     1,
+		MaxQueuedBatches: 1,
+	})
+	g.Expect(sender.Start()).To(Succeed())
+
+	// "i1" is being exported, "i2" is queued, "i3" is dropped: Add never waits on the collector.
+	sender.Add(&transmission.Event{Metadata: "i1", Data: map[string]any{"info": "i1"}})
+	g.Eventually(srv.getReceived, "1s", "10ms").Should(Equal(1))
+	sender.Add(&transmission.Event{Metadata: "i2", Data: map[string]any{"info": "i2"}})
+	sender.Add(&transmission.Event{Metadata: "i3", Data: map[string]any{"info": "i3"}})
+
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(And(
+		HaveField("Metadata", "i3"),
+		HaveField("Err", MatchError("OTLP export queue is full")))))
+
+	close(srv.gate)
+	g.Expect(sender.Stop()).To(Succeed())
+	g.Expect(srv.getRequests()).To(HaveLen(2))
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(HaveField("Metadata", "i1")))
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(HaveField("Metadata", "i2")))
+}
+
+func (*OTLPSuite) TestOTLPSender_Error(ctx context.Context, g *WithT) {
+	srv := newTestOTLPServer()
+	srv.statusCode = http.StatusBadRequest
+	defer srv.Close()
+
+	sender := logm.NewOTLPSender(ctx, &logm.OTLPSenderConfig{Endpoint: srv.URL})
+	sender.Add(&transmission.Event{Metadata: "m", Data: map[string]any{"info": "i"}})
+
+	g.Expect(sender.Flush()).To(MatchError("OTLP export failed with status 400"))
+	g.Eventually(sender.TxResponses(), "1s", "10ms").Should(Receive(And(
+		HaveField("Metadata", "m"),
+		HaveField("StatusCode", http.StatusBadRequest),
+		HaveField("Err", HaveOccurred()))))
+
+	g.Expect(func() { logm.NewOTLPSender(ctx, &logm.OTLPSenderConfig{}) }).To(PanicWith(MatchError("OTLP endpoint is empty")))
+}
+
+func (*OTLPSuite) TestMustNewDefaultOTLPSender(ctx context.Context, g *WithT) {
+	g.Expect(logm.MustNewDefaultOTLPSender(
+		cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{
+			OTLPEndpoint: cfgm.DisabledValue,
+		})(ctx))).To(BeNil())
+
+	g.Expect(logm.MustNewDefaultOTLPSender(
+		cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{
+			OTLPEndpoint: "http://localhost:4318",
+		})(ctx))).ToNot(BeNil())
+}
@@ -0,0 +1,186 @@
+package logm
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"runtime"
+
+	"github.com/ibrt/golang-utils/errorz"
+)
+
+var (
+	_ encoding.TextUnmarshaler = (*PanicPolicy)(nil)
+)
+
+// PanicPolicy describes how Wrap*Panic helpers propagate panics recovered from the wrapped function.
+type PanicPolicy string
+
+// Known PanicPolicy values.
+const (
+	// PanicPolicyConvert panics with the recovered panic converted to an error.
+	PanicPolicyConvert PanicPolicy = "convert"
+	// PanicPolicyRepanic panics with the original recovered value, e.g. for upstream recovery handlers that inspect it.
+	// Nested Wrap*Panic helpers panic with the converted error instead, so that enclosing ones don't emit it again.
+	PanicPolicyRepanic PanicPolicy = "repanic"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (p *PanicPolicy) UnmarshalText(text []byte) error {
+	switch v := PanicPolicy(text); v {
+	case "":
+		*p = PanicPolicyConvert
+		return nil
+	case PanicPolicyConvert, PanicPolicyRepanic:
+		*p = v
+		return nil
+	default:
+		return errorz.Errorf("invalid value for PanicPolicy: '%s'", v)
+	}
+}
+
+// String implements the [fmt.Stringer] interface.
+func (p *PanicPolicy) String() string {
+	return string(*p)
+}
+
+type panicContextKey int
+
+const (
+	isRecoveringContextKey panicContextKey = iota
+)
+
+type panicInfo struct {
+	value      any
+	valueType  string
+	goroutines string
+}
+
+// IsPanicError returns true if the error was recovered from a panic by one of the Wrap* helpers.
+func IsPanicError(err error) bool {
+	_, ok := errorz.MaybeGetMetadata[*panicInfo](err, panicErrorMetadataKey)
+	return ok
+}
+
+func newPanicError(ctx context.Context, r any) error {
+	err := errorz.WrapRecover(r)
+
+	if IsPanicError(err) {
+		// re-panicked by a nested Wrap*Panic helper: keep the original panic info
+		return err
+	}
+
+	info := &panicInfo{
+		value:     r,
+		valueType: fmt.Sprintf("%T", r),
+	}
+
+	if maxBytes := getPanicOptions(ctx).panicGoroutineDumpMaxBytes; maxBytes > 0 {
+		info.goroutines = getGoroutineDump(maxBytes)
+	}
+
+	errorz.MaybeSetMetadata(err, panicErrorMetadataKey, info)
+	return err
+}
+
+// withIsRecovering marks the context as running inside a helper that recovers panics.
+func withIsRecovering(ctx context.Context) context.Context {
+	return context.WithValue(ctx, isRecoveringContextKey, true)
+}
+
+func getGoroutineDump(maxBytes int) string {
+	buf := make([]byte, maxBytes)
+	n := runtime.Stack(buf, true)
+
+	if n == len(buf) {
+		return string(buf) + "\n... (truncated)"
+	}
+
+	return string(buf[:n])
+}
+
+func getPanicOptions(ctx context.Context) *rawLogOptions {
+	if bL, ok := getRootRawLog(ctx).(*backgroundLogImpl); ok {
+		return bL.o
+	}
+
+	return newRawLogOptions()
+}
+
+func maybeRepanic(ctx context.Context, err error) {
+	if getPanicOptions(ctx).panicPolicy != PanicPolicyRepanic {
+		return
+	}
+
+	if ctx.Value(isRecoveringContextKey) != nil {
+		// an enclosing Wrap* helper recovers the error, which is already marked as emitted
+		return
+	}
+
+	if info, ok := errorz.MaybeGetMetadata[*panicInfo](err, panicErrorMetadataKey); ok {
+		panic(info.value)
+	}
+}
+
+func addPanicFields(af AddField, err error) {
+	if info, ok := errorz.MaybeGetMetadata[*panicInfo](err, panicErrorMetadataKey); ok {
+		af.AddField("error.panic", true)
+		af.AddField("error.panic.type", info.valueType)
+		maybeAddLenField(af, "", "error.panic.goroutines", info.goroutines)
+	}
+}
+
+func catch0Ctx(ctx context.Context, f func(ctx context.Context) error) (outErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			outErr = newPanicError(ctx, r)
+		}
+	}()
+
+	return errorz.MaybeWrap(f(withIsRecovering(ctx)))
+}
+
+func catch1Ctx[T any](ctx context.Context, f func(ctx context.Context) (T, error)) (outV T, outErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var t T
+			outV = t
+			outErr = newPanicError(ctx, r)
+		}
+	}()
+
+	out, err := f(withIsRecovering(ctx))
+	return out, errorz.MaybeWrap(err)
+}
+
+func catch2Ctx[T1 any, T2 any](ctx context.Context, f func(ctx context.Context) (T1, T2, error)) (outV1 T1, outV2 T2, outErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var t1 T1
+			var t2 T2
+			outV1 = t1
+			outV2 = t2
+			outErr = newPanicError(ctx, r)
+		}
+	}()
+
+	out1, out2, err := f(withIsRecovering(ctx))
+	return out1, out2, errorz.MaybeWrap(err)
+}
+
+func catch3Ctx[T1 any, T2 any, T3 any](ctx context.Context, f func(ctx context.Context) (T1, T2, T3, error)) (outV1 T1, outV2 T2, outV3 T3, outErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var t1 T1
+			var t2 T2
+			var t3 T3
+			outV1 = t1
+			outV2 = t2
+			outV3 = t3
+			outErr = newPanicError(ctx, r)
+		}
+	}()
+
+	out1, out2, out3, err := f(withIsRecovering(ctx))
+	return out1, out2, out3, errorz.MaybeWrap(err)
+}
@@ -0,0 +1,168 @@
+package logm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type PanicSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestPanicSuite(t *testing.T) {
+	fixturez.RunSuite(t, &PanicSuite{})
+}
+
+func (s *PanicSuite) newContext(ctx context.Context, g *WithT, options ...logm.RawLogOption) (context.Context, *tlogm.MockSender, func()) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+
+	return logm.NewSingletonInjector(logm.NewRawLogFromClient(client, options...))(ctx), sender, client.Close
+}
+
+func (s *PanicSuite) TestPanicPolicy(g *WithT) {
+	p := logm.PanicPolicy("")
+	g.Expect(p.UnmarshalText([]byte(""))).To(Succeed())
+	g.Expect(p).To(Equal(logm.PanicPolicyConvert))
+	g.Expect(p.UnmarshalText([]byte("repanic"))).To(Succeed())
+	g.Expect(p).To(Equal(logm.PanicPolicyRepanic))
+	g.Expect(p.String()).To(Equal("repanic"))
+	g.Expect(p.UnmarshalText([]byte("unknown"))).To(MatchError("invalid value for PanicPolicy: 'unknown'"))
+}
+
+func (s *PanicSuite) TestConvert(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g)
+	defer closer()
+
+	err := logm.Wrap0(ctx, "wrap0", func(ctx context.Context) error {
+		panic("test panic")
+	})
+	g.Expect(err).To(MatchError("test panic"))
+	g.Expect(logm.IsPanicError(err)).To(BeTrue())
+	g.Expect(logm.IsPanicError(errorz.Errorf("test error"))).To(BeFalse())
+
+	g.Expect(func() {
+		logm.Wrap0Panic(ctx, "wrap0", func(ctx context.Context) error {
+			panic("test panic")
+		})
+	}).To(PanicWith(MatchError("test panic")))
+
+	g.Expect(sender.GetEvents()).To(HaveEach(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": Or(
+				And(
+					HaveKeyWithValue("name", "error"),
+					HaveKeyWithValue("error.panic", true),
+					HaveKeyWithValue("error.panic.type", "string"),
+					Not(HaveKey("error.panic.goroutines"))),
+				And(
+					HaveKeyWithValue("name", "wrap0"),
+					HaveKeyWithValue("error", true))),
+		}))))
+}
+
+func (s *PanicSuite) TestRepanic(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g, logm.RawLogPanics(logm.PanicPolicyRepanic, 0))
+	defer closer()
+
+	type panicValue struct {
+		v string
+	}
+
+	g.Expect(func() {
+		logm.Wrap1Panic(ctx, "wrap1", func(ctx context.Context) (int, error) {
+			panic(&panicValue{v: "test panic"})
+		})
+	}).To(PanicWith(Equal(&panicValue{v: "test panic"})))
+
+	g.Expect(func() {
+		logm.Wrap1Panic(ctx, "wrap1", func(ctx context.Context) (int, error) {
+			return 0, errorz.Errorf("test error")
+		})
+	}).To(PanicWith(MatchError("test error")))
+
+	g.Expect(sender.GetEvents()).To(ContainElement(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": And(
+				HaveKeyWithValue("name", "error"),
+				HaveKeyWithValue("error.panic", true),
+				HaveKeyWithValue("error.panic.type", "*logm_test.panicValue")),
+		}))))
+}
+
+func (s *PanicSuite) TestNested(ctx context.Context, g *WithT) {
+	type panicValue struct {
+		v string
+	}
+
+	for _, policy := range []logm.PanicPolicy{logm.PanicPolicyConvert, logm.PanicPolicyRepanic} {
+		func() {
+			ctx, sender, closer := s.newContext(ctx, g, logm.RawLogPanics(policy, 0))
+			defer closer()
+
+			f := func() {
+				logm.Wrap0Panic(ctx, "outer", func(ctx context.Context) error {
+					logm.Wrap0Panic(ctx, "inner", func(ctx context.Context) error {
+						panic(&panicValue{v: "test panic"})
+					})
+					return nil
+				})
+			}
+
+			if policy == logm.PanicPolicyRepanic {
+				g.Expect(f).To(PanicWith(Equal(&panicValue{v: "test panic"})))
+			} else {
+				g.Expect(f).To(PanicWith(Satisfy(logm.IsPanicError)))
+			}
+
+			g.Expect(sender.GetEvents()).To(HaveExactElements(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Data": And(
+						HaveKeyWithValue("name", "error"),
+						HaveKeyWithValue("error.panic.type", "*logm_test.panicValue")),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Data": And(HaveKeyWithValue("name", "inner"), HaveKeyWithValue("error", true)),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Data": And(HaveKeyWithValue("name", "outer"), HaveKeyWithValue("error", true)),
+				}))), "%v", policy)
+		}()
+	}
+}
+
+func (s *PanicSuite) TestGoroutineDump(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g, logm.RawLogPanics(logm.PanicPolicyConvert, 64))
+	defer closer()
+
+	g.Expect(logm.Wrap0(ctx, "wrap0", func(ctx context.Context) error {
+		panic("test panic")
+	})).To(MatchError("test panic"))
+
+	g.Expect(sender.GetEvents()).To(ContainElement(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": And(
+				HaveKeyWithValue("name", "error"),
+				HaveKeyWithValue("error.panic.goroutines", And(
+					HavePrefix("goroutine "),
+					HaveSuffix("... (truncated)"))),
+			),
+		}))))
+}
@@ -0,0 +1,287 @@
+package logm
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/ibrt/golang-utils/hashz"
+	"github.com/ibrt/golang-utils/jsonz"
+)
+
+// PayloadLimits describes limits enforced on the fields attached to each event. Metadata fields (including params and
+// payloads) may be dropped, while messages and error dumps are only truncated. Zero values disable the corresponding
+// limit.
+type PayloadLimits struct {
+	// MaxFields is the maximum number of metadata fields per event. Fields in excess are dropped.
+	MaxFields int
+
+	// MaxStringBytes is the maximum length of string fields. Longer strings are truncated.
+	MaxStringBytes int
+
+	// MaxTotalBytes is the maximum total size of an event, as JSON, including the fields that are never dropped (e.g.
+	// messages and error dumps). Metadata fields that would exceed it are dropped.
+	MaxTotalBytes int
+
+	// MaxDepth is the maximum nesting depth of structured fields. Deeper values are replaced by their JSON encoding.
+	MaxDepth int
+
+	// MaxBlobBytes is the maximum size of a field value. Larger values are replaced by their SHA256 hash.
+	MaxBlobBytes int
+}
+
+// eventFields describes the ability to list the fields already added to an event (e.g. [*libhoney.Event]).
+type eventFields interface {
+	Fields() map[string]any
+}
+
+// payloadValue wraps a structured value attached using [EmitPayload] or [BeginPayload], so that it can be flattened.
+type payloadValue struct {
+	v any
+}
+
+// payloadLimiter is an [AddField] that enforces [*PayloadLimits] on the fields added to an event, and flattens
+// payloads. Fields that are modified are marked by an additional "<key>.truncated" field. If fields are dropped, the
+// event is marked by "payload.truncated" and "payload.dropped_fields" fields when finish is called. Fields already added
+// to the event count towards MaxTotalBytes.
+type payloadLimiter struct {
+	af      AddField
+	limits  *PayloadLimits
+	fields  int
+	bytes   int
+	dropped int
+}
+
+func newPayloadLimiter(af AddField, limits *PayloadLimits) *payloadLimiter {
+	if limits == nil {
+		limits = &PayloadLimits{}
+	}
+
+	l := &payloadLimiter{
+		af:     af,
+		limits: limits,
+	}
+
+	if ef, ok := af.(eventFields); ok && limits.MaxTotalBytes > 0 {
+		for k, v := range ef.Fields() {
+			l.bytes += len(k) + getPayloadSize(v)
+		}
+	}
+
+	return l
+}
+
+// required returns an [AddField] that adds fields which are never dropped, such as messages and error dumps.
+func (l *payloadLimiter) required() AddField {
+	return (*requiredPayloadLimiter)(l)
+}
+
+// AddField implements the [AddField] interface.
+func (l *payloadLimiter) AddField(k string, v any) {
+	if p, ok := v.(*payloadValue); ok {
+		l.addPayload(k, normalizePayloadValue(p.v), 1)
+		return
+	}
+
+	l.add(k, v, 1)
+}
+
+func (l *payloadLimiter) addPayload(k string, v any, depth int) {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) == 0 || l.limits.MaxDepth > 0 && depth > l.limits.MaxDepth {
+		l.add(k, v, depth)
+		return
+	}
+
+	for _, kk := range slices.Sorted(maps.Keys(m)) {
+		l.addPayload(k+"."+kk, m[kk], depth+1)
+	}
+}
+
+func (l *payloadLimiter) add(k string, v any, depth int) {
+	if l.limits.MaxFields > 0 && l.fields >= l.limits.MaxFields {
+		l.dropped++
+		return
+	}
+
+	v, isTruncated := l.limitValue(v, depth)
+
+	if l.limits.MaxTotalBytes > 0 {
+		size := len(k) + getPayloadSize(v)
+
+		if l.bytes+size > l.limits.MaxTotalBytes {
+			l.dropped++
+			return
+		}
+
+		l.bytes += size
+	}
+
+	l.fields++
+	l.af.AddField(k, v)
+
+	if isTruncated {
+		l.af.AddField(k+".truncated", true)
+	}
+}
+
+// addRequired adds a field that is never dropped. Long strings are truncated rather than hashed, so that they remain
+// readable, and the field counts towards MaxTotalBytes.
+func (l *payloadLimiter) addRequired(k string, v any) {
+	isTruncated := false
+
+	if s, ok := v.(string); ok && l.limits.MaxStringBytes > 0 && len(s) > l.limits.MaxStringBytes {
+		v, isTruncated = truncateString(s, l.limits.MaxStringBytes), true
+	}
+
+	if l.limits.MaxTotalBytes > 0 {
+		l.bytes += len(k) + getPayloadSize(v)
+	}
+
+	l.af.AddField(k, v)
+
+	if isTruncated {
+		l.af.AddField(k+".truncated", true)
+	}
+}
+
+func (l *payloadLimiter) limitValue(v any, depth int) (any, bool) {
+	isTruncated := false
+
+	if l.limits.MaxDepth > 0 && isStructuredPayloadValue(v) {
+		if lv, ok := limitPayloadDepth(normalizePayloadValue(v), depth, l.limits.MaxDepth); ok {
+			v, isTruncated = lv, true
+		}
+	}
+
+	if l.limits.MaxBlobBytes > 0 {
+		if buf := getPayloadBytes(v); len(buf) > l.limits.MaxBlobBytes {
+			return "sha256:" + hashz.MustHashSHA256(buf), true
+		}
+	}
+
+	if s, ok := v.(string); ok && l.limits.MaxStringBytes > 0 && len(s) > l.limits.MaxStringBytes {
+		return truncateString(s, l.limits.MaxStringBytes), true
+	}
+
+	return v, isTruncated
+}
+
+// finish marks the event as truncated if any fields were dropped.
+func (l *payloadLimiter) finish() {
+	if l.dropped > 0 {
+		l.af.AddField("payload.truncated", true)
+		l.af.AddField("payload.dropped_fields", l.dropped)
+	}
+}
+
+// requiredPayloadLimiter is an [AddField] that adds fields using [*payloadLimiter.addRequired].
+type requiredPayloadLimiter payloadLimiter
+
+// AddField implements the [AddField] interface.
+func (l *requiredPayloadLimiter) AddField(k string, v any) {
+	(*payloadLimiter)(l).addRequired(k, v)
+}
+
+func isStructuredPayloadValue(v any) bool {
+	if v == nil {
+		return false
+	}
+
+	switch rv := reflect.Indirect(reflect.ValueOf(v)); rv.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Array:
+		return true
+	case reflect.Slice:
+		return rv.Type().Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
+
+// normalizePayloadValue converts structured values to their generic JSON representation.
+func normalizePayloadValue(v any) any {
+	if !isStructuredPayloadValue(v) {
+		return v
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return jsonz.MustUnmarshal[any](buf)
+}
+
+func limitPayloadDepth(v any, depth, maxDepth int) (any, bool) {
+	switch vv := v.(type) {
+	case map[string]any:
+		if depth > maxDepth {
+			return string(jsonz.MustMarshal(vv)), true
+		}
+
+		m := make(map[string]any, len(vv))
+		isTruncated := false
+
+		for k, x := range vv {
+			var t bool
+			m[k], t = limitPayloadDepth(x, depth+1, maxDepth)
+			isTruncated = isTruncated || t
+		}
+
+		return m, isTruncated
+	case []any:
+		if depth > maxDepth {
+			return string(jsonz.MustMarshal(vv)), true
+		}
+
+		s := make([]any, len(vv))
+		isTruncated := false
+
+		for i, x := range vv {
+			var t bool
+			s[i], t = limitPayloadDepth(x, depth+1, maxDepth)
+			isTruncated = isTruncated || t
+		}
+
+		return s, isTruncated
+	default:
+		return v, false
+	}
+}
+
+func getPayloadBytes(v any) []byte {
+	switch vv := v.(type) {
+	case string:
+		return []byte(vv)
+	case []byte:
+		return vv
+	default:
+		if isStructuredPayloadValue(v) {
+			if buf, err := json.Marshal(v); err == nil {
+				return buf
+			}
+		}
+		return nil
+	}
+}
+
+func getPayloadSize(v any) int {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+
+	return len(buf)
+}
+
+// truncateString truncates the given string to at most maxBytes, without splitting multibyte characters.
+func truncateString(s string, maxBytes int) string {
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+
+	return s[:maxBytes]
+}
@@ -0,0 +1,133 @@
+package logm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibrt/golang-utils/fixturez"
+	"github.com/ibrt/golang-utils/hashz"
+	. "github.com/onsi/gomega"
+)
+
+type PayloadSuite struct {
+	// intentionally empty
+}
+
+func TestPayloadSuite(t *testing.T) {
+	fixturez.RunSuite(t, &PayloadSuite{})
+}
+
+func (*PayloadSuite) TestPayloadLimiter_Unlimited(g *WithT) {
+	type testStruct struct {
+		Key    string         `json:"key"`
+		Nested map[string]any `json:"nested"`
+	}
+
+	af := newTestAddField()
+	pl := newPayloadLimiter(af, nil)
+	addMetadataFields(pl, "prefix", map[string]any{
+		"k1": strings.Repeat("x", 1000),
+		"k2": &payloadValue{v: &testStruct{Key: "v", Nested: map[string]any{"a": map[string]any{"b": 1}}}},
+		"k3": &payloadValue{v: 1},
+	})
+	pl.finish()
+
+	g.Expect(af.fields).To(Equal(map[string]any{
+		"prefix.k1":            strings.Repeat("x", 1000),
+		"prefix.k2.key":        "v",
+		"prefix.k2.nested.a.b": float64(1),
+		"prefix.k3":            1,
+	}))
+}
+
+func (*PayloadSuite) TestPayloadLimiter_Strings(g *WithT) {
+	af := newTestAddField()
+	pl := newPayloadLimiter(af, &PayloadLimits{MaxStringBytes: 4, MaxBlobBytes: 8})
+	addMetadataFields(pl, "", map[string]any{
+		"k1": "abcd",
+		"k2": "abcdef",
+		"k3": "aé€",
+		"k4": "abcdefghi",
+		"k5": []byte("abcdefghi"),
+	})
+	pl.finish()
+
+	g.Expect(af.fields).To(Equal(map[string]any{
+		"k1":           "abcd",
+		"k2":           "abcd",
+		"k2.truncated": true,
+		"k3":           "aé",
+		"k3.truncated": true,
+		"k4":           "sha256:" + hashz.MustHashSHA256([]byte("abcdefghi")),
+		"k4.truncated": true,
+		"k5":           "sha256:" + hashz.MustHashSHA256([]byte("abcdefghi")),
+		"k5.truncated": true,
+	}))
+}
+
+func (*PayloadSuite) TestPayloadLimiter_Depth(g *WithT) {
+	af := newTestAddField()
+	pl := newPayloadLimiter(af, &PayloadLimits{MaxDepth: 2})
+	addMetadataFields(pl, "", map[string]any{
+		"k1": map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}},
+		"k2": &payloadValue{v: map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}}},
+		"k3": []string{"a", "b"},
+	})
+	pl.finish()
+
+	g.Expect(af.fields).To(Equal(map[string]any{
+		"k1":               map[string]any{"a": map[string]any{"b": `{"c":1}`}},
+		"k1.truncated":     true,
+		"k2.a.b":           `{"c":1}`,
+		"k2.a.b.truncated": true,
+		"k3":               []string{"a", "b"},
+	}))
+}
+
+func (*PayloadSuite) TestPayloadLimiter_Fields(g *WithT) {
+	{
+		af := newTestAddField()
+		pl := newPayloadLimiter(af, &PayloadLimits{MaxFields: 2})
+		addMetadataFields(pl, "", map[string]any{"k1": 1, "k2": 2, "k3": 3, "k4": 4})
+		pl.finish()
+
+		g.Expect(af.fields).To(Equal(map[string]any{
+			"k1":                     1,
+			"k2":                     2,
+			"payload.truncated":      true,
+			"payload.dropped_fields": 2,
+		}))
+	}
+	{
+		af := newTestAddField()
+		pl := newPayloadLimiter(af, &PayloadLimits{MaxTotalBytes: 12})
+		addMetadataFields(pl, "", map[string]any{"k1": "v1", "k2": "long-value", "k3": "v3"})
+		pl.finish()
+
+		g.Expect(af.fields).To(Equal(map[string]any{
+			"k1":                     "v1",
+			"k3":                     "v3",
+			"payload.truncated":      true,
+			"payload.dropped_fields": 1,
+		}))
+	}
+}
+
+func (*PayloadSuite) TestPayloadLimiter_Required(g *WithT) {
+	af := newTestAddField()
+	af.AddField("existing", "0123456789")
+
+	pl := newPayloadLimiter(af, &PayloadLimits{MaxStringBytes: 8, MaxTotalBytes: 45, MaxBlobBytes: 4})
+	pl.required().AddField("message", "long message")
+	addMetadataFields(pl, "", map[string]any{"k1": "v1", "k2": "v2"})
+	pl.finish()
+
+	g.Expect(af.fields).To(Equal(map[string]any{
+		"existing":               "0123456789",
+		"message":                "long mes",
+		"message.truncated":      true,
+		"k1":                     "v1",
+		"payload.truncated":      true,
+		"payload.dropped_fields": 1,
+	}))
+}
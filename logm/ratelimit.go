@@ -0,0 +1,317 @@
+package logm
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+const (
+	rateLimiterCallerMaxFrames = 16
+)
+
+var (
+	_ encoding.TextUnmarshaler = (*RateLimitPolicy)(nil)
+)
+
+// RateLimitPolicy describes how debug and info events are rate-limited per call site.
+type RateLimitPolicy string
+
+// Known RateLimitPolicy values.
+const (
+	// RateLimitPolicyTokenBucket allows bursts of up to burst events, refilled at a rate of burst events per window.
+	RateLimitPolicyTokenBucket RateLimitPolicy = "token-bucket"
+
+	// RateLimitPolicySample allows the first burst events in each window, then one every sampleEvery events.
+	RateLimitPolicySample RateLimitPolicy = "sample"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (p *RateLimitPolicy) UnmarshalText(text []byte) error {
+	switch v := RateLimitPolicy(text); v {
+	case "":
+		*p = RateLimitPolicyTokenBucket
+		return nil
+	case RateLimitPolicyTokenBucket, RateLimitPolicySample:
+		*p = v
+		return nil
+	default:
+		return errorz.Errorf("invalid value for RateLimitPolicy: '%s'", v)
+	}
+}
+
+// String implements the [fmt.Stringer] interface.
+func (p *RateLimitPolicy) String() string {
+	return string(*p)
+}
+
+type rateLimiterKey struct {
+	level string
+	key   string
+}
+
+type rateLimiterEntry struct {
+	windowStart time.Time
+	count       int
+	suppressed  int
+	tokens      float64
+	refilledAt  time.Time
+}
+
+// rateLimiter rate-limits debug and info events by call site (or explicit key, see [EmitRateLimitKey]). Suppressed
+// occurrences are counted and reported in a summary event when the window expires or the log is flushed. Token buckets
+// are kept across windows until they are full again. Expired windows are swept by a background goroutine, which runs
+// only while there are entries, until stopped.
+type rateLimiter struct {
+	ne          newEvent
+	policy      RateLimitPolicy
+	window      time.Duration
+	burst       int
+	sampleEvery int
+	m           *sync.Mutex
+	entries     map[rateLimiterKey]*rateLimiterEntry
+	isSweeping  bool
+	isStopped   bool
+	stopC       chan struct{}
+	doneC       chan struct{}
+}
+
+func newRateLimiter(ne newEvent, o *rawLogOptions) *rateLimiter {
+	if o.rateLimitWindow <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		ne:          ne,
+		policy:      o.rateLimitPolicy,
+		window:      o.rateLimitWindow,
+		burst:       max(o.rateLimitBurst, 1),
+		sampleEvery: o.rateLimitSampleEvery,
+		m:           &sync.Mutex{},
+		entries:     make(map[rateLimiterKey]*rateLimiterEntry),
+		stopC:       make(chan struct{}),
+	}
+}
+
+// allow records an occurrence of an event with the given level, and returns whether it should be emitted. The key
+// defaults to the location of the caller. A nil limiter allows all events.
+func (l *rateLimiter) allow(ctx context.Context, level string, o *emitOptions) bool {
+	if l == nil {
+		return true
+	}
+
+	k := rateLimiterKey{
+		level: level,
+		key:   o.rateLimitKey,
+	}
+
+	if k.key == "" {
+		k.key = getCallerLocation()
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	now := clkm.MustGet(ctx).Now()
+	entry, ok := l.entries[k]
+
+	if ok && now.Sub(entry.windowStart) >= l.window {
+		// the sweeper has not caught up with this entry yet
+		ok = l.endWindow(ctx, now, k, entry)
+	}
+
+	if !ok {
+		entry = &rateLimiterEntry{
+			windowStart: now,
+			tokens:      float64(l.burst),
+			refilledAt:  now,
+		}
+		l.entries[k] = entry
+		l.maybeStartSweeping(ctx)
+	}
+
+	entry.count++
+
+	if l.isAllowed(entry, now) {
+		return true
+	}
+
+	entry.suppressed++
+	return false
+}
+
+func (l *rateLimiter) isAllowed(entry *rateLimiterEntry, now time.Time) bool {
+	switch l.policy {
+	case RateLimitPolicySample:
+		if entry.count <= l.burst {
+			return true
+		}
+		return l.sampleEvery > 0 && (entry.count-l.burst)%l.sampleEvery == 0
+	default:
+		if l.refill(entry, now) >= 1 {
+			entry.tokens--
+			return true
+		}
+		return false
+	}
+}
+
+// refill refills the token bucket of the given entry at a rate of burst tokens per window, and returns the tokens.
+func (l *rateLimiter) refill(entry *rateLimiterEntry, now time.Time) float64 {
+	entry.tokens = min(
+		float64(l.burst),
+		entry.tokens+float64(l.burst)*float64(now.Sub(entry.refilledAt))/float64(l.window))
+	entry.refilledAt = now
+	return entry.tokens
+}
+
+// flush emits summary events for all entries with suppressed occurrences, starting new windows for them.
+func (l *rateLimiter) flush(ctx context.Context) {
+	if l == nil {
+		return
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.sweep(ctx, clkm.MustGet(ctx).Now(), true)
+}
+
+// stop stops the sweeper goroutine and waits for it to exit. Suppressed events are still reported by flush.
+func (l *rateLimiter) stop() {
+	if l == nil {
+		return
+	}
+
+	l.m.Lock()
+
+	if l.isStopped {
+		l.m.Unlock()
+		return
+	}
+
+	l.isStopped = true
+	close(l.stopC)
+	doneC := l.doneC
+	l.m.Unlock()
+
+	if doneC != nil {
+		<-doneC
+	}
+}
+
+// maybeStartSweeping starts a goroutine that sweeps expired windows until there are no entries left or the limiter is
+// stopped, if not running.
+func (l *rateLimiter) maybeStartSweeping(ctx context.Context) {
+	if l.isSweeping || l.isStopped {
+		return
+	}
+
+	l.isSweeping = true
+	l.doneC = make(chan struct{})
+	ctx = context.WithoutCancel(ctx)
+	clk := clkm.MustGet(ctx)
+	timer := clk.Timer(l.window)
+
+	go func(doneC chan struct{}) {
+		defer close(doneC)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-l.stopC:
+				return
+			case <-timer.C:
+				l.m.Lock()
+				now := clk.Now()
+				l.sweep(ctx, now, false)
+
+				if len(l.entries) == 0 {
+					l.isSweeping = false
+					l.m.Unlock()
+					return
+				}
+
+				timer.Reset(l.getNextExpiry(now))
+				l.m.Unlock()
+			}
+		}
+	}(l.doneC)
+}
+
+// getNextExpiry returns the time until the earliest window expires.
+func (l *rateLimiter) getNextExpiry(now time.Time) time.Duration {
+	next := l.window
+
+	for _, entry := range l.entries {
+		next = min(next, entry.windowStart.Add(l.window).Sub(now))
+	}
+
+	return max(next, 0)
+}
+
+func (l *rateLimiter) sweep(ctx context.Context, now time.Time, force bool) {
+	for k, entry := range l.entries {
+		if now.Sub(entry.windowStart) < l.window && (!force || entry.suppressed == 0) {
+			continue
+		}
+
+		l.endWindow(ctx, now, k, entry)
+	}
+}
+
+// endWindow emits a summary event for the given entry if it has suppressed occurrences, and starts a new window. The
+// entry is removed (and false is returned) if it carries no state into the new window, i.e. if sampling, or if its
+// token bucket is full again, so that ending a window never grants a fresh burst.
+func (l *rateLimiter) endWindow(ctx context.Context, now time.Time, k rateLimiterKey, entry *rateLimiterEntry) bool {
+	if entry.suppressed > 0 {
+		e := newAttachableEvent(ctx, l.ne, "", "rate-limit-summary")
+		e.Timestamp = entry.windowStart
+		e.AddField("rate_limit.level", k.level)
+		e.AddField("rate_limit.key", k.key)
+		e.AddField("rate_limit.policy", string(l.policy))
+		e.AddField("rate_limit.count", entry.count)
+		e.AddField("rate_limit.suppressed", entry.suppressed)
+		e.AddField("rate_limit.window_ms", float64(now.Sub(entry.windowStart))/float64(time.Millisecond))
+		errorz.MaybeMustWrap(e.Send())
+	}
+
+	entry.windowStart = now
+	entry.count = 0
+	entry.suppressed = 0
+
+	if l.policy == RateLimitPolicySample || l.refill(entry, now) >= float64(l.burst) {
+		delete(l.entries, k)
+		return false
+	}
+
+	return true
+}
+
+// getCallerLocation returns the location of the first caller outside of this package, as "function (file:line)". It
+// only resolves the frames it needs, unlike [getLocationFrames] which captures and formats the whole stack.
+func getCallerLocation() string {
+	pcs := make([]uintptr, rateLimiterCallerMaxFrames)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	for {
+		f, more := frames.Next()
+
+		if i := strings.LastIndex(f.Function, "/"); f.Function != "" && !strings.HasPrefix(f.Function[i+1:], "logm.") {
+			return fmt.Sprintf("%v (%v:%v)", f.Function[i+1:], filepath.Base(f.File), f.Line)
+		}
+
+		if !more {
+			return "<unknown>"
+		}
+	}
+}
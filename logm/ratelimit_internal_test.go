@@ -0,0 +1,52 @@
+package logm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+)
+
+type RateLimitInternalSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestRateLimitInternalSuite(t *testing.T) {
+	fixturez.RunSuite(t, &RateLimitInternalSuite{})
+}
+
+func (s *RateLimitInternalSuite) TestRateLimiter_Stop(ctx context.Context, g *WithT) {
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		Transmission: &transmission.DiscardSender{},
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	l := newRateLimiter(client, newRawLogOptions(RawLogRateLimit(RateLimitPolicyTokenBucket, time.Minute, 1, 0)))
+	g.Expect(l.allow(ctx, "info", &emitOptions{rateLimitKey: "k1"})).To(BeTrue())
+	g.Expect(l.isSweeping).To(BeTrue())
+
+	l.stop()
+	g.Expect(l.doneC).To(BeClosed())
+	l.stop()
+
+	// The sweeper is not restarted once stopped.
+	doneC := l.doneC
+	s.CLK.GetMock().Add(time.Minute)
+	l.flush(ctx)
+	g.Expect(l.entries).To(BeEmpty())
+	g.Expect(l.allow(ctx, "info", &emitOptions{rateLimitKey: "k2"})).To(BeTrue())
+	g.Expect(l.entries).To(HaveLen(1))
+	g.Expect(l.doneC).To(Equal(doneC))
+
+	var nilLimiter *rateLimiter
+	g.Expect(nilLimiter.stop).ToNot(Panic())
+}
@@ -0,0 +1,163 @@
+package logm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type RateLimitSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	fixturez.RunSuite(t, &RateLimitSuite{})
+}
+
+func (s *RateLimitSuite) newContext(ctx context.Context, g *WithT, options ...logm.RawLogOption) (context.Context, *tlogm.MockSender, func()) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+
+	return logm.NewSingletonInjector(logm.NewRawLogFromClient(client, options...))(ctx), sender, client.Close
+}
+
+func (s *RateLimitSuite) getMessages(sender *tlogm.MockSender) []string {
+	messages := make([]string, 0)
+
+	for _, e := range sender.GetEvents() {
+		switch e.Data["name"] {
+		case "debug":
+			messages = append(messages, e.Data["debug.message"].(string))
+		case "info":
+			messages = append(messages, e.Data["info.message"].(string))
+		case "rate-limit-summary":
+			messages = append(messages, fmt.Sprintf("summary:%v:%v", e.Data["rate_limit.level"], e.Data["rate_limit.suppressed"]))
+		}
+	}
+
+	return messages
+}
+
+func (s *RateLimitSuite) TestTokenBucket(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g, logm.RawLogRateLimit(logm.RateLimitPolicyTokenBucket, time.Minute, 2, 0))
+	defer closer()
+
+	emit := func(n int) {
+		for i := 0; i < n; i++ {
+			logm.MustGet(ctx).EmitInfo("i%v", logm.EmitA(i))
+		}
+	}
+
+	emit(5)
+	logm.MustGet(ctx).EmitDebug("d")
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("i0", "i1", "d"))
+
+	s.CLK.GetMock().Add(30 * time.Second)
+	emit(2)
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("i0", "i1", "d", "i0"))
+
+	s.CLK.GetMock().Add(30 * time.Second)
+	emit(1)
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("i0", "i1", "d", "i0", "summary:info:4", "i0"))
+
+	g.Expect(sender.GetEvents()).To(ContainElement(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Data": And(
+			HaveKeyWithValue("name", "rate-limit-summary"),
+			HaveKeyWithValue("rate_limit.key", MatchRegexp(`^logm_test\.\(\*RateLimitSuite\)\.TestTokenBucket\.func1 \(ratelimit_test\.go:\d+\)$`)),
+			HaveKeyWithValue("rate_limit.policy", "token-bucket"),
+			HaveKeyWithValue("rate_limit.count", 7),
+			HaveKeyWithValue("rate_limit.suppressed", 4),
+			HaveKeyWithValue("rate_limit.window_ms", float64(time.Minute/time.Millisecond))),
+	}))))
+}
+
+func (s *RateLimitSuite) TestTokenBucket_AcrossWindows(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g, logm.RawLogRateLimit(logm.RateLimitPolicyTokenBucket, time.Minute, 2, 0))
+	defer closer()
+
+	emit := func(n int) {
+		for i := 0; i < n; i++ {
+			logm.MustGet(ctx).EmitInfo("i%v", logm.EmitA(i), logm.EmitRateLimitKey("key"))
+		}
+	}
+
+	emit(1)
+	s.CLK.GetMock().Add(59 * time.Second)
+	emit(2)
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("i0", "i0", "i1"))
+
+	// The bucket is empty when the window ends: a new window doesn't grant a fresh burst.
+	s.CLK.GetMock().Add(time.Second)
+	emit(2)
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("i0", "i0", "i1"))
+
+	// Summaries are emitted when the window expires, without waiting for another event.
+	g.Eventually(func() []string {
+		s.CLK.GetMock().Add(10 * time.Second)
+		return s.getMessages(sender)
+	}).Should(HaveExactElements("i0", "i0", "i1", "summary:info:2"))
+}
+
+func (s *RateLimitSuite) TestSample(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g, logm.RawLogRateLimit(logm.RateLimitPolicySample, time.Minute, 2, 3))
+	defer closer()
+
+	spanCtx, end := logm.MustGet(ctx).Begin("span")
+	defer end()
+
+	for i := 0; i < 8; i++ {
+		logm.MustGet(spanCtx).EmitDebug("d%v", logm.EmitA(i), logm.EmitRateLimitKey("key"))
+	}
+
+	logm.MustGet(spanCtx).EmitDebug("other", logm.EmitRateLimitKey("other"))
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("d0", "d1", "d4", "d7", "other"))
+
+	logm.MustGet(ctx).Flush()
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("d0", "d1", "d4", "d7", "other", "summary:debug:4"))
+	g.Expect(sender.GetEvents()).To(ContainElement(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Data": And(
+			HaveKeyWithValue("name", "rate-limit-summary"),
+			HaveKeyWithValue("rate_limit.key", "key"),
+			HaveKeyWithValue("rate_limit.policy", "sample")),
+	}))))
+
+	logm.MustGet(spanCtx).EmitDebug("d8", logm.EmitRateLimitKey("key"))
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("d0", "d1", "d4", "d7", "other", "summary:debug:4", "d8"))
+}
+
+func (s *RateLimitSuite) TestDisabled(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g, logm.RawLogRateLimit(logm.RateLimitPolicySample, 0, 1, 0))
+	defer closer()
+
+	for i := 0; i < 3; i++ {
+		logm.MustGet(ctx).EmitInfo("i")
+	}
+
+	g.Expect(s.getMessages(sender)).To(HaveExactElements("i", "i", "i"))
+}
+
+func (s *RateLimitSuite) TestRateLimitPolicy(g *WithT) {
+	p := logm.RateLimitPolicy("")
+	g.Expect(p.UnmarshalText([]byte(""))).To(Succeed())
+	g.Expect(p.String()).To(Equal("token-bucket"))
+	g.Expect(p.UnmarshalText([]byte("sample"))).To(Succeed())
+	g.Expect(p).To(Equal(logm.RateLimitPolicySample))
+	g.Expect(p.UnmarshalText([]byte("invalid"))).To(MatchError("invalid value for RateLimitPolicy: 'invalid'"))
+}
@@ -0,0 +1,144 @@
+package logm
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ibrt/golang-utils/errorz"
+)
+
+const (
+	// DefaultErrorReferencesMaxSize is the default number of recent error references that can be resolved in-process.
+	DefaultErrorReferencesMaxSize = 1000
+
+	errorReferenceAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ" // Crockford's base32
+	errorReferenceLen      = 10
+)
+
+var (
+	errorReferenceRegexp = regexp.MustCompile(`^E-[0-9A-HJKMNP-TV-Z]{5}-[0-9A-HJKMNP-TV-Z]{5}$`)
+)
+
+// NewErrorReference generates a new random error reference, e.g. "E-7K3MQ-9QX2B". References are short enough to be
+// read over the phone, and avoid ambiguous characters.
+func NewErrorReference() string {
+	buf := make([]byte, errorReferenceLen)
+	_, err := rand.Read(buf)
+	errorz.MaybeMustWrap(err)
+
+	ref := &strings.Builder{}
+	ref.WriteString("E-")
+
+	for i, b := range buf {
+		if i == errorReferenceLen/2 {
+			ref.WriteString("-")
+		}
+		ref.WriteByte(errorReferenceAlphabet[int(b)%len(errorReferenceAlphabet)])
+	}
+
+	return ref.String()
+}
+
+// IsValidErrorReference returns true if the given string is a well-formed error reference.
+func IsValidErrorReference(ref string) bool {
+	return errorReferenceRegexp.MatchString(ref)
+}
+
+// GetErrorReference returns the reference assigned to the given error when it was emitted, or an empty string. It is
+// meant to be included in error responses, so that it can be looked up using the "error.reference" field or
+// [ResolveErrorReference].
+func GetErrorReference(err error) string {
+	ref, _ := errorz.MaybeGetMetadata[string](err, errorReferenceErrorMetadataKey)
+	return ref
+}
+
+// maybeSetErrorReference assigns a reference to the given error, unless it already has one, and returns it.
+func maybeSetErrorReference(err error) string {
+	if ref := GetErrorReference(err); ref != "" {
+		return ref
+	}
+
+	ref := NewErrorReference()
+	errorz.MaybeSetMetadata(err, errorReferenceErrorMetadataKey, ref)
+	return ref
+}
+
+// ResolveErrorReference returns the [*TraceLink] of the span in which the error with the given reference was emitted.
+// Only recent references emitted in the current process are known, see [RawLogErrorReferences]. Errors emitted in the
+// background log are not part of any trace: their references are known but resolve to a nil link.
+func ResolveErrorReference(ctx context.Context, ref string) (*TraceLink, bool) {
+	if bL, ok := getRootRawLog(ctx).(*backgroundLogImpl); ok {
+		return bL.refs.resolve(ref)
+	}
+
+	return nil, false
+}
+
+type errorReferenceEntry struct {
+	ref       string
+	traceLink *TraceLink
+}
+
+// errorReferences remembers the trace links of the most recent error references.
+type errorReferences struct {
+	maxSize int
+	m       *sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newErrorReferences(maxSize int) *errorReferences {
+	if maxSize <= 0 {
+		return nil
+	}
+
+	return &errorReferences{
+		maxSize: maxSize,
+		m:       &sync.Mutex{},
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// add records the trace link of a reference. It does nothing on a nil instance.
+func (r *errorReferences) add(ref string, traceLink *TraceLink) {
+	if r == nil || ref == "" {
+		return
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if elem, ok := r.entries[ref]; ok {
+		r.order.MoveToBack(elem)
+		return
+	}
+
+	r.entries[ref] = r.order.PushBack(&errorReferenceEntry{
+		ref:       ref,
+		traceLink: traceLink,
+	})
+
+	for r.order.Len() > r.maxSize {
+		delete(r.entries, r.order.Remove(r.order.Front()).(*errorReferenceEntry).ref)
+	}
+}
+
+func (r *errorReferences) resolve(ref string) (*TraceLink, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if elem, ok := r.entries[ref]; ok {
+		return elem.Value.(*errorReferenceEntry).traceLink, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,132 @@
+package logm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type ReferenceSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestReferenceSuite(t *testing.T) {
+	fixturez.RunSuite(t, &ReferenceSuite{})
+}
+
+func (s *ReferenceSuite) newContext(ctx context.Context, g *WithT, options ...logm.RawLogOption) (context.Context, *tlogm.MockSender, func()) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+
+	return logm.NewSingletonInjector(logm.NewRawLogFromClient(client, options...))(ctx), sender, client.Close
+}
+
+func (s *ReferenceSuite) TestNewErrorReference(g *WithT) {
+	ref := logm.NewErrorReference()
+	g.Expect(ref).To(MatchRegexp(`^E-[0-9A-Z]{5}-[0-9A-Z]{5}$`))
+	g.Expect(logm.IsValidErrorReference(ref)).To(BeTrue())
+	g.Expect(logm.NewErrorReference()).ToNot(Equal(ref))
+	g.Expect(logm.IsValidErrorReference("E-ILOU0-00000")).To(BeFalse())
+	g.Expect(logm.IsValidErrorReference("")).To(BeFalse())
+}
+
+func (s *ReferenceSuite) TestErrorReference_Span(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g)
+	defer closer()
+
+	err := errorz.Errorf("test error")
+	g.Expect(logm.GetErrorReference(err)).To(BeEmpty())
+
+	spanCtx, end := logm.MustGet(ctx).Begin("span")
+	logm.MustGet(spanCtx).EmitError(err)
+	end()
+
+	ref := logm.GetErrorReference(err)
+	g.Expect(logm.IsValidErrorReference(ref)).To(BeTrue())
+
+	g.Expect(sender.GetEvents()).To(ContainElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": And(
+				HaveKeyWithValue("name", "error"),
+				HaveKeyWithValue("error.reference", ref)),
+		})),
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": And(
+				HaveKeyWithValue("name", "span"),
+				HaveKeyWithValue("error", true),
+				HaveKeyWithValue("error.reference", ref)),
+		}))))
+
+	traceLink, ok := logm.ResolveErrorReference(ctx, ref)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(traceLink).To(Equal(logm.MustGet(spanCtx).GetCurrentTraceLink()))
+
+	spanCtx, end = logm.MustGet(ctx).Begin("other")
+	logm.MustGet(spanCtx).EmitError(err)
+	end()
+	g.Expect(logm.GetErrorReference(err)).To(Equal(ref))
+
+	_, ok = logm.ResolveErrorReference(ctx, logm.NewErrorReference())
+	g.Expect(ok).To(BeFalse())
+}
+
+func (s *ReferenceSuite) TestErrorReference_Background(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g)
+	defer closer()
+
+	err := errorz.Errorf("test error")
+	logm.MustGet(ctx).EmitError(err)
+	ref := logm.GetErrorReference(err)
+
+	g.Expect(sender.GetEvents()).To(HaveExactElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": HaveKeyWithValue("error.reference", ref),
+		}))))
+
+	traceLink, ok := logm.ResolveErrorReference(ctx, ref)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(traceLink).To(BeNil())
+}
+
+func (s *ReferenceSuite) TestErrorReference_Eviction(ctx context.Context, g *WithT) {
+	ctx, _, closer := s.newContext(ctx, g, logm.RawLogErrorReferences(2))
+	defer closer()
+
+	refs := make([]string, 0)
+
+	for i := 0; i < 3; i++ {
+		err := errorz.Errorf("test error")
+		logm.MustGet(ctx).EmitError(err)
+		refs = append(refs, logm.GetErrorReference(err))
+	}
+
+	_, ok := logm.ResolveErrorReference(ctx, refs[0])
+	g.Expect(ok).To(BeFalse())
+	_, ok = logm.ResolveErrorReference(ctx, refs[2])
+	g.Expect(ok).To(BeTrue())
+
+	ctx, _, closer = s.newContext(ctx, g, logm.RawLogErrorReferences(0))
+	defer closer()
+
+	err := errorz.Errorf("test error")
+	logm.MustGet(ctx).EmitError(err)
+	g.Expect(logm.GetErrorReference(err)).ToNot(BeEmpty())
+	_, ok = logm.ResolveErrorReference(ctx, logm.GetErrorReference(err))
+	g.Expect(ok).To(BeFalse())
+}
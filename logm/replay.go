@@ -0,0 +1,347 @@
+package logm
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/memz"
+)
+
+const (
+	capturedEventMaxLineBytes = 16 * 1024 * 1024
+)
+
+var (
+	logrusReservedKeys = []string{
+		"level",
+		"msg",
+		"time",
+	}
+)
+
+// CapturedTrace describes a trace rebuilt from captured events, see [NewCapturedTraces].
+type CapturedTrace struct {
+	*ViewedTrace
+	// Spans are sorted in depth-first order.
+	Spans  []*CapturedSpan
+	Events []*transmission.Event
+}
+
+// CapturedSpan describes a span in a [*CapturedTrace].
+type CapturedSpan struct {
+	Name       string
+	SpanID     string
+	ParentID   string
+	Timestamp  time.Time
+	DurationMS float64
+	HasError   bool
+	User       string
+	UserEmail  string
+	Event      *transmission.Event
+}
+
+// TraceQuery describes criteria for selecting captured traces, see [QueryTraces]. Zero values match everything.
+type TraceQuery struct {
+	TraceID string
+
+	// The remaining criteria must all be matched by the same span.
+	SpanName    string
+	User        string
+	HasError    bool
+	MinDuration time.Duration
+}
+
+// SpanLatency describes latency percentiles (in milliseconds) for spans with the same name, see [GetSpanLatencies].
+type SpanLatency struct {
+	Name  string
+	Count int
+	P50   float64
+	P90   float64
+	P99   float64
+	Max   float64
+}
+
+// ReadCapturedEvents reads events captured as JSON lines, either by the JSON logrus output (see
+// [LogConfigLogrusOutputJSON]) or by a [*FileSender] (or [transmission.WriterSender]). Blank and non-JSON lines (e.g.
+// unrelated output interleaved on stdout) are skipped.
+func ReadCapturedEvents(r io.Reader) ([]*transmission.Event, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), capturedEventMaxLineBytes)
+	events := make([]*transmission.Event, 0)
+
+	for line := 1; scanner.Scan(); line++ {
+		buf := scanner.Bytes()
+
+		if len(buf) == 0 || buf[0] != '{' {
+			continue
+		}
+
+		e, err := parseCapturedEvent(buf)
+		if err != nil {
+			return nil, errorz.Wrap(err, errorz.Errorf("captured events line %v", line))
+		}
+
+		events = append(events, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	return events, nil
+}
+
+func parseCapturedEvent(buf []byte) (*transmission.Event, error) {
+	fileLine := &fileSenderLine{}
+
+	if err := json.Unmarshal(buf, fileLine); err == nil && fileLine.Data != nil {
+		e := &transmission.Event{
+			Data:       fileLine.Data,
+			SampleRate: fileLine.SampleRate,
+			Dataset:    fileLine.Dataset,
+		}
+
+		if fileLine.Timestamp != nil {
+			e.Timestamp = *fileLine.Timestamp
+		}
+
+		return e, nil
+	}
+
+	data := make(map[string]any)
+	if err := json.Unmarshal(buf, &data); err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	e := &transmission.Event{
+		Data: make(map[string]any, len(data)),
+	}
+
+	if ts, ok := data["time"].(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, errorz.Wrap(err)
+		}
+		e.Timestamp = t
+	}
+
+	for k, v := range data {
+		if slices.Contains(logrusReservedKeys, k) {
+			continue
+		}
+
+		// logrus prefixes fields that clash with its reserved keys.
+		if kk := strings.TrimPrefix(k, "fields."); kk != k && slices.Contains(logrusReservedKeys, kk) {
+			k = kk
+		}
+
+		e.Data[k] = v
+	}
+
+	return e, nil
+}
+
+// NewCapturedTraces groups the given events by trace, and rebuilds the corresponding span trees. Events that are not
+// part of a trace are ignored. Traces are sorted by start time.
+func NewCapturedTraces(events []*transmission.Event) []*CapturedTrace {
+	traceIDs := make([]string, 0)
+	byTraceID := make(map[string][]*transmission.Event)
+
+	for _, e := range events {
+		if traceID, ok := e.Data["trace.trace_id"].(string); ok && traceID != "" {
+			if _, ok := byTraceID[traceID]; !ok {
+				traceIDs = append(traceIDs, traceID)
+			}
+			byTraceID[traceID] = append(byTraceID[traceID], e)
+		}
+	}
+
+	traces := make([]*CapturedTrace, 0, len(traceIDs))
+
+	for _, traceID := range traceIDs {
+		traces = append(traces, newCapturedTrace(traceID, byTraceID[traceID]))
+	}
+
+	slices.SortStableFunc(traces, func(a, b *CapturedTrace) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	return traces
+}
+
+func newCapturedTrace(traceID string, events []*transmission.Event) *CapturedTrace {
+	viewerEvents := make([]*traceViewerEvent, 0, len(events))
+	spans := make([]*CapturedSpan, 0)
+	hasRoot := false
+
+	for _, e := range events {
+		vE := &traceViewerEvent{
+			timestamp: e.Timestamp,
+			data:      e.Data,
+		}
+
+		viewerEvents = append(viewerEvents, vE)
+		hasRoot = hasRoot || vE.isRootSpan()
+
+		if vE.isSpan() {
+			spans = append(spans, &CapturedSpan{
+				Name:       vE.getString("name"),
+				SpanID:     vE.getString("trace.span_id"),
+				ParentID:   vE.getString("trace.parent_id"),
+				Timestamp:  e.Timestamp,
+				DurationMS: vE.getDurationMS(),
+				HasError:   vE.hasError(),
+				User:       vE.getString("scope.user"),
+				UserEmail:  vE.getString("scope.user.email"),
+				Event:      e,
+			})
+		}
+	}
+
+	return &CapturedTrace{
+		ViewedTrace: newViewedTrace(traceID, viewerEvents, !hasRoot),
+		Spans:       sortCapturedSpans(spans),
+		Events:      events,
+	}
+}
+
+// sortCapturedSpans sorts spans in depth-first order, siblings by start time.
+func sortCapturedSpans(spans []*CapturedSpan) []*CapturedSpan {
+	slices.SortStableFunc(spans, func(a, b *CapturedSpan) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	spanIDs := make(map[string]bool, len(spans))
+	children := make(map[string][]*CapturedSpan, len(spans))
+
+	for _, s := range spans {
+		spanIDs[s.SpanID] = true
+		children[s.ParentID] = append(children[s.ParentID], s)
+	}
+
+	sorted := make([]*CapturedSpan, 0, len(spans))
+
+	var visit func(s *CapturedSpan)
+	visit = func(s *CapturedSpan) {
+		sorted = append(sorted, s)
+
+		for _, c := range children[s.SpanID] {
+			visit(c)
+		}
+	}
+
+	for _, s := range spans {
+		if !spanIDs[s.ParentID] {
+			visit(s)
+		}
+	}
+
+	return sorted
+}
+
+// Match returns true if the given trace matches the query.
+func (q *TraceQuery) Match(t *CapturedTrace) bool {
+	if q.TraceID != "" && q.TraceID != t.TraceID {
+		return false
+	}
+
+	if q.SpanName == "" && q.User == "" && !q.HasError && q.MinDuration <= 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(t.Spans, q.matchSpan)
+}
+
+func (q *TraceQuery) matchSpan(s *CapturedSpan) bool {
+	if q.SpanName != "" && q.SpanName != s.Name {
+		return false
+	}
+
+	if q.User != "" && q.User != s.User && q.User != s.UserEmail {
+		return false
+	}
+
+	if q.HasError && !s.HasError {
+		return false
+	}
+
+	if q.MinDuration > 0 && s.DurationMS < float64(q.MinDuration)/float64(time.Millisecond) {
+		return false
+	}
+
+	return true
+}
+
+// QueryTraces returns the traces matching the given query.
+func QueryTraces(traces []*CapturedTrace, q *TraceQuery) []*CapturedTrace {
+	return slices.DeleteFunc(slices.Clone(traces), func(t *CapturedTrace) bool {
+		return !q.Match(t)
+	})
+}
+
+// GetSpanLatencies aggregates latency percentiles by span name, sorted by name.
+func GetSpanLatencies(traces []*CapturedTrace) []*SpanLatency {
+	durations := make(map[string][]float64)
+
+	for _, t := range traces {
+		for _, s := range t.Spans {
+			durations[s.Name] = append(durations[s.Name], s.DurationMS)
+		}
+	}
+
+	latencies := make([]*SpanLatency, 0, len(durations))
+
+	for name, d := range durations {
+		slices.Sort(d)
+
+		latencies = append(latencies, &SpanLatency{
+			Name:  name,
+			Count: len(d),
+			P50:   getPercentile(d, 0.5),
+			P90:   getPercentile(d, 0.9),
+			P99:   getPercentile(d, 0.99),
+			Max:   d[len(d)-1],
+		})
+	}
+
+	slices.SortFunc(latencies, func(a, b *SpanLatency) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return latencies
+}
+
+// getPercentile returns the given percentile of the sorted values, using the nearest-rank method.
+func getPercentile(sorted []float64, p float64) float64 {
+	return sorted[max(int(math.Ceil(p*float64(len(sorted))))-1, 0)]
+}
+
+// ReplayTraces re-sends all events of the given traces to the given (started) [transmission.Sender], preserving their
+// timestamps, sample rates and datasets. The API key, API host, dataset and sample rate of the given defaults are used
+// for events captured without them (e.g. by the logrus output). Defaults may be nil.
+func ReplayTraces(sender transmission.Sender, defaults *transmission.Event, traces []*CapturedTrace) {
+	if defaults == nil {
+		defaults = &transmission.Event{}
+	}
+
+	for _, t := range traces {
+		for _, e := range t.Events {
+			sender.Add(&transmission.Event{
+				APIKey:     memz.Ternary(e.APIKey != "", e.APIKey, defaults.APIKey),
+				Dataset:    memz.Ternary(e.Dataset != "", e.Dataset, defaults.Dataset),
+				SampleRate: memz.Ternary(e.SampleRate > 0, e.SampleRate, max(defaults.SampleRate, 1)),
+				APIHost:    memz.Ternary(e.APIHost != "", e.APIHost, defaults.APIHost),
+				Timestamp:  e.Timestamp,
+				Data:       maps.Clone(e.Data),
+			})
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package logm_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type ReplaySuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestReplaySuite(t *testing.T) {
+	fixturez.RunSuite(t, &ReplaySuite{})
+}
+
+func (s *ReplaySuite) capture(ctx context.Context, g *WithT, sender transmission.Sender) {
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	ctx = logm.NewSingletonInjector(logm.NewRawLogFromClient(client))(ctx)
+
+	for i, d := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		func() {
+			ctx, end := logm.MustGet(ctx).Begin("root")
+			defer end()
+
+			if i == 1 {
+				logm.MustGet(ctx).SetUser(&logm.User{ID: "u1", Email: "u1@example.com"})
+			}
+
+			func() {
+				ctx, end := logm.MustGet(ctx).Begin("child")
+				defer end()
+
+				logm.MustGet(ctx).EmitInfo("hello")
+				s.CLK.GetMock().Add(d)
+
+				if i == 2 {
+					logm.MustGet(ctx).EmitError(errorz.Errorf("test error"))
+				}
+			}()
+		}()
+	}
+
+	logm.MustGet(ctx).EmitInfo("background")
+}
+
+func (s *ReplaySuite) TestReplay_Logrus(ctx context.Context, g *WithT) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetLevel(logrus.DebugLevel)
+	logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	s.capture(ctx, g, logm.NewSink(logger, nil))
+
+	events, err := logm.ReadCapturedEvents(strings.NewReader("not json\n\n" + buf.String()))
+	g.Expect(err).To(Succeed())
+	g.Expect(events).To(HaveLen(11))
+	g.Expect(events[0].Data).ToNot(HaveKey("msg"))
+	g.Expect(events[0].Data).ToNot(HaveKey("level"))
+	traces := s.checkTraces(g, events)
+
+	sender := tlogm.NewMockSender()
+	logm.ReplayTraces(sender, &transmission.Event{Dataset: "other-dataset", SampleRate: 2}, traces[:1])
+	g.Expect(sender.GetEvents()).To(HaveLen(3))
+	g.Expect(sender.GetEvents()).To(HaveEach(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Dataset":    Equal("other-dataset"),
+		"SampleRate": Equal(uint(2)),
+	}))))
+
+	_, err = logm.ReadCapturedEvents(strings.NewReader(`{"time":"invalid"}`))
+	g.Expect(err).To(MatchError(ContainSubstring("captured events line 1")))
+}
+
+func (s *ReplaySuite) TestReplay_File(ctx context.Context, g *WithT) {
+	buf := &bytes.Buffer{}
+	s.capture(ctx, g, &transmission.WriterSender{W: buf})
+
+	events, err := logm.ReadCapturedEvents(buf)
+	g.Expect(err).To(Succeed())
+	g.Expect(events).To(HaveLen(11))
+	g.Expect(events[0].Dataset).To(Equal("test-dataset"))
+	traces := s.checkTraces(g, events)
+
+	sender := tlogm.NewMockSender()
+	logm.ReplayTraces(sender, &transmission.Event{APIKey: "test-honeycomb-api-key", Dataset: "other-dataset"}, traces[2:])
+	g.Expect(sender.GetEvents()).To(HaveLen(4))
+	g.Expect(sender.GetEvents()).To(HaveEach(PointTo(MatchFields(IgnoreExtras, Fields{
+		"APIKey":     Equal("test-honeycomb-api-key"),
+		"Dataset":    Equal("test-dataset"),
+		"SampleRate": Equal(uint(1)),
+		"Data":       HaveKeyWithValue("trace.trace_id", traces[2].TraceID),
+	}))))
+	g.Expect(sender.GetTree()).To(tlogm.HaveSpan("root", tlogm.HaveChild("child", tlogm.HaveError("generic"))))
+}
+
+func (s *ReplaySuite) checkTraces(g *WithT, events []*transmission.Event) []*logm.CapturedTrace {
+	traces := logm.NewCapturedTraces(events)
+	g.Expect(traces).To(HaveLen(3))
+	g.Expect(traces[0].Spans).To(HaveExactElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Name":       Equal("root"),
+			"ParentID":   BeEmpty(),
+			"DurationMS": Equal(float64(1000)),
+		})),
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Name":       Equal("child"),
+			"ParentID":   Equal(traces[0].Spans[0].SpanID),
+			"DurationMS": Equal(float64(1000)),
+		}))))
+
+	g.Expect(traces[2].Tree).To(Equal("" +
+		"[+0.00ms] root (4000.00ms)\n" +
+		"└── [+0.00ms] child (4000.00ms) [error]\n" +
+		"    ├── [+0.00ms] info: hello\n" +
+		"    └── [+4000.00ms] error: generic: test error"))
+
+	g.Expect(logm.QueryTraces(traces, &logm.TraceQuery{})).To(HaveLen(3))
+	g.Expect(logm.QueryTraces(traces, &logm.TraceQuery{TraceID: traces[1].TraceID})).To(HaveExactElements(traces[1]))
+	g.Expect(logm.QueryTraces(traces, &logm.TraceQuery{User: "u1@example.com"})).To(HaveExactElements(traces[1]))
+	g.Expect(logm.QueryTraces(traces, &logm.TraceQuery{HasError: true})).To(HaveExactElements(traces[2]))
+	g.Expect(logm.QueryTraces(traces, &logm.TraceQuery{MinDuration: 2 * time.Second})).To(HaveExactElements(traces[1], traces[2]))
+	g.Expect(logm.QueryTraces(traces, &logm.TraceQuery{SpanName: "child", MinDuration: 3 * time.Second})).To(HaveExactElements(traces[2]))
+	g.Expect(logm.QueryTraces(traces, &logm.TraceQuery{SpanName: "child", User: "u1", HasError: true})).To(BeEmpty())
+
+	g.Expect(logm.GetSpanLatencies(traces)).To(HaveExactElements(
+		&logm.SpanLatency{Name: "child", Count: 3, P50: 2000, P90: 4000, P99: 4000, Max: 4000},
+		&logm.SpanLatency{Name: "root", Count: 3, P50: 2000, P90: 4000, P99: 4000, Max: 4000}))
+
+	return traces
+}
@@ -0,0 +1,166 @@
+package logm
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/ibrt/golang-modules/cfgm"
+)
+
+var (
+	cgroupContainerIDRegexp    = regexp.MustCompile(`(?:^|[/\-:.])([\da-f]{64})(?:$|[/\-.])`)
+	mountinfoContainerIDRegexp = regexp.MustCompile(`/containers/([\da-f]{64})/(?:hostname|hosts|resolv\.conf)$`)
+)
+
+// Resource describes the process emitting events. Its fields are attached to all events sent by the client configured
+// by [NewInitializer].
+type Resource struct {
+	ServiceName        string
+	ServiceEnvironment string
+	ServiceVersion     string
+	Hostname           string
+	PID                int
+	GoVersion          string
+	BuildPath          string
+	BuildVersion       string
+	VCSRevision        string
+	VCSTime            string
+	VCSModified        bool
+	ContainerID        string
+}
+
+// DetectResource detects the [*Resource] describing the current process. The service name and version default to the
+// main module path and version (or VCS revision) from the build info, the environment is left empty.
+func DetectResource() *Resource {
+	r := &Resource{
+		PID:       os.Getpid(),
+		GoVersion: runtime.Version(),
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		r.Hostname = hostname
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		r.BuildPath = buildInfo.Main.Path
+		r.BuildVersion = buildInfo.Main.Version
+
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				r.VCSRevision = setting.Value
+			case "vcs.time":
+				r.VCSTime = setting.Value
+			case "vcs.modified":
+				r.VCSModified = setting.Value == "true"
+			}
+		}
+	}
+
+	if r.ContainerID = maybeReadContainerID("/proc/self/cgroup", parseCgroupContainerID); r.ContainerID == "" {
+		// on cgroup v2 the cgroup path is usually "/", fall back to the files bind-mounted by the container runtime
+		r.ContainerID = maybeReadContainerID("/proc/self/mountinfo", parseMountinfoContainerID)
+	}
+
+	r.ServiceName = r.BuildPath
+
+	switch {
+	case r.BuildVersion != "" && r.BuildVersion != "(devel)":
+		r.ServiceVersion = r.BuildVersion
+	default:
+		r.ServiceVersion = r.VCSRevision
+	}
+
+	return r
+}
+
+// NewDefaultResource detects the [*Resource] describing the current process, and applies the overrides from the
+// [LogConfigMixin] from context.
+func NewDefaultResource(ctx context.Context) *Resource {
+	logCfg := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig()
+	r := DetectResource()
+
+	if logCfg.ServiceName != "" {
+		r.ServiceName = logCfg.ServiceName
+	}
+
+	if logCfg.ServiceEnvironment != "" {
+		r.ServiceEnvironment = logCfg.ServiceEnvironment
+	}
+
+	if logCfg.ServiceVersion != "" {
+		r.ServiceVersion = logCfg.ServiceVersion
+	}
+
+	return r
+}
+
+// AddFields adds the resource fields, using OpenTelemetry semantic conventions where possible.
+func (r *Resource) AddFields(af AddField) {
+	maybeAddLenField(af, "", "service.name", r.ServiceName)
+	maybeAddLenField(af, "", "deployment.environment", r.ServiceEnvironment)
+	maybeAddLenField(af, "", "service.version", r.ServiceVersion)
+	maybeAddLenField(af, "", "host.name", r.Hostname)
+	maybeAddNumericField(af, "process.pid", r.PID)
+	maybeAddLenField(af, "", "process.runtime.version", r.GoVersion)
+	maybeAddLenField(af, "", "build.path", r.BuildPath)
+	maybeAddLenField(af, "", "build.version", r.BuildVersion)
+	maybeAddLenField(af, "", "build.vcs.revision", r.VCSRevision)
+	maybeAddLenField(af, "", "build.vcs.time", r.VCSTime)
+	maybeAddLenField(af, "", "container.id", r.ContainerID)
+
+	if r.VCSModified {
+		af.AddField("build.vcs.modified", true)
+	}
+}
+
+func maybeReadContainerID(path string, parse func(r io.Reader) string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return parse(f)
+}
+
+// parseCgroupContainerID finds a container ID in the contents of a cgroup file. Container runtimes use 64 hex
+// characters IDs, e.g. "/docker/<id>" or "/kubepods/.../cri-containerd-<id>.scope".
+func parseCgroupContainerID(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		for _, part := range strings.Fields(scanner.Text()) {
+			if matches := cgroupContainerIDRegexp.FindStringSubmatch(part); len(matches) == 2 {
+				return matches[1]
+			}
+		}
+	}
+
+	return ""
+}
+
+// parseMountinfoContainerID finds a container ID in the contents of a mountinfo file. Only the root of the files
+// bind-mounted by the container runtime is considered (e.g. "/var/lib/docker/containers/<id>/hostname"), as other
+// entries contain unrelated 64 hex characters IDs, e.g. the overlay2 layer of the root filesystem.
+func parseMountinfoContainerID(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 3 {
+			if matches := mountinfoContainerIDRegexp.FindStringSubmatch(fields[3]); len(matches) == 2 {
+				return matches[1]
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,55 @@
+package logm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+)
+
+type ResourceInternalSuite struct {
+	// intentionally empty
+}
+
+func TestResourceInternalSuite(t *testing.T) {
+	fixturez.RunSuite(t, &ResourceInternalSuite{})
+}
+
+// testCgroupV2Mountinfo is the /proc/self/mountinfo of a Docker container on a cgroup v2 host, where /proc/self/cgroup
+// only contains "0::/". The overlay2 layer ID in the root mount must not be mistaken for the container ID.
+const testCgroupV2Mountinfo = `1195 1052 0:138 / / rw,relatime master:466 - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/TAP7RJ3OWIYLOQXKAGZSMB3FMR:/var/lib/docker/overlay2/l/EP5QPWGXQ4XIEXRJFJNZTNLCVN,upperdir=/var/lib/docker/overlay2/8a1fbe5d4dba39b16a3d1ea7d4e1db6a5a2bf19ac3f6dc1a4f40c0a47c5bd0e9/diff,workdir=/var/lib/docker/overlay2/8a1fbe5d4dba39b16a3d1ea7d4e1db6a5a2bf19ac3f6dc1a4f40c0a47c5bd0e9/work
+1196 1195 0:141 / /proc rw,nosuid,nodev,noexec,relatime - proc proc rw
+1197 1195 0:142 / /dev rw,nosuid - tmpfs tmpfs rw,size=65536k,mode=755
+1198 1197 0:143 / /dev/pts rw,nosuid,noexec,relatime - devpts devpts rw,gid=5,mode=620,ptmxmode=666
+1199 1195 0:144 / /sys ro,nosuid,nodev,noexec,relatime - sysfs sysfs ro
+1200 1199 0:30 / /sys/fs/cgroup ro,nosuid,nodev,noexec,relatime - cgroup2 cgroup rw,nsdelegate,memory_recursiveprot
+1201 1197 0:140 / /dev/mqueue rw,nosuid,nodev,noexec,relatime - mqueue mqueue rw
+1202 1197 0:145 / /dev/shm rw,nosuid,nodev,noexec,relatime - tmpfs shm rw,size=65536k
+1203 1195 254:1 /docker/containers/3f4c5a7e9b2d1c0e8f6a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e/resolv.conf /etc/resolv.conf rw,relatime - ext4 /dev/vda1 rw,discard
+1204 1195 254:1 /docker/containers/3f4c5a7e9b2d1c0e8f6a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e/hostname /etc/hostname rw,relatime - ext4 /dev/vda1 rw,discard
+1205 1195 254:1 /docker/containers/3f4c5a7e9b2d1c0e8f6a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e/hosts /etc/hosts rw,relatime - ext4 /dev/vda1 rw,discard
+1053 1196 0:141 /bus /proc/bus ro,nosuid,nodev,noexec,relatime - proc proc rw
+1054 1197 0:146 / /proc/acpi ro,relatime - tmpfs tmpfs ro
+`
+
+func (*ResourceInternalSuite) TestParseCgroupContainerID(g *WithT) {
+	id := strings.Repeat("0123456789abcdef", 4)
+
+	g.Expect(parseCgroupContainerID(strings.NewReader("0::/\n"))).To(BeEmpty())
+	g.Expect(parseCgroupContainerID(strings.NewReader("12:pids:/docker/" + id + "\n"))).To(Equal(id))
+	g.Expect(parseCgroupContainerID(strings.NewReader("0::/kubepods.slice/kubepods-pod1.slice/cri-containerd-" + id + ".scope\n"))).To(Equal(id))
+	g.Expect(parseCgroupContainerID(strings.NewReader("0::/" + id + "0\n"))).To(BeEmpty())
+	g.Expect(maybeReadContainerID("/does/not/exist", parseCgroupContainerID)).To(BeEmpty())
+}
+
+func (*ResourceInternalSuite) TestParseMountinfoContainerID(g *WithT) {
+	id := strings.Repeat("0123456789abcdef", 4)
+
+	g.Expect(parseMountinfoContainerID(strings.NewReader(testCgroupV2Mountinfo))).
+		To(Equal("3f4c5a7e9b2d1c0e8f6a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e"))
+	g.Expect(parseMountinfoContainerID(strings.NewReader("1 2 0:3 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw - ext4 /dev/sda1 rw\n"))).To(Equal(id))
+	g.Expect(parseMountinfoContainerID(strings.NewReader("1 2 0:3 /var/lib/docker/overlay2/" + id + "/diff / rw - overlay overlay rw\n"))).To(BeEmpty())
+	g.Expect(parseMountinfoContainerID(strings.NewReader("1 2 0:3 / /\n"))).To(BeEmpty())
+	g.Expect(maybeReadContainerID("/does/not/exist", parseMountinfoContainerID)).To(BeEmpty())
+}
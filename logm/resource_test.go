@@ -0,0 +1,145 @@
+package logm_test
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/cfgm"
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type ResourceSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestResourceSuite(t *testing.T) {
+	fixturez.RunSuite(t, &ResourceSuite{})
+}
+
+func (s *ResourceSuite) TestDetectResource(g *WithT) {
+	r := logm.DetectResource()
+	g.Expect(r.PID).To(Equal(os.Getpid()))
+	g.Expect(r.GoVersion).To(Equal(runtime.Version()))
+	g.Expect(r.Hostname).ToNot(BeEmpty())
+	g.Expect(r.ServiceName).To(Equal(r.BuildPath))
+	g.Expect(r.ServiceEnvironment).To(BeEmpty())
+}
+
+func (s *ResourceSuite) TestNewDefaultResource(ctx context.Context, g *WithT) {
+	{
+		r := logm.NewDefaultResource(cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{})(ctx))
+		g.Expect(r).To(Equal(logm.DetectResource()))
+	}
+	{
+		r := logm.NewDefaultResource(cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{
+			ServiceName:        "svc",
+			ServiceEnvironment: "production",
+			ServiceVersion:     "v1.2.3",
+		})(ctx))
+
+		g.Expect(r.ServiceName).To(Equal("svc"))
+		g.Expect(r.ServiceEnvironment).To(Equal("production"))
+		g.Expect(r.ServiceVersion).To(Equal("v1.2.3"))
+		g.Expect(r.PID).To(Equal(os.Getpid()))
+	}
+}
+
+func (s *ResourceSuite) TestAddFields(g *WithT) {
+	fields := map[string]any{}
+
+	(&logm.Resource{
+		ServiceName:        "svc",
+		ServiceEnvironment: "production",
+		ServiceVersion:     "v1.2.3",
+		Hostname:           "host",
+		PID:                10,
+		GoVersion:          "go1.23.0",
+		BuildPath:          "github.com/org/svc",
+		BuildVersion:       "(devel)",
+		VCSRevision:        "abc",
+		VCSTime:            "2024-01-01T00:00:00Z",
+		VCSModified:        true,
+		ContainerID:        "cid",
+	}).AddFields(mapAddField(fields))
+
+	g.Expect(fields).To(Equal(map[string]any{
+		"service.name":            "svc",
+		"deployment.environment":  "production",
+		"service.version":         "v1.2.3",
+		"host.name":               "host",
+		"process.pid":             10,
+		"process.runtime.version": "go1.23.0",
+		"build.path":              "github.com/org/svc",
+		"build.version":           "(devel)",
+		"build.vcs.revision":      "abc",
+		"build.vcs.time":          "2024-01-01T00:00:00Z",
+		"build.vcs.modified":      true,
+		"container.id":            "cid",
+	}))
+
+	fields = map[string]any{}
+	(&logm.Resource{}).AddFields(mapAddField(fields))
+	g.Expect(fields).To(BeEmpty())
+}
+
+func (s *ResourceSuite) TestRuntimeStats(ctx context.Context, g *WithT) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	ctx = logm.NewSingletonInjector(logm.NewRawLogFromClient(client))(ctx)
+
+	ms := &runtime.MemStats{}
+	runtime.ReadMemStats(ms)
+	startNumGC := ms.NumGC
+
+	stop := logm.StartRuntimeStats(ctx, time.Minute)
+	runtime.GC()
+
+	g.Eventually(func() []*transmission.Event {
+		s.CLK.GetMock().Add(time.Minute)
+		return sender.GetEvents()
+	}).Should(ContainElement(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Data": And(
+				HaveKeyWithValue("name", "runtime-stats"),
+				HaveKeyWithValue("runtime.goroutines", BeNumerically(">", 0)),
+				HaveKeyWithValue("runtime.heap.alloc_bytes", BeNumerically(">", 0)),
+				HaveKey("runtime.gc.pause_total_ms"),
+				HaveKey("runtime.gc.pause_max_ms")),
+		}))))
+
+	stop()
+
+	// The first event only counts the collections that happened since the start.
+	runtime.ReadMemStats(ms)
+	g.Expect(sender.GetEvents()[0].Data).To(And(
+		HaveKeyWithValue("runtime.gc.count", BeNumerically(">=", 1)),
+		HaveKeyWithValue("runtime.gc.count", BeNumerically("<=", ms.NumGC-startNumGC))))
+
+	g.Expect(func() { logm.StartRuntimeStats(ctx, 0) }).To(PanicWith(MatchError("runtime stats interval must be positive")))
+}
+
+type mapAddField map[string]any
+
+func (m mapAddField) AddField(k string, v any) {
+	m[k] = v
+}
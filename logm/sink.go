@@ -50,20 +50,92 @@ func MustNewDefaultLogrusLogger(ctx context.Context) *logrus.Logger {
 }
 
 // MustNewDefaultHoneycombSender initializes a default [transmission.Sender] using the [LogConfigMixin] from context.
+// If a buffer directory is configured, the sender is wrapped in a [*DiskBufferSender].
 func MustNewDefaultHoneycombSender(ctx context.Context) transmission.Sender {
-	if apiKey := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig().HoneycombAPIKey; apiKey != "" && apiKey != cfgm.DisabledValue {
-		return &transmission.Honeycomb{
+	logCfg := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig()
+
+	if apiKey := logCfg.HoneycombAPIKey; apiKey != "" && apiKey != cfgm.DisabledValue {
+		sender := &transmission.Honeycomb{
 			BatchTimeout:         libhoney.DefaultBatchTimeout,
 			BlockOnSend:          true,
 			MaxBatchSize:         libhoney.DefaultMaxBatchSize,
 			MaxConcurrentBatches: libhoney.DefaultMaxConcurrentBatches,
 			PendingWorkCapacity:  libhoney.DefaultPendingWorkCapacity,
 		}
+
+		if dir := logCfg.HoneycombBufferDir; dir != "" && dir != cfgm.DisabledValue {
+			return NewDiskBufferSender(ctx, sender, &DiskBufferConfig{
+				Dir:      dir,
+				MaxBytes: logCfg.HoneycombBufferMaxBytes,
+			})
+		}
+
+		return sender
 	}
 
 	return nil
 }
 
+// MustNewDefaultSink initializes a default [transmission.Sender] using the [LogConfigMixin] from context. It combines
+// [MustNewDefaultLogrusLogger], [MustNewDefaultHoneycombSender], [MustNewDefaultOTLPSender], [MustNewDefaultFileSender]
+// and [MustNewDefaultTraceViewer] as destinations of a [*FanOutSink], each with its configured route, and makes it
+// asynchronous if a sink queue size is configured.
+func MustNewDefaultSink(ctx context.Context) transmission.Sender {
+	logCfg := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig()
+	destinations := make([]*SinkDestination, 0)
+
+	if logger := MustNewDefaultLogrusLogger(ctx); logger != nil {
+		destinations = append(destinations, &SinkDestination{
+			Name:   "logrus",
+			Sender: NewSink(logger, nil),
+			Route:  logCfg.LogrusRoute.MustGetRoute(),
+		})
+	}
+
+	if sender := MustNewDefaultHoneycombSender(ctx); sender != nil {
+		destinations = append(destinations, &SinkDestination{
+			Name:   "honeycomb",
+			Sender: sender,
+			Route:  logCfg.HoneycombRoute.MustGetRoute(),
+		})
+	}
+
+	if otlpSender := MustNewDefaultOTLPSender(ctx); otlpSender != nil {
+		destinations = append(destinations, &SinkDestination{
+			Name:   "otlp",
+			Sender: otlpSender,
+			Route:  logCfg.OTLPRoute.MustGetRoute(),
+		})
+	}
+
+	if fileSender := MustNewDefaultFileSender(ctx); fileSender != nil {
+		destinations = append(destinations, &SinkDestination{
+			Name:   "file",
+			Sender: fileSender,
+			Route:  logCfg.FileRoute.MustGetRoute(),
+		})
+	}
+
+	if traceViewer := MustNewDefaultTraceViewer(ctx); traceViewer != nil {
+		destinations = append(destinations, &SinkDestination{
+			Name:   "trace-viewer",
+			Sender: traceViewer,
+			Route:  logCfg.TraceViewerRoute.MustGetRoute(),
+		})
+	}
+
+	sink := NewFanOutSink(destinations...)
+
+	if logCfg.SinkQueueSize > 0 {
+		return NewAsyncSink(ctx, sink, &AsyncSinkConfig{
+			QueueSize:      logCfg.SinkQueueSize,
+			OverflowPolicy: logCfg.SinkOverflowPolicy,
+		})
+	}
+
+	return sink
+}
+
 // Sink describes a sink.
 type Sink struct {
 	l *logrus.Logger
@@ -91,7 +163,7 @@ func (s *Sink) Add(e *transmission.Event) {
 		logrus.NewEntry(s.l).
 			WithTime(e.Timestamp).
 			WithFields(e.Data).
-			Log(s.getLevel(e.Data), s.getMessage(e.Data))
+			Log(getEventLevel(e.Data), getEventMessage(e.Data))
 	}
 
 	if s.s != nil {
@@ -99,7 +171,7 @@ func (s *Sink) Add(e *transmission.Event) {
 	}
 }
 
-func (s *Sink) getLevel(data map[string]any) logrus.Level {
+func getEventLevel(data map[string]any) logrus.Level {
 	if _, ok := data["debug"]; ok {
 		return logrus.DebugLevel
 	} else if _, ok := data["info"]; ok {
@@ -113,7 +185,7 @@ func (s *Sink) getLevel(data map[string]any) logrus.Level {
 	}
 }
 
-func (s *Sink) getMessage(data map[string]any) string {
+func getEventMessage(data map[string]any) string {
 	msg := ""
 
 	for _, k := range messageKeys {
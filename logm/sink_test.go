@@ -288,4 +288,14 @@ func (s *SinkSuite) TestMustNewDefaultHoneycombSender(ctx context.Context, g *Wi
 				PendingWorkCapacity:  libhoney.DefaultPendingWorkCapacity,
 			}))
 	}
+	{
+		sender := logm.MustNewDefaultHoneycombSender(
+			cfgm.NewSingletonInjector[logm.LogConfigMixin](&logm.LogConfig{
+				HoneycombAPIKey:    "test-honeycomb-api-key",
+				HoneycombBufferDir: "/tmp/test-buffer",
+			})(ctx))
+
+		_, ok := sender.(*logm.DiskBufferSender)
+		g.Expect(ok).To(BeTrue())
+	}
 }
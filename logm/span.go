@@ -3,13 +3,16 @@ package logm
 import (
 	"context"
 	"fmt"
+	"maps"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/honeycombio/libhoney-go"
 	"github.com/ibrt/golang-utils/errorz"
 	"github.com/ibrt/golang-utils/idz"
+	"github.com/ibrt/golang-utils/memz"
 
 	"github.com/ibrt/golang-modules/clkm"
 )
@@ -19,16 +22,28 @@ var (
 )
 
 type spanLogImpl struct {
-	m            *sync.Mutex
-	b            *libhoney.Builder
-	startTime    time.Time
-	name         string
-	traceID      string
-	parentID     string
-	spanID       string
-	metadata     map[string]any
-	errMetadata  map[string]any
-	hasErrorFlag bool
+	m                 *sync.Mutex
+	b                 *libhoney.Builder
+	startTime         time.Time
+	name              string
+	traceID           string
+	parentID          string
+	spanID            string
+	metadata          map[string]any
+	errMetadata       map[string]any
+	attributes        map[string]any
+	baggage           Baggage
+	kind              SpanKind
+	status            SpanStatus
+	statusMessage     string
+	hasErrorFlag      bool
+	errorRef          string
+	isEnded           bool
+	budget            time.Duration
+	watchdog          *clock.Timer
+	hasDeadline       bool
+	deadlineRemaining time.Duration
+	bL                *backgroundLogImpl
 }
 
 // EmitDebug implements the RawLog interface.
@@ -37,8 +52,13 @@ func (sL *spanLogImpl) EmitDebug(ctx context.Context, format string, options ...
 	defer sL.m.Unlock()
 
 	o := newEmitOptions(options...)
+
+	if !sL.bL.limiter.allow(ctx, "debug", o) {
+		return
+	}
+
 	e := newAttachableEvent(ctx, sL.b, sL.spanID, "debug")
-	addDebugFields(e, format, o)
+	addDebugFields(e, sL.bL.o.payloadLimits, format, o)
 	errorz.MaybeMustWrap(e.Send())
 }
 
@@ -48,8 +68,13 @@ func (sL *spanLogImpl) EmitInfo(ctx context.Context, format string, options ...E
 	defer sL.m.Unlock()
 
 	o := newEmitOptions(options...)
+
+	if !sL.bL.limiter.allow(ctx, "info", o) {
+		return
+	}
+
 	e := newAttachableEvent(ctx, sL.b, sL.spanID, "info")
-	addInfoFields(e, format, o)
+	addInfoFields(e, sL.bL.o.payloadLimits, format, o)
 	errorz.MaybeMustWrap(e.Send())
 }
 
@@ -60,7 +85,7 @@ func (sL *spanLogImpl) EmitWarning(ctx context.Context, err error) {
 
 	maybeSetIsEmitted(err)
 	e := newAttachableEvent(ctx, sL.b, sL.spanID, "warning")
-	addWarningFields(e, err)
+	addWarningFields(e, sL.bL.o.payloadLimits, err)
 	errorz.MaybeMustWrap(e.Send())
 }
 
@@ -71,8 +96,16 @@ func (sL *spanLogImpl) EmitError(ctx context.Context, err error) {
 
 	maybeSetIsEmitted(err)
 	sL.hasErrorFlag = true
+	sL.errorRef = maybeSetErrorReference(err)
+	sL.bL.refs.add(sL.errorRef, &TraceLink{TraceID: sL.traceID, SpanID: sL.spanID})
+
+	if !sL.bL.errAgg.allow(ctx, err) {
+		return
+	}
+
 	e := newAttachableEvent(ctx, sL.b, sL.spanID, "error")
-	addErrorFields(e, err)
+	addErrorFields(e, sL.bL.o.payloadLimits, err)
+	e.AddField("error.reference", sL.errorRef)
 	errorz.MaybeMustWrap(e.Send())
 }
 
@@ -87,13 +120,27 @@ func (sL *spanLogImpl) EmitTraceLink(ctx context.Context, traceLink *TraceLink)
 	}
 }
 
+// EmitAudit implements the RawLog interface. The actor defaults to the span user, and the record links to the span.
+func (sL *spanLogImpl) EmitAudit(ctx context.Context, event *AuditEvent) {
+	sL.m.Lock()
+	user := getBaggageUser(sL.baggage)
+	traceLink := &TraceLink{TraceID: sL.traceID, SpanID: sL.spanID}
+	sL.m.Unlock()
+
+	sL.bL.appendAudit(ctx, sL, event, user, traceLink)
+}
+
 // Begin implements the RawLog interface.
 func (sL *spanLogImpl) Begin(ctx context.Context, name string, options ...BeginOption) (context.Context, func()) {
+	o := newBeginOptions(options...)
+
+	if o.isRoot {
+		return sL.bL.Begin(ctx, name, options...)
+	}
+
 	sL.m.Lock()
 	defer sL.m.Unlock()
 
-	o := newBeginOptions(options...)
-
 	nsL := &spanLogImpl{
 		m:            &sync.Mutex{},
 		b:            sL.b.Clone(),
@@ -104,43 +151,110 @@ func (sL *spanLogImpl) Begin(ctx context.Context, name string, options ...BeginO
 		spanID:       idz.MustNewRandomUUID(),
 		metadata:     o.metadata,
 		errMetadata:  o.errMetadata,
+		attributes:   map[string]any{},
+		baggage:      maps.Clone(sL.baggage),
+		kind:         o.kind,
 		hasErrorFlag: false,
+		bL:           sL.bL,
 	}
 
+	nsL.captureDeadline(ctx)
+	nsL.restoreBaggage(o.baggage)
+	nsL.emitLinks(ctx, o.links)
 	ctx = NewSingletonInjector(nsL)(ctx)
+	nsL.SetAttributes(ctx, o.attributes...)
+	sL.bL.tracker.track(ctx, nsL, sL)
+	nsL.startWatchdog(ctx, memz.Ternary(o.budget > 0, o.budget, sL.bL.o.spanBudget))
 
 	return ctx, func() {
-		nsL.end(ctx)
+		nsL.end(ctx, false)
 	}
 }
 
-func (sL *spanLogImpl) end(ctx context.Context) {
+func (sL *spanLogImpl) emitLinks(ctx context.Context, links []*TraceLink) {
+	for _, link := range links {
+		e := newTraceLinkEvent(ctx, sL.b, sL.spanID, link)
+		errorz.MaybeMustWrap(e.Send())
+	}
+}
+
+func (sL *spanLogImpl) end(ctx context.Context, isTruncated bool) {
+	if !sL.markEnded() {
+		sL.EmitWarning(ctx, errorz.Errorf("span '%v' ended more than once", sL.name))
+		return
+	}
+
+	for _, child := range sL.bL.tracker.getChildrenToClose(sL) {
+		child.sL.end(child.ctx, true)
+	}
+
 	sL.m.Lock()
 	defer sL.m.Unlock()
+	defer sL.bL.tracker.untrack(sL)
 
 	e := newTraceableEvent(ctx, sL.b, sL.name, sL.spanID, sL.parentID, sL.startTime)
-	addMetadataFields(e, "scope.metadata", sL.metadata)
+	pl := newPayloadLimiter(e, sL.bL.o.payloadLimits)
+	rf := pl.required()
 
 	if sL.hasErrorFlag {
-		e.AddField("error", true)
-		addMetadataFields(e, "scope.metadata", sL.errMetadata)
+		rf.AddField("error", true)
+		maybeAddLenField(rf, "", "error.reference", sL.errorRef)
 	}
 
+	status := sL.status
+	if status == "" {
+		status = memz.Ternary(sL.hasErrorFlag, SpanStatusError, SpanStatusOK)
+	}
+
+	rf.AddField("span.kind", string(sL.kind))
+	rf.AddField("span.status", string(status))
+	maybeAddLenField(rf, "", "span.status.message", sL.statusMessage)
+
+	if isTruncated {
+		rf.AddField("span.truncated", true)
+	}
+
+	sL.addContextFields(ctx, rf)
+
+	// Metadata is added last, so that it is dropped first when the event exceeds the limits.
+	addMetadataFields(pl, "scope.metadata", sL.metadata)
+	addMetadataFields(pl, "", sL.attributes)
+
+	if sL.hasErrorFlag {
+		addMetadataFields(pl, "scope.metadata", sL.errMetadata)
+	}
+
+	pl.finish()
 	errorz.MaybeMustWrap(e.Send())
 }
 
+func (sL *spanLogImpl) markEnded() bool {
+	sL.m.Lock()
+	defer sL.m.Unlock()
+
+	if sL.isEnded {
+		return false
+	}
+
+	sL.isEnded = true
+	return true
+}
+
 // SetUser implements the RawLog interface.
 func (sL *spanLogImpl) SetUser(_ context.Context, user *User) {
 	sL.m.Lock()
 	defer sL.m.Unlock()
 	maybeAddUserFields(sL.b, user)
+	maybeAddUserFields(sL.baggage, user)
 }
 
 // SetPropagatingField implements the RawLog interface.
 func (sL *spanLogImpl) SetPropagatingField(_ context.Context, k string, v any) {
 	sL.m.Lock()
 	defer sL.m.Unlock()
-	sL.b.AddField(fmt.Sprintf("scope.%v", strings.TrimPrefix(k, "scope.")), v)
+	k = fmt.Sprintf("scope.%v", strings.TrimPrefix(k, "scope."))
+	sL.b.AddField(k, v)
+	sL.baggage.AddField(k, v)
 }
 
 // SetMetadataKey implements the RawLog interface.
@@ -157,6 +271,41 @@ func (sL *spanLogImpl) SetErrorMetadataKey(_ context.Context, k string, v any) {
 	sL.errMetadata[k] = v
 }
 
+// SetAttributes implements the RawLog interface.
+func (sL *spanLogImpl) SetAttributes(ctx context.Context, attrs ...*Attribute) {
+	attrs = sL.validateAttributes(ctx, attrs)
+
+	sL.m.Lock()
+	defer sL.m.Unlock()
+
+	for _, attr := range attrs {
+		sL.attributes[attr.Key] = attr.Value
+	}
+}
+
+// SetPropagatingAttributes implements the RawLog interface.
+func (sL *spanLogImpl) SetPropagatingAttributes(ctx context.Context, attrs ...*Attribute) {
+	attrs = sL.validateAttributes(ctx, attrs)
+
+	sL.m.Lock()
+	defer sL.m.Unlock()
+
+	for _, attr := range attrs {
+		sL.b.AddField(attr.Key, attr.Value)
+		sL.baggage.AddField(attr.Key, attr.Value)
+	}
+}
+
+func (sL *spanLogImpl) validateAttributes(ctx context.Context, attrs []*Attribute) []*Attribute {
+	valid, errs := sL.bL.validateAttributes(attrs)
+
+	for _, err := range errs {
+		sL.EmitWarning(ctx, err)
+	}
+
+	return valid
+}
+
 // SetErrorFlag implements the RawLog interface.
 func (sL *spanLogImpl) SetErrorFlag(_ context.Context) {
 	sL.m.Lock()
@@ -164,6 +313,20 @@ func (sL *spanLogImpl) SetErrorFlag(_ context.Context) {
 	sL.hasErrorFlag = true
 }
 
+// SetStatus implements the RawLog interface. Setting [SpanStatusError] also sets the error flag, while cancellation
+// and deadlines do not. If no status is set, it is derived from the error flag when the span ends.
+func (sL *spanLogImpl) SetStatus(_ context.Context, status SpanStatus, message string) {
+	sL.m.Lock()
+	defer sL.m.Unlock()
+
+	sL.status = status
+	sL.statusMessage = message
+
+	if status == SpanStatusError {
+		sL.hasErrorFlag = true
+	}
+}
+
 // GetCurrentTraceLink implements the RawLog interface.
 func (sL *spanLogImpl) GetCurrentTraceLink(_ context.Context) *TraceLink {
 	sL.m.Lock()
@@ -175,6 +338,25 @@ func (sL *spanLogImpl) GetCurrentTraceLink(_ context.Context) *TraceLink {
 	}
 }
 
+// GetCurrentBaggage implements the RawLog interface.
+func (sL *spanLogImpl) GetCurrentBaggage(_ context.Context) Baggage {
+	sL.m.Lock()
+	defer sL.m.Unlock()
+	return limitBaggage(sL.baggage, sL.bL.o.baggageAllowlist, sL.bL.o.baggageMaxBytes)
+}
+
+func (sL *spanLogImpl) restoreBaggage(baggage Baggage) {
+	baggage = maps.Clone(baggage)
+	maps.DeleteFunc(baggage, func(k string, _ any) bool {
+		return !isBaggageKeyRestorable(k, sL.bL.o.baggageAllowlist)
+	})
+
+	for k, v := range limitBaggage(baggage, sL.bL.o.baggageAllowlist, sL.bL.o.baggageMaxBytes) {
+		sL.b.AddField(k, v)
+		sL.baggage.AddField(k, v)
+	}
+}
+
 // Flush implements the RawLog interface.
 func (sL *spanLogImpl) Flush(_ context.Context) {
 	// do nothing: flushing is only enabled on the background log
@@ -218,3 +218,42 @@ func (s *SpanSuite) TestSpan(ctx context.Context, g *WithT) {
 			})),
 		))
 }
+
+func (s *SpanSuite) TestSpanLinks(ctx context.Context, g *WithT) {
+	ctx, e1 := logm.MustGet(ctx).Begin("S1")
+	l1 := logm.MustGet(ctx).GetCurrentTraceLink()
+	e1()
+
+	ctx, e2 := logm.MustGet(ctx).Begin("S2")
+	l2 := logm.MustGet(ctx).GetCurrentTraceLink()
+
+	err := logm.Wrap0(ctx, "S3", func(ctx context.Context) error {
+		l3 := logm.MustGet(ctx).GetCurrentTraceLink()
+		g.Expect(l3.TraceID).ToNot(Equal(l2.TraceID))
+		return nil
+	}, logm.BeginRoot(), logm.BeginLinks(l1, l2, nil, &logm.TraceLink{}))
+	g.Expect(err).To(Succeed())
+	e2()
+
+	events := s.LOG.GetMock().GetEvents()
+	g.Expect(events).To(HaveLen(5))
+
+	g.Expect(events[1].Data).To(And(
+		HaveKeyWithValue("meta.annotation_type", "link"),
+		HaveKeyWithValue("trace.link.trace_id", l1.TraceID),
+		HaveKeyWithValue("trace.link.span_id", l1.SpanID),
+		HaveKeyWithValue("trace.trace_id", events[3].Data["trace.trace_id"]),
+		HaveKeyWithValue("trace.parent_id", events[3].Data["trace.span_id"])))
+
+	g.Expect(events[2].Data).To(And(
+		HaveKeyWithValue("meta.annotation_type", "link"),
+		HaveKeyWithValue("trace.link.trace_id", l2.TraceID),
+		HaveKeyWithValue("trace.link.span_id", l2.SpanID),
+		HaveKeyWithValue("trace.parent_id", events[3].Data["trace.span_id"])))
+
+	g.Expect(events[3].Data).To(And(
+		HaveKeyWithValue("name", "S3"),
+		Not(HaveKey("trace.parent_id"))))
+
+	g.Expect(events[4].Data).To(HaveKeyWithValue("name", "S2"))
+}
@@ -0,0 +1,81 @@
+package logm
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+// StartRuntimeStats starts emitting a "runtime-stats" event every interval, describing goroutines, heap usage and GC
+// pauses since the previous event. It returns a function that stops emitting events.
+func StartRuntimeStats(ctx context.Context, interval time.Duration) func() {
+	errorz.Assertf(interval > 0, "runtime stats interval must be positive")
+
+	stopC := make(chan struct{})
+	doneC := make(chan struct{})
+
+	// Seed the GC count, so that the first event only reports the collections that happened since the start.
+	ms := &runtime.MemStats{}
+	runtime.ReadMemStats(ms)
+	prevNumGC := ms.NumGC
+
+	go func() {
+		defer close(doneC)
+
+		ticker := clkm.MustGet(ctx).Ticker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopC:
+				return
+			case <-ticker.C:
+				prevNumGC = emitRuntimeStats(ctx, prevNumGC)
+			}
+		}
+	}()
+
+	return func() {
+		close(stopC)
+		<-doneC
+	}
+}
+
+func emitRuntimeStats(ctx context.Context, prevNumGC uint32) uint32 {
+	bL, ok := getRootRawLog(ctx).(*backgroundLogImpl)
+	if !ok {
+		return prevNumGC
+	}
+
+	ms := &runtime.MemStats{}
+	runtime.ReadMemStats(ms)
+
+	e := newAttachableEvent(ctx, bL.client, "", "runtime-stats")
+	e.AddField("runtime.goroutines", runtime.NumGoroutine())
+	e.AddField("runtime.heap.alloc_bytes", ms.HeapAlloc)
+	e.AddField("runtime.heap.inuse_bytes", ms.HeapInuse)
+	e.AddField("runtime.heap.sys_bytes", ms.HeapSys)
+	e.AddField("runtime.heap.objects", ms.HeapObjects)
+
+	// MemStats only retains the last 256 pauses, older ones are lost if more GCs happened since the previous event.
+	numGC := ms.NumGC - prevNumGC
+	pauseTotal := time.Duration(0)
+	pauseMax := time.Duration(0)
+
+	for i := ms.NumGC; i > prevNumGC && ms.NumGC-i < uint32(len(ms.PauseNs)); i-- {
+		pause := time.Duration(ms.PauseNs[(i+uint32(len(ms.PauseNs))-1)%uint32(len(ms.PauseNs))])
+		pauseTotal += pause
+		pauseMax = max(pauseMax, pause)
+	}
+
+	e.AddField("runtime.gc.count", numGC)
+	e.AddField("runtime.gc.pause_total_ms", float64(pauseTotal)/float64(time.Millisecond))
+	e.AddField("runtime.gc.pause_max_ms", float64(pauseMax)/float64(time.Millisecond))
+	errorz.MaybeMustWrap(e.Send())
+
+	return ms.NumGC
+}
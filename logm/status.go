@@ -0,0 +1,87 @@
+package logm
+
+import (
+	"context"
+	"encoding"
+	"errors"
+
+	"github.com/ibrt/golang-utils/errorz"
+)
+
+var (
+	_ encoding.TextUnmarshaler = (*SpanStatus)(nil)
+	_ encoding.TextUnmarshaler = (*SpanKind)(nil)
+)
+
+// SpanStatus describes the outcome of a span.
+type SpanStatus string
+
+// Known SpanStatus values.
+const (
+	SpanStatusOK               SpanStatus = "ok"
+	SpanStatusError            SpanStatus = "error"
+	SpanStatusCancelled        SpanStatus = "cancelled"
+	SpanStatusDeadlineExceeded SpanStatus = "deadline-exceeded"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (s *SpanStatus) UnmarshalText(text []byte) error {
+	switch v := SpanStatus(text); v {
+	case SpanStatusOK, SpanStatusError, SpanStatusCancelled, SpanStatusDeadlineExceeded:
+		*s = v
+		return nil
+	default:
+		return errorz.Errorf("invalid value for SpanStatus: '%s'", v)
+	}
+}
+
+// String implements the [fmt.Stringer] interface.
+func (s *SpanStatus) String() string {
+	return string(*s)
+}
+
+// SpanKind describes the role of a span in a trace.
+type SpanKind string
+
+// Known SpanKind values.
+const (
+	SpanKindInternal SpanKind = "internal"
+	SpanKindServer   SpanKind = "server"
+	SpanKindClient   SpanKind = "client"
+	SpanKindProducer SpanKind = "producer"
+	SpanKindConsumer SpanKind = "consumer"
+)
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (k *SpanKind) UnmarshalText(text []byte) error {
+	switch v := SpanKind(text); v {
+	case "":
+		*k = SpanKindInternal
+		return nil
+	case SpanKindInternal, SpanKindServer, SpanKindClient, SpanKindProducer, SpanKindConsumer:
+		*k = v
+		return nil
+	default:
+		return errorz.Errorf("invalid value for SpanKind: '%s'", v)
+	}
+}
+
+// String implements the [fmt.Stringer] interface.
+func (k *SpanKind) String() string {
+	return string(*k)
+}
+
+// GetErrorSpanStatus returns the [SpanStatus] corresponding to the given error: [SpanStatusCancelled] and
+// [SpanStatusDeadlineExceeded] for context errors, [SpanStatusError] for other errors, [SpanStatusOK] for nil.
+func GetErrorSpanStatus(err error) SpanStatus {
+	switch {
+	case err == nil:
+		return SpanStatusOK
+	case errors.Is(err, context.Canceled):
+		return SpanStatusCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return SpanStatusDeadlineExceeded
+	default:
+		return SpanStatusError
+	}
+}
@@ -0,0 +1,119 @@
+package logm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type StatusSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestStatusSuite(t *testing.T) {
+	fixturez.RunSuite(t, &StatusSuite{})
+}
+
+func (s *StatusSuite) TestSpanStatus(g *WithT) {
+	st := logm.SpanStatus("")
+	g.Expect(st.UnmarshalText([]byte("deadline-exceeded"))).To(Succeed())
+	g.Expect(st).To(Equal(logm.SpanStatusDeadlineExceeded))
+	g.Expect(st.String()).To(Equal("deadline-exceeded"))
+	g.Expect(st.UnmarshalText([]byte(""))).To(MatchError("invalid value for SpanStatus: ''"))
+
+	k := logm.SpanKind("")
+	g.Expect(k.UnmarshalText([]byte(""))).To(Succeed())
+	g.Expect(k).To(Equal(logm.SpanKindInternal))
+	g.Expect(k.UnmarshalText([]byte("consumer"))).To(Succeed())
+	g.Expect(k.String()).To(Equal("consumer"))
+	g.Expect(k.UnmarshalText([]byte("unknown"))).To(MatchError("invalid value for SpanKind: 'unknown'"))
+}
+
+func (s *StatusSuite) TestGetErrorSpanStatus(ctx context.Context, g *WithT) {
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	g.Expect(logm.GetErrorSpanStatus(nil)).To(Equal(logm.SpanStatusOK))
+	g.Expect(logm.GetErrorSpanStatus(errorz.Errorf("test error"))).To(Equal(logm.SpanStatusError))
+	g.Expect(logm.GetErrorSpanStatus(errorz.Wrap(canceledCtx.Err()))).To(Equal(logm.SpanStatusCancelled))
+	g.Expect(logm.GetErrorSpanStatus(errorz.Wrap(context.DeadlineExceeded))).To(Equal(logm.SpanStatusDeadlineExceeded))
+}
+
+func (s *StatusSuite) TestWrap(ctx context.Context, g *WithT) {
+	g.Expect(logm.Wrap0(ctx, "ok", func(ctx context.Context) error {
+		return nil
+	}, logm.BeginKind(logm.SpanKindServer))).To(Succeed())
+
+	g.Expect(logm.Wrap0(ctx, "error", func(ctx context.Context) error {
+		return errorz.Errorf("test error")
+	})).To(MatchError("test error"))
+
+	g.Expect(logm.Wrap0(ctx, "cancelled", func(ctx context.Context) error {
+		return errorz.Wrap(context.Canceled)
+	})).To(MatchError(context.Canceled))
+
+	g.Expect(logm.Wrap0(ctx, "deadline-exceeded", func(ctx context.Context) error {
+		return errorz.Wrap(context.DeadlineExceeded)
+	}, logm.BeginKind(logm.SpanKindClient))).To(MatchError(context.DeadlineExceeded))
+
+	hasStatus := func(kind logm.SpanKind, status logm.SpanStatus, message string) OmegaMatcher {
+		fields := And(
+			HaveKeyWithValue("span.kind", string(kind)),
+			HaveKeyWithValue("span.status", string(status)))
+
+		if message != "" {
+			fields = And(fields, HaveKeyWithValue("span.status.message", message))
+		} else {
+			fields = And(fields, Not(HaveKey("span.status.message")))
+		}
+
+		return HaveField("Fields", fields)
+	}
+
+	tree := s.LOG.GetMock().GetTree()
+	g.Expect(tree).To(tlogm.HaveSpan("ok", hasStatus(logm.SpanKindServer, logm.SpanStatusOK, "")))
+	g.Expect(tree).To(tlogm.HaveSpan("error", hasStatus(logm.SpanKindInternal, logm.SpanStatusError, "test error")))
+	g.Expect(tree).To(tlogm.HaveSpan("cancelled", hasStatus(logm.SpanKindInternal, logm.SpanStatusCancelled, "context canceled")))
+	g.Expect(tree).To(tlogm.HaveSpan("deadline-exceeded", hasStatus(logm.SpanKindClient, logm.SpanStatusDeadlineExceeded, "context deadline exceeded")))
+
+	// Context errors are not considered errors: they set the status, but not the error flag, and are not emitted.
+	g.Expect(tree.FindSpan("error").HasError).To(BeTrue())
+	g.Expect(tree.FindSpan("cancelled").HasError).To(BeFalse())
+	g.Expect(tree.FindSpan("cancelled").Events).To(BeEmpty())
+	g.Expect(tree.FindSpan("deadline-exceeded").HasError).To(BeFalse())
+	g.Expect(tree.FindSpan("deadline-exceeded").Events).To(BeEmpty())
+}
+
+func (s *StatusSuite) TestSetStatus(ctx context.Context, g *WithT) {
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("explicit")
+		defer end()
+		logm.MustGet(ctx).SetStatus(logm.SpanStatusCancelled, "message")
+	}()
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("flag")
+		defer end()
+		logm.MustGet(ctx).SetErrorFlag()
+	}()
+
+	tree := s.LOG.GetMock().GetTree()
+
+	g.Expect(tree).To(tlogm.HaveSpan("explicit", HaveField("Fields", And(
+		Not(HaveKey("error")),
+		HaveKeyWithValue("span.status", string(logm.SpanStatusCancelled)),
+		HaveKeyWithValue("span.status.message", "message")))))
+
+	g.Expect(tree).To(tlogm.HaveSpan("flag", HaveField("Fields", And(
+		HaveKeyWithValue("error", true),
+		HaveKeyWithValue("span.status", string(logm.SpanStatusError)),
+		Not(HaveKey("span.status.message"))))))
+}
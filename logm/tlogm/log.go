@@ -2,10 +2,12 @@ package tlogm
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 
 	"github.com/honeycombio/libhoney-go"
 	"github.com/ibrt/golang-utils/fixturez"
+	"github.com/ibrt/golang-utils/injectz"
 	"github.com/ibrt/golang-utils/outz"
 	"github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
@@ -19,6 +21,7 @@ var (
 	_ fixturez.BeforeSuite = (*MockHelper)(nil)
 	_ fixturez.AfterSuite  = (*MockHelper)(nil)
 	_ fixturez.BeforeTest  = (*MockHelper)(nil)
+	_ fixturez.AfterTest   = (*MockHelper)(nil)
 )
 
 // MockHelper is a test helper.
@@ -50,7 +53,7 @@ func (h *MockHelper) BeforeSuite(ctx context.Context, g *gomega.WithT) context.C
 	h.mock = mock
 	h.client = client
 
-	return logm.NewSingletonInjector(logm.NewRawLogFromClient(client))(ctx)
+	return h.newInjector()(ctx)
 }
 
 // AfterSuite implements [fixturez.AfterSuite].
@@ -68,7 +71,22 @@ func (h *MockHelper) AfterSuite(_ context.Context, _ *gomega.WithT) {
 // BeforeTest implements [fixturez.BeforeTest].
 func (h *MockHelper) BeforeTest(ctx context.Context, _ *gomega.WithT, _ *gomock.Controller) context.Context {
 	h.mock.ClearEvents()
-	return ctx
+	return h.newInjector()(ctx)
+}
+
+// AfterTest implements [fixturez.AfterTest]. It fails the test if any span was begun but not ended.
+func (h *MockHelper) AfterTest(ctx context.Context, g *gomega.WithT) {
+	leaks := make([]string, 0)
+
+	for _, openSpan := range logm.GetOpenSpans(ctx) {
+		leaks = append(leaks, fmt.Sprintf("'%v' begun at '%v'", openSpan.Name, openSpan.Location))
+	}
+
+	g.Expect(leaks).To(gomega.BeEmpty(), "leaked spans")
+}
+
+func (h *MockHelper) newInjector() injectz.Injector {
+	return logm.NewSingletonInjector(logm.NewRawLogFromClient(h.client, logm.RawLogSpanTracking(true, false)))
 }
 
 // GetMock returns the mock.
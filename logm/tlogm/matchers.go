@@ -0,0 +1,167 @@
+package tlogm
+
+import (
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+)
+
+// HaveSpan succeeds if the actual value contains a span with the given name that satisfies all the given matchers. The
+// actual value can be a [*Tree], a [*MockSender], a []*transmission.Event, or a [*Span] (whose descendants are
+// searched).
+func HaveSpan(name string, matchers ...types.GomegaMatcher) types.GomegaMatcher {
+	return gomega.WithTransform(
+		func(actual any) ([]*Span, error) {
+			if s, ok := actual.(*Span); ok {
+				return s.GetSpans()[1:], nil
+			}
+
+			t, err := toTree(actual)
+			if err != nil {
+				return nil, errorz.Wrap(err)
+			}
+
+			return t.GetSpans(), nil
+		},
+		gomega.ContainElement(newSpanMatcher(name, matchers)))
+}
+
+// HaveChild succeeds if the actual [*Span] has a direct child with the given name that satisfies all the given
+// matchers.
+func HaveChild(name string, matchers ...types.GomegaMatcher) types.GomegaMatcher {
+	return gomega.WithTransform(
+		func(s *Span) []*Span {
+			return s.Children
+		},
+		gomega.ContainElement(newSpanMatcher(name, matchers)))
+}
+
+// HaveError succeeds if the actual [*Span] has the error flag set, and has an error event with the given error name.
+func HaveError(name any) types.GomegaMatcher {
+	return gomega.And(
+		gomega.WithTransform(
+			func(s *Span) bool {
+				return s.HasError
+			},
+			gomega.BeTrue()),
+		gomega.WithTransform(
+			func(s *Span) []*Event {
+				return s.Events
+			},
+			gomega.ContainElement(gomega.And(
+				newEventMatcher("error", nil, nil),
+				gomega.WithTransform(
+					func(e *Event) any {
+						return e.Fields["error"]
+					},
+					toMatcher(name))))))
+}
+
+// HaveEvent succeeds if the actual value contains an event with the given level and message, that satisfies all the
+// given matchers. The actual value can be a [*Tree], a [*MockSender], a []*transmission.Event (all events are
+// searched), or a [*Span] (only its own events are searched). The message can be a string, a matcher, or nil to match
+// any message.
+func HaveEvent(level string, message any, matchers ...types.GomegaMatcher) types.GomegaMatcher {
+	return gomega.WithTransform(
+		func(actual any) ([]*Event, error) {
+			if s, ok := actual.(*Span); ok {
+				return s.Events, nil
+			}
+
+			t, err := toTree(actual)
+			if err != nil {
+				return nil, errorz.Wrap(err)
+			}
+
+			return t.GetEvents(), nil
+		},
+		gomega.ContainElement(newEventMatcher(level, message, matchers)))
+}
+
+// HaveMetadata succeeds if the actual [*Span] or [*Event] has the given metadata key, with a value equal to or matching
+// the given value.
+func HaveMetadata(k string, v any) types.GomegaMatcher {
+	return gomega.WithTransform(
+		func(actual any) (map[string]any, error) {
+			switch actual := actual.(type) {
+			case *Span:
+				return actual.Metadata, nil
+			case *Event:
+				return actual.Metadata, nil
+			default:
+				return nil, errorz.Errorf("HaveMetadata expects a *Span or an *Event, got %T", actual)
+			}
+		},
+		gomega.HaveKeyWithValue(k, v))
+}
+
+// HaveAttribute succeeds if the actual [*Span] or [*Event] has the given typed attribute (i.e. set using
+// logm.SetAttributes), with a value equal to or matching the given value.
+func HaveAttribute(k string, v any) types.GomegaMatcher {
+	return gomega.WithTransform(
+		func(actual any) (map[string]any, error) {
+			switch actual := actual.(type) {
+			case *Span:
+				return actual.Fields, nil
+			case *Event:
+				return actual.Fields, nil
+			default:
+				return nil, errorz.Errorf("HaveAttribute expects a *Span or an *Event, got %T", actual)
+			}
+		},
+		gomega.HaveKeyWithValue(k, toMatcher(v)))
+}
+
+func newSpanMatcher(name string, matchers []types.GomegaMatcher) types.GomegaMatcher {
+	return gomega.And(append(
+		[]types.GomegaMatcher{
+			gomega.WithTransform(
+				func(s *Span) string {
+					return s.Name
+				},
+				gomega.Equal(name)),
+		},
+		matchers...)...)
+}
+
+func newEventMatcher(level string, message any, matchers []types.GomegaMatcher) types.GomegaMatcher {
+	allMatchers := []types.GomegaMatcher{
+		gomega.WithTransform(
+			func(e *Event) string {
+				return e.Level
+			},
+			gomega.Equal(level)),
+	}
+
+	if message != nil {
+		allMatchers = append(allMatchers, gomega.WithTransform(
+			func(e *Event) string {
+				return e.Message
+			},
+			toMatcher(message)))
+	}
+
+	return gomega.And(append(allMatchers, matchers...)...)
+}
+
+func toMatcher(v any) types.GomegaMatcher {
+	if m, ok := v.(types.GomegaMatcher); ok {
+		return m
+	}
+
+	return gomega.Equal(v)
+}
+
+func toTree(actual any) (*Tree, error) {
+	switch actual := actual.(type) {
+	case *Tree:
+		return actual, nil
+	case *MockSender:
+		return actual.GetTree(), nil
+	case []*transmission.Event:
+		return NewTree(actual), nil
+	default:
+		return nil, errorz.Errorf("expected a *Tree, a *MockSender, or a []*transmission.Event, got %T", actual)
+	}
+}
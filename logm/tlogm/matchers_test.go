@@ -0,0 +1,106 @@
+package tlogm_test
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type MatchersSuite struct {
+	// intentionally empty
+}
+
+func TestMatchersSuite(t *testing.T) {
+	fixturez.RunSuite(t, &MatchersSuite{})
+}
+
+func (*MatchersSuite) TestHaveSpan(g *WithT) {
+	events := newTestEvents()
+	sender := tlogm.NewMockSender()
+
+	for _, e := range events {
+		sender.Add(e)
+	}
+
+	for _, actual := range []any{events, tlogm.NewTree(events), sender} {
+		g.Expect(actual).To(tlogm.HaveSpan("root", tlogm.HaveChild("child")))
+		g.Expect(actual).To(tlogm.HaveSpan("unparented"))
+		g.Expect(actual).ToNot(tlogm.HaveSpan("root", tlogm.HaveChild("unparented")))
+		g.Expect(actual).ToNot(tlogm.HaveSpan("missing"))
+	}
+
+	root := tlogm.NewTree(events).FindSpan("root")
+	g.Expect(root).To(tlogm.HaveSpan("child"))
+	g.Expect(root).ToNot(tlogm.HaveSpan("root"))
+}
+
+func (*MatchersSuite) TestHaveSpan_InvalidActual(g *WithT) {
+	ok, err := tlogm.HaveSpan("root").Match("invalid")
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(MatchError(ContainSubstring("expected a *Tree, a *MockSender, or a []*transmission.Event, got string")))
+
+	ok, err = tlogm.HaveSpan("root").Match([]transmission.Event{})
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(MatchError(ContainSubstring("got []transmission.Event")))
+}
+
+func (*MatchersSuite) TestHaveEvent(g *WithT) {
+	t := tlogm.NewTree(newTestEvents())
+
+	g.Expect(t).To(tlogm.HaveEvent("info", "background"))
+	g.Expect(t).To(tlogm.HaveEvent("error", nil))
+	g.Expect(t).To(tlogm.HaveEvent("error", ContainSubstring("test")))
+	g.Expect(t).ToNot(tlogm.HaveEvent("warning", nil))
+	g.Expect(t.FindSpan("child")).To(tlogm.HaveEvent("error", "test error"))
+	g.Expect(t.FindSpan("root")).ToNot(tlogm.HaveEvent("error", nil))
+
+	ok, err := tlogm.HaveEvent("info", nil).Match(1)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(MatchError(ContainSubstring("got int")))
+}
+
+func (*MatchersSuite) TestHaveError(g *WithT) {
+	t := tlogm.NewTree(newTestEvents())
+
+	g.Expect(t.FindSpan("child")).To(tlogm.HaveError("generic"))
+	g.Expect(t.FindSpan("child")).ToNot(tlogm.HaveError("other"))
+
+	// The error flag alone is not enough: the span must also have an error event.
+	g.Expect(&tlogm.Span{Name: "s", HasError: true}).ToNot(tlogm.HaveError("generic"))
+
+	// The error event alone is not enough: the span must also have the error flag set.
+	g.Expect(&tlogm.Span{Name: "s", Events: t.FindSpan("child").Events}).ToNot(tlogm.HaveError("generic"))
+
+	ok, err := tlogm.HaveError("generic").Match(t)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(HaveOccurred())
+}
+
+func (*MatchersSuite) TestHaveMetadata(g *WithT) {
+	t := tlogm.NewTree(newTestEvents())
+
+	g.Expect(t.FindSpan("root")).To(tlogm.HaveMetadata("k", "v"))
+	g.Expect(t.FindSpan("root")).ToNot(tlogm.HaveMetadata("k", "other"))
+	g.Expect(&tlogm.Event{Metadata: map[string]any{"k": "v"}}).To(tlogm.HaveMetadata("k", "v"))
+
+	ok, err := tlogm.HaveMetadata("k", "v").Match(t)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(MatchError(ContainSubstring("HaveMetadata expects a *Span or an *Event, got *tlogm.Tree")))
+}
+
+func (*MatchersSuite) TestHaveAttribute(g *WithT) {
+	t := tlogm.NewTree(newTestEvents())
+
+	g.Expect(t.FindSpan("root")).To(tlogm.HaveAttribute("custom.attribute", "a"))
+	g.Expect(t.FindSpan("root")).To(tlogm.HaveAttribute("custom.attribute", HavePrefix("a")))
+	g.Expect(t.FindSpan("root")).ToNot(tlogm.HaveAttribute("duration_ms", 3000.0))
+	g.Expect(t.GetEvents()[0]).To(tlogm.HaveAttribute("info.message", "background"))
+
+	ok, err := tlogm.HaveAttribute("k", "v").Match(nil)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(MatchError(ContainSubstring("HaveAttribute expects a *Span or an *Event, got <nil>")))
+}
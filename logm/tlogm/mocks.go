@@ -85,3 +85,8 @@ func (s *MockSender) SendResponse(response transmission.Response) bool {
 		return true
 	}
 }
+
+// GetTree returns the events rebuilt as a [*Tree].
+func (s *MockSender) GetTree() *Tree {
+	return NewTree(s.GetEvents())
+}
@@ -0,0 +1,240 @@
+package tlogm
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/jsonz"
+)
+
+var (
+	eventLevels = []string{
+		"debug",
+		"info",
+		"warning",
+		"error",
+	}
+
+	volatileFieldKeys = []string{
+		"duration_ms",
+		"location",
+		"trace.trace_id",
+		"trace.span_id",
+		"trace.parent_id",
+		"trace.link.trace_id",
+		"trace.link.span_id",
+		"meta.annotation_type",
+		"error.fingerprint",
+		"error.reference",
+		"error.dump",
+		"error.panic.goroutines",
+		"warning.dump",
+	}
+
+	volatileFieldPrefixes = []string{
+		"location.",
+	}
+)
+
+// Tree describes the spans and events captured by a [*MockSender], rebuilt as a tree. Volatile fields such as IDs,
+// timestamps, durations and locations are omitted, so that trees can be compared across runs, e.g. as golden files.
+// Span durations remain available as [Span.DurationMS], which is not marshaled.
+type Tree struct {
+	Spans  []*Span  `json:"spans"`
+	Events []*Event `json:"events"`
+}
+
+// Span describes a span in a [*Tree].
+type Span struct {
+	Name       string         `json:"name"`
+	HasError   bool           `json:"hasError,omitempty"`
+	DurationMS float64        `json:"-"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	Fields     map[string]any `json:"fields"`
+	Events     []*Event       `json:"events,omitempty"`
+	Children   []*Span        `json:"children,omitempty"`
+}
+
+// Event describes a span event or a background event in a [*Tree].
+type Event struct {
+	Name     string         `json:"name"`
+	Level    string         `json:"level,omitempty"`
+	Message  string         `json:"message,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Fields   map[string]any `json:"fields"`
+}
+
+// NewTree rebuilds a [*Tree] from the given events. Spans are linked to their parents using trace IDs, span events are
+// attached to their span. Spans and events whose parent was not captured are returned at the top level.
+func NewTree(events []*transmission.Event) *Tree {
+	t := &Tree{
+		Spans:  make([]*Span, 0),
+		Events: make([]*Event, 0),
+	}
+
+	spans := make(map[string]*Span)
+
+	for _, e := range events {
+		if spanID := getStringField(e.Data, "trace.span_id"); spanID != "" {
+			spans[spanID] = newSpan(e)
+		}
+	}
+
+	// Spans are emitted when they end, so children precede their parents: events are re-sorted by timestamp (stably)
+	// to yield a natural ordering.
+	sorted := slices.Clone(events)
+	slices.SortStableFunc(sorted, func(a, b *transmission.Event) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	for _, e := range sorted {
+		parent := spans[getStringField(e.Data, "trace.parent_id")]
+
+		if spanID := getStringField(e.Data, "trace.span_id"); spanID != "" {
+			if parent != nil {
+				parent.Children = append(parent.Children, spans[spanID])
+			} else {
+				t.Spans = append(t.Spans, spans[spanID])
+			}
+			continue
+		}
+
+		if parent != nil {
+			parent.Events = append(parent.Events, newEvent(e))
+		} else {
+			t.Events = append(t.Events, newEvent(e))
+		}
+	}
+
+	return t
+}
+
+// GetSpans returns all spans in the tree, in depth-first order.
+func (t *Tree) GetSpans() []*Span {
+	spans := make([]*Span, 0)
+
+	for _, s := range t.Spans {
+		spans = append(spans, s.GetSpans()...)
+	}
+
+	return spans
+}
+
+// GetEvents returns all events in the tree, including span events, in depth-first order.
+func (t *Tree) GetEvents() []*Event {
+	events := slices.Clone(t.Events)
+
+	for _, s := range t.GetSpans() {
+		events = append(events, s.Events...)
+	}
+
+	return events
+}
+
+// FindSpan returns the first span with the given name, in depth-first order, or nil.
+func (t *Tree) FindSpan(name string) *Span {
+	return findSpan(t.GetSpans(), name)
+}
+
+// MustMarshalSnapshot marshals the tree as indented JSON, e.g. to be compared with a golden file.
+func (t *Tree) MustMarshalSnapshot() string {
+	return string(jsonz.MustMarshalPretty(t))
+}
+
+// GetSpans returns the span and all its descendants, in depth-first order.
+func (s *Span) GetSpans() []*Span {
+	spans := []*Span{s}
+
+	for _, c := range s.Children {
+		spans = append(spans, c.GetSpans()...)
+	}
+
+	return spans
+}
+
+// FindSpan returns the first descendant span with the given name, in depth-first order, or nil.
+func (s *Span) FindSpan(name string) *Span {
+	return findSpan(s.GetSpans()[1:], name)
+}
+
+func findSpan(spans []*Span, name string) *Span {
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func newSpan(e *transmission.Event) *Span {
+	hasError, _ := e.Data["error"].(bool)
+	durationMS, _ := e.Data["duration_ms"].(float64)
+
+	return &Span{
+		Name:       getStringField(e.Data, "name"),
+		HasError:   hasError,
+		DurationMS: durationMS,
+		Metadata:   getMetadataFields(e.Data, "scope.metadata."),
+		Fields:     getStableFields(e.Data),
+		Events:     make([]*Event, 0),
+		Children:   make([]*Span, 0),
+	}
+}
+
+func newEvent(e *transmission.Event) *Event {
+	ev := &Event{
+		Name:   getStringField(e.Data, "name"),
+		Fields: getStableFields(e.Data),
+	}
+
+	if slices.Contains(eventLevels, ev.Name) {
+		ev.Level = ev.Name
+		ev.Message = getStringField(e.Data, ev.Name+".message")
+		ev.Metadata = getMetadataFields(e.Data, ev.Name+".metadata.")
+	}
+
+	return ev
+}
+
+func getStringField(data map[string]any, k string) string {
+	v, _ := data[k].(string)
+	return v
+}
+
+func getMetadataFields(data map[string]any, prefix string) map[string]any {
+	var metadata map[string]any
+
+	for k, v := range data {
+		if strings.HasPrefix(k, prefix) {
+			if metadata == nil {
+				metadata = make(map[string]any)
+			}
+			metadata[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+
+	return metadata
+}
+
+func getStableFields(data map[string]any) map[string]any {
+	fields := maps.Clone(data)
+
+	maps.DeleteFunc(fields, func(k string, _ any) bool {
+		if slices.Contains(volatileFieldKeys, k) {
+			return true
+		}
+
+		for _, prefix := range volatileFieldPrefixes {
+			if strings.HasPrefix(k, prefix) {
+				return true
+			}
+		}
+
+		return false
+	})
+
+	return fields
+}
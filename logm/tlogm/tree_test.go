@@ -0,0 +1,145 @@
+package tlogm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type TreeSuite struct {
+	// intentionally empty
+}
+
+func TestTreeSuite(t *testing.T) {
+	fixturez.RunSuite(t, &TreeSuite{})
+}
+
+func newTestEvents() []*transmission.Event {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	return []*transmission.Event{
+		{
+			Timestamp: now.Add(time.Second),
+			Data: map[string]any{
+				"name":            "child",
+				"duration_ms":     1000.0,
+				"error":           true,
+				"trace.trace_id":  "t1",
+				"trace.span_id":   "s2",
+				"trace.parent_id": "s1",
+				"location":        "child.go:1",
+			},
+		},
+		{
+			Timestamp: now.Add(2 * time.Second),
+			Data: map[string]any{
+				"name":                 "error",
+				"error":                "generic",
+				"error.message":        "test error",
+				"error.fingerprint":    "f1",
+				"meta.annotation_type": "span_event",
+				"trace.trace_id":       "t1",
+				"trace.parent_id":      "s2",
+			},
+		},
+		{
+			Timestamp: now,
+			Data: map[string]any{
+				"name":                "root",
+				"duration_ms":         3000.0,
+				"trace.trace_id":      "t1",
+				"trace.span_id":       "s1",
+				"scope.metadata.k":    "v",
+				"location.package":    "root",
+				"custom.attribute":    "a",
+				"trace.link.trace_id": "t0",
+			},
+		},
+		{
+			Timestamp: now,
+			Data: map[string]any{
+				"name":            "unparented",
+				"duration_ms":     1.0,
+				"trace.trace_id":  "t2",
+				"trace.span_id":   "s3",
+				"trace.parent_id": "missing",
+			},
+		},
+		{
+			Timestamp: now,
+			Data: map[string]any{
+				"name":         "info",
+				"info.message": "background",
+			},
+		},
+	}
+}
+
+func (*TreeSuite) TestNewTree(g *WithT) {
+	t := tlogm.NewTree(newTestEvents())
+
+	g.Expect(t.Spans).To(HaveLen(2))
+	g.Expect(t.Spans[0].Name).To(Equal("root"))
+	g.Expect(t.Spans[0].DurationMS).To(Equal(3000.0))
+	g.Expect(t.Spans[0].Metadata).To(Equal(map[string]any{"k": "v"}))
+	g.Expect(t.Spans[0].Fields).To(Equal(map[string]any{
+		"name":             "root",
+		"scope.metadata.k": "v",
+		"custom.attribute": "a",
+	}))
+	g.Expect(t.Spans[0].Children).To(HaveLen(1))
+	g.Expect(t.Spans[0].FindSpan("child")).To(BeIdenticalTo(t.Spans[0].Children[0]))
+	g.Expect(t.Spans[0].FindSpan("root")).To(BeNil())
+
+	// Spans whose parent was not captured are returned at the top level.
+	g.Expect(t.Spans[1].Name).To(Equal("unparented"))
+	g.Expect(t.FindSpan("unparented")).To(BeIdenticalTo(t.Spans[1]))
+	g.Expect(t.FindSpan("missing")).To(BeNil())
+
+	child := t.FindSpan("child")
+	g.Expect(child.HasError).To(BeTrue())
+	g.Expect(child.DurationMS).To(Equal(1000.0))
+	g.Expect(child.Events).To(HaveExactElements(&tlogm.Event{
+		Name:    "error",
+		Level:   "error",
+		Message: "test error",
+		Fields: map[string]any{
+			"name":          "error",
+			"error":         "generic",
+			"error.message": "test error",
+		},
+	}))
+
+	g.Expect(t.Events).To(HaveExactElements(&tlogm.Event{
+		Name:    "info",
+		Level:   "info",
+		Message: "background",
+		Fields: map[string]any{
+			"name":         "info",
+			"info.message": "background",
+		},
+	}))
+
+	g.Expect(t.GetSpans()).To(HaveLen(3))
+	g.Expect(t.GetEvents()).To(HaveLen(2))
+}
+
+func (*TreeSuite) TestMustMarshalSnapshot(g *WithT) {
+	snapshot := tlogm.NewTree(newTestEvents()).MustMarshalSnapshot()
+
+	// Snapshots are stable across runs, even without a mocked clock.
+	g.Expect(snapshot).ToNot(Or(
+		ContainSubstring("duration_ms"),
+		ContainSubstring("DurationMS"),
+		ContainSubstring("trace."),
+		ContainSubstring("location"),
+		ContainSubstring("fingerprint"),
+		ContainSubstring("2024")))
+	g.Expect(snapshot).To(ContainSubstring(`"custom.attribute": "a"`))
+	g.Expect(tlogm.NewTree(nil).MustMarshalSnapshot()).To(MatchJSON(`{"spans":[],"events":[]}`))
+}
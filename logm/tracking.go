@@ -0,0 +1,161 @@
+package logm
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+)
+
+// OpenSpan describes a span that was begun but not ended yet, see [GetOpenSpans].
+type OpenSpan struct {
+	Name      string
+	TraceID   string
+	SpanID    string
+	Location  string
+	StartTime time.Time
+}
+
+type trackedSpan struct {
+	ctx      context.Context
+	sL       *spanLogImpl
+	parent   *spanLogImpl
+	openSpan *OpenSpan
+}
+
+// spanTracker keeps track of open spans, so that leaks can be reported with the location of the corresponding Begin
+// call, and open children can be closed when their parent ends.
+type spanTracker struct {
+	autoCloseChildren bool
+	m                 *sync.Mutex
+	spans             map[*spanLogImpl]*trackedSpan
+}
+
+func newSpanTracker(o *rawLogOptions) *spanTracker {
+	if !o.spanTracking {
+		return nil
+	}
+
+	return &spanTracker{
+		autoCloseChildren: o.spanAutoCloseChildren,
+		m:                 &sync.Mutex{},
+		spans:             make(map[*spanLogImpl]*trackedSpan),
+	}
+}
+
+// track records an open span. It does nothing on a nil tracker.
+func (t *spanTracker) track(ctx context.Context, sL, parent *spanLogImpl) {
+	if t == nil {
+		return
+	}
+
+	openSpan := &OpenSpan{
+		Name:      sL.name,
+		TraceID:   sL.traceID,
+		SpanID:    sL.spanID,
+		StartTime: sL.startTime,
+	}
+
+	if frames := getLocationFrames(nil); len(frames) > 0 {
+		openSpan.Location = frames[0].Summary
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.spans[sL] = &trackedSpan{
+		ctx:      ctx,
+		sL:       sL,
+		parent:   parent,
+		openSpan: openSpan,
+	}
+}
+
+// untrack forgets an ended span. It does nothing on a nil tracker.
+func (t *spanTracker) untrack(sL *spanLogImpl) {
+	if t == nil {
+		return
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	delete(t.spans, sL)
+}
+
+// getChildrenToClose returns the open children of the given span if auto-closing is enabled, nil otherwise.
+func (t *spanTracker) getChildrenToClose(sL *spanLogImpl) []*trackedSpan {
+	if t == nil || !t.autoCloseChildren {
+		return nil
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	children := make([]*trackedSpan, 0)
+
+	for _, tS := range t.spans {
+		if tS.parent == sL {
+			children = append(children, tS)
+		}
+	}
+
+	slices.SortFunc(children, func(a, b *trackedSpan) int {
+		return compareOpenSpans(a.openSpan, b.openSpan)
+	})
+
+	return children
+}
+
+func (t *spanTracker) getOpenSpans() []*OpenSpan {
+	if t == nil {
+		return nil
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	openSpans := make([]*OpenSpan, 0, len(t.spans))
+
+	for _, tS := range t.spans {
+		openSpans = append(openSpans, tS.openSpan)
+	}
+
+	slices.SortFunc(openSpans, compareOpenSpans)
+	return openSpans
+}
+
+func compareOpenSpans(a, b *OpenSpan) int {
+	if c := a.StartTime.Compare(b.StartTime); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.Name, b.Name)
+}
+
+// GetOpenSpans returns the spans that were begun but not ended yet, sorted by start time. It returns nil unless span
+// tracking is enabled, see [RawLogSpanTracking].
+func GetOpenSpans(ctx context.Context) []*OpenSpan {
+	if bL, ok := getRootRawLog(ctx).(*backgroundLogImpl); ok {
+		return bL.tracker.getOpenSpans()
+	}
+
+	return nil
+}
+
+// ReportOpenSpans emits a warning for each span that was begun but not ended yet, and returns them. It is typically
+// called on shutdown to detect leaked spans. It does nothing unless span tracking is enabled, see
+// [RawLogSpanTracking].
+func ReportOpenSpans(ctx context.Context) []*OpenSpan {
+	openSpans := GetOpenSpans(ctx)
+
+	for _, openSpan := range openSpans {
+		getRootRawLog(ctx).EmitWarning(ctx,
+			errorz.Errorf("span '%v' begun at '%v' was not ended", openSpan.Name, openSpan.Location))
+	}
+
+	return openSpans
+}
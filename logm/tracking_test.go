@@ -0,0 +1,118 @@
+package logm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm"
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type TrackingSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestTrackingSuite(t *testing.T) {
+	fixturez.RunSuite(t, &TrackingSuite{})
+}
+
+func (s *TrackingSuite) newContext(ctx context.Context, g *WithT, options ...logm.RawLogOption) (context.Context, *tlogm.MockSender, func()) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+
+	return logm.NewSingletonInjector(logm.NewRawLogFromClient(client, options...))(ctx), sender, client.Close
+}
+
+func (s *TrackingSuite) TestDoubleEnd(ctx context.Context, g *WithT) {
+	_, end := logm.MustGet(ctx).Begin("span")
+	end()
+	end()
+
+	g.Expect(s.LOG.GetMock()).To(tlogm.HaveSpan("span",
+		tlogm.HaveEvent("warning", "span 'span' ended more than once")))
+	g.Expect(s.LOG.GetMock().GetTree().GetSpans()).To(HaveLen(1))
+}
+
+func (s *TrackingSuite) TestOpenSpans(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g, logm.RawLogSpanTracking(true, false))
+	defer closer()
+
+	g.Expect(logm.GetOpenSpans(ctx)).To(BeEmpty())
+
+	ctx1, end1 := logm.MustGet(ctx).Begin("s1")
+	s.CLK.GetMock().Add(time.Second)
+	_, end2 := logm.MustGet(ctx1).Begin("s2")
+
+	g.Expect(logm.GetOpenSpans(ctx1)).To(HaveExactElements(
+		PointTo(MatchAllFields(Fields{
+			"Name":      Equal("s1"),
+			"TraceID":   Equal(logm.MustGet(ctx1).GetCurrentTraceLink().TraceID),
+			"SpanID":    Equal(logm.MustGet(ctx1).GetCurrentTraceLink().SpanID),
+			"Location":  ContainSubstring("tracking_test.go:"),
+			"StartTime": Equal(clkm.MustGet(ctx).Now().Add(-time.Second)),
+		})),
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Name": Equal("s2"),
+		}))))
+
+	end1()
+	g.Expect(logm.GetOpenSpans(ctx)).To(HaveExactElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Name": Equal("s2"),
+		}))))
+
+	g.Expect(logm.ReportOpenSpans(ctx)).To(HaveLen(1))
+	g.Expect(sender).To(tlogm.HaveEvent("warning", MatchRegexp(`^span 's2' begun at '.*tracking_test\.go:\d+\)' was not ended$`)))
+
+	end2()
+	g.Expect(logm.GetOpenSpans(ctx)).To(BeEmpty())
+}
+
+func (s *TrackingSuite) TestDisabled(ctx context.Context, g *WithT) {
+	ctx, _, closer := s.newContext(ctx, g)
+	defer closer()
+
+	_, end := logm.MustGet(ctx).Begin("span")
+	defer end()
+
+	g.Expect(logm.GetOpenSpans(ctx)).To(BeNil())
+	g.Expect(logm.ReportOpenSpans(ctx)).To(BeNil())
+}
+
+func (s *TrackingSuite) TestAutoCloseChildren(ctx context.Context, g *WithT) {
+	ctx, sender, closer := s.newContext(ctx, g, logm.RawLogSpanTracking(true, true))
+	defer closer()
+
+	ctx1, end1 := logm.MustGet(ctx).Begin("parent")
+	ctx2, end2 := logm.MustGet(ctx1).Begin("child")
+	logm.MustGet(ctx2).Begin("grandchild")
+
+	end1()
+	end2()
+
+	g.Expect(logm.GetOpenSpans(ctx)).To(BeEmpty())
+
+	g.Expect(sender).To(tlogm.HaveSpan("parent",
+		HaveField("Fields", Not(HaveKey("span.truncated"))),
+		tlogm.HaveChild("child",
+			HaveField("Fields", HaveKeyWithValue("span.truncated", true)),
+			tlogm.HaveEvent("warning", "span 'child' ended more than once"),
+			tlogm.HaveChild("grandchild",
+				HaveField("Fields", HaveKeyWithValue("span.truncated", true))))))
+}
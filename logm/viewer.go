@@ -0,0 +1,445 @@
+package logm
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/cfgm"
+)
+
+// Default values for [TraceViewerConfig].
+const (
+	DefaultTraceViewerMaxTraces        = 100
+	DefaultTraceViewerMaxPendingTraces = 1000
+)
+
+const (
+	traceViewerResponsesLen = 1024
+)
+
+var (
+	_ transmission.Sender = (*TraceViewer)(nil)
+	_ http.Handler        = (*TraceViewer)(nil)
+)
+
+var (
+	traceViewerTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Traces</title>
+<style>
+body { font-family: monospace; margin: 2em; }
+summary { cursor: pointer; padding: 0.25em 0; }
+.error { color: #c00; }
+pre { margin: 0.5em 0 1em 1.5em; }
+</style>
+</head>
+<body>
+<h1>Traces</h1>
+{{- range . }}
+<details>
+<summary{{ if .HasError }} class="error"{{ end }}>{{ .Timestamp.Format "15:04:05.000" }} {{ .Name }} ({{ printf "%.2f" .DurationMS }}ms){{ if .HasError }} [error]{{ end }}{{ if .IsIncomplete }} [incomplete]{{ end }}</summary>
+<pre>{{ .Tree }}</pre>
+</details>
+{{- else }}
+<p>No traces yet.</p>
+{{- end }}
+</body>
+</html>
+`))
+)
+
+// MustNewDefaultTraceViewer initializes a default [*TraceViewer] using the [LogConfigMixin] from context. It returns
+// nil if the trace viewer is not enabled.
+func MustNewDefaultTraceViewer(ctx context.Context) *TraceViewer {
+	logCfg := cfgm.MustGet[LogConfigMixin](ctx).GetLogConfig()
+
+	if logCfg.TraceViewer {
+		addr := logCfg.TraceViewerAddr
+
+		if addr == cfgm.DisabledValue {
+			addr = ""
+		}
+
+		return NewTraceViewer(&TraceViewerConfig{
+			Output: os.Stderr,
+			Addr:   addr,
+		})
+	}
+
+	return nil
+}
+
+// TraceViewerConfig describes the configuration for a [*TraceViewer].
+type TraceViewerConfig struct {
+	// Output is where completed traces are printed as trees. If nil, traces are not printed.
+	Output io.Writer
+	// Addr is the address of the local HTTP server serving completed traces. If empty, no server is started.
+	Addr string
+	// MaxTraces is the number of completed traces retained for the HTTP page.
+	MaxTraces int
+	// MaxPendingTraces is the number of traces assembled at the same time, past which the oldest one is rendered as
+	// incomplete. Traces whose root span is never received (or events received after it) would pile up otherwise.
+	MaxPendingTraces int
+}
+
+// ViewedTrace describes a trace assembled by a [*TraceViewer].
+type ViewedTrace struct {
+	TraceID      string
+	Name         string
+	Timestamp    time.Time
+	DurationMS   float64
+	HasError     bool
+	IsIncomplete bool
+	Tree         string
+}
+
+// TraceViewer is a [transmission.Sender] meant for local development. It assembles events by trace, and renders each
+// completed trace as an indented tree with durations, errors and span events. Completed traces are printed to the
+// configured output and served as an HTML page by a local HTTP server. Events that don't belong to a trace are ignored.
+type TraceViewer struct {
+	cfg     *TraceViewerConfig
+	m       *sync.Mutex
+	pending map[string]*traceViewerPending
+	seq     uint64
+	traces  []*ViewedTrace
+	l       net.Listener
+	srv     *http.Server
+	c       chan transmission.Response
+}
+
+// NewTraceViewer initializes a new [*TraceViewer].
+func NewTraceViewer(cfg *TraceViewerConfig) *TraceViewer {
+	cfg = &TraceViewerConfig{
+		Output:           cfg.Output,
+		Addr:             cfg.Addr,
+		MaxTraces:        cfg.MaxTraces,
+		MaxPendingTraces: cfg.MaxPendingTraces,
+	}
+
+	if cfg.MaxTraces <= 0 {
+		cfg.MaxTraces = DefaultTraceViewerMaxTraces
+	}
+
+	if cfg.MaxPendingTraces <= 0 {
+		cfg.MaxPendingTraces = DefaultTraceViewerMaxPendingTraces
+	}
+
+	return &TraceViewer{
+		cfg:     cfg,
+		m:       &sync.Mutex{},
+		pending: make(map[string]*traceViewerPending),
+		traces:  make([]*ViewedTrace, 0),
+		c:       make(chan transmission.Response, traceViewerResponsesLen),
+	}
+}
+
+// GetAddr returns the address the HTTP server is listening on, or an empty string if the server is not running.
+func (v *TraceViewer) GetAddr() string {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	if v.l == nil {
+		return ""
+	}
+
+	return v.l.Addr().String()
+}
+
+// GetTraces returns the retained traces, most recent first.
+func (v *TraceViewer) GetTraces() []*ViewedTrace {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	traces := slices.Clone(v.traces)
+	slices.Reverse(traces)
+	return traces
+}
+
+// ServeHTTP implements the [http.Handler] interface.
+func (v *TraceViewer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := traceViewerTemplate.Execute(w, v.GetTraces()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Add implements the [transmission.Sender] interface.
+func (v *TraceViewer) Add(e *transmission.Event) {
+	if e == nil {
+		return
+	}
+
+	if traceID, ok := e.Data["trace.trace_id"].(string); ok && traceID != "" {
+		v.add(traceID, &traceViewerEvent{
+			timestamp: e.Timestamp,
+			data:      e.Data,
+		})
+	}
+
+	v.SendResponse(transmission.Response{
+		Metadata: e.Metadata,
+	})
+}
+
+func (v *TraceViewer) add(traceID string, e *traceViewerEvent) {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	pending, ok := v.pending[traceID]
+	if !ok {
+		v.maybeEvictPending()
+		v.seq++
+		pending = &traceViewerPending{seq: v.seq}
+		v.pending[traceID] = pending
+	}
+
+	pending.events = append(pending.events, e)
+
+	if e.isRootSpan() {
+		v.complete(traceID, false)
+	}
+}
+
+// maybeEvictPending renders the oldest pending trace as incomplete, if there is no room for a new one.
+func (v *TraceViewer) maybeEvictPending() {
+	if len(v.pending) < v.cfg.MaxPendingTraces {
+		return
+	}
+
+	oldestTraceID := ""
+	oldestSeq := uint64(0)
+
+	for traceID, pending := range v.pending {
+		if oldestTraceID == "" || pending.seq < oldestSeq {
+			oldestTraceID, oldestSeq = traceID, pending.seq
+		}
+	}
+
+	v.complete(oldestTraceID, true)
+}
+
+func (v *TraceViewer) complete(traceID string, isIncomplete bool) {
+	pending := v.pending[traceID]
+	delete(v.pending, traceID)
+
+	trace := newViewedTrace(traceID, pending.events, isIncomplete)
+	v.traces = append(v.traces, trace)
+
+	if len(v.traces) > v.cfg.MaxTraces {
+		v.traces = slices.Delete(v.traces, 0, len(v.traces)-v.cfg.MaxTraces)
+	}
+
+	if v.cfg.Output != nil {
+		_, _ = fmt.Fprintf(v.cfg.Output, "trace %v\n%v\n", trace.TraceID, trace.Tree)
+	}
+}
+
+// Start implements the [transmission.Sender] interface.
+func (v *TraceViewer) Start() error {
+	if v.cfg.Addr == "" {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", v.cfg.Addr)
+	if err != nil {
+		return errorz.Wrap(err)
+	}
+
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	v.l = l
+	v.srv = &http.Server{
+		Handler:           v,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		_ = v.srv.Serve(l)
+	}()
+
+	return nil
+}
+
+// Stop implements the [transmission.Sender] interface. Traces still pending are rendered as incomplete.
+func (v *TraceViewer) Stop() error {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	traceIDs := make([]string, 0, len(v.pending))
+	for traceID := range v.pending {
+		traceIDs = append(traceIDs, traceID)
+	}
+	slices.Sort(traceIDs)
+
+	for _, traceID := range traceIDs {
+		v.complete(traceID, true)
+	}
+
+	if v.srv != nil {
+		err := v.srv.Close()
+		v.srv = nil
+		v.l = nil
+		return errorz.MaybeWrap(err)
+	}
+
+	return nil
+}
+
+// Flush implements the [transmission.Sender] interface.
+func (v *TraceViewer) Flush() error {
+	return nil
+}
+
+// TxResponses implements the [transmission.Sender] interface.
+func (v *TraceViewer) TxResponses() chan transmission.Response {
+	return v.c
+}
+
+// SendResponse implements the [transmission.Sender] interface.
+func (v *TraceViewer) SendResponse(response transmission.Response) bool {
+	select {
+	case v.c <- response:
+		return false
+	default:
+		return true
+	}
+}
+
+type traceViewerPending struct {
+	seq    uint64
+	events []*traceViewerEvent
+}
+
+type traceViewerEvent struct {
+	timestamp time.Time
+	data      map[string]any
+	children  []*traceViewerEvent
+}
+
+func (e *traceViewerEvent) getString(k string) string {
+	v, _ := e.data[k].(string)
+	return v
+}
+
+func (e *traceViewerEvent) isSpan() bool {
+	return e.getString("trace.span_id") != ""
+}
+
+func (e *traceViewerEvent) isRootSpan() bool {
+	return e.isSpan() && e.getString("trace.parent_id") == ""
+}
+
+func (e *traceViewerEvent) hasError() bool {
+	v, ok := e.data["error"]
+	return ok && v != false
+}
+
+func (e *traceViewerEvent) getDurationMS() float64 {
+	v, _ := e.data["duration_ms"].(float64)
+	return v
+}
+
+func (e *traceViewerEvent) getLabel(startTime time.Time) string {
+	offset := fmt.Sprintf("[+%.2fms]", float64(e.timestamp.Sub(startTime))/float64(time.Millisecond))
+	name := e.getString("name")
+
+	switch {
+	case e.isSpan():
+		label := fmt.Sprintf("%v %v (%.2fms)", offset, name, e.getDurationMS())
+		if e.hasError() {
+			label += " [error]"
+		}
+		return label
+	case e.getString("meta.annotation_type") == "link":
+		return fmt.Sprintf("%v link: %v/%v", offset, e.getString("trace.link.trace_id"), e.getString("trace.link.span_id"))
+	case name == "error" || name == "warning":
+		return fmt.Sprintf("%v %v: %v: %v", offset, name, e.getString(name), e.getString(name+".message"))
+	default:
+		return fmt.Sprintf("%v %v: %v", offset, name, e.getString(name+".message"))
+	}
+}
+
+func newViewedTrace(traceID string, events []*traceViewerEvent, isIncomplete bool) *ViewedTrace {
+	spans := make(map[string]*traceViewerEvent)
+	roots := make([]*traceViewerEvent, 0)
+
+	for _, e := range events {
+		if e.isSpan() {
+			spans[e.getString("trace.span_id")] = e
+		}
+	}
+
+	for _, e := range events {
+		if parent, ok := spans[e.getString("trace.parent_id")]; ok && parent != e {
+			parent.children = append(parent.children, e)
+		} else {
+			roots = append(roots, e)
+		}
+	}
+
+	sortTraceViewerEvents(roots)
+
+	trace := &ViewedTrace{
+		TraceID:      traceID,
+		IsIncomplete: isIncomplete,
+	}
+
+	if len(roots) > 0 {
+		trace.Name = roots[0].getString("name")
+		trace.Timestamp = roots[0].timestamp
+		trace.DurationMS = roots[0].getDurationMS()
+	}
+
+	for _, e := range events {
+		if e.hasError() {
+			trace.HasError = true
+			break
+		}
+	}
+
+	sb := &strings.Builder{}
+	for _, root := range roots {
+		renderTraceViewerEvent(sb, root, trace.Timestamp, "", "")
+	}
+
+	trace.Tree = strings.TrimSuffix(sb.String(), "\n")
+	return trace
+}
+
+func sortTraceViewerEvents(events []*traceViewerEvent) {
+	slices.SortStableFunc(events, func(a, b *traceViewerEvent) int {
+		return a.timestamp.Compare(b.timestamp)
+	})
+}
+
+func renderTraceViewerEvent(sb *strings.Builder, e *traceViewerEvent, startTime time.Time, prefix, childPrefix string) {
+	sb.WriteString(prefix)
+	sb.WriteString(e.getLabel(startTime))
+	sb.WriteString("\n")
+
+	sortTraceViewerEvents(e.children)
+
+	for i, child := range e.children {
+		if i == len(e.children)-1 {
+			renderTraceViewerEvent(sb, child, startTime, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			renderTraceViewerEvent(sb, child, startTime, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package logm_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+)
+
+type ViewerSuite struct {
+	CLK *tclkm.MockHelper
+}
+
+func TestViewerSuite(t *testing.T) {
+	fixturez.RunSuite(t, &ViewerSuite{})
+}
+
+func (s *ViewerSuite) TestTraceViewer(ctx context.Context, g *WithT) {
+	buf := &bytes.Buffer{}
+
+	viewer := logm.NewTraceViewer(&logm.TraceViewerConfig{
+		Output: buf,
+		Addr:   "127.0.0.1:0",
+	})
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: viewer,
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	ctx = logm.NewSingletonInjector(logm.NewRawLogFromClient(client))(ctx)
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("root")
+		defer end()
+
+		logm.MustGet(ctx).EmitInfo("hello %v", logm.EmitA("world"))
+		s.CLK.GetMock().Add(time.Second)
+
+		func() {
+			ctx, end := logm.MustGet(ctx).Begin("child")
+			defer end()
+
+			s.CLK.GetMock().Add(time.Second)
+			logm.MustGet(ctx).EmitError(errorz.Errorf("test error"))
+		}()
+
+		s.CLK.GetMock().Add(time.Second)
+	}()
+
+	tree := "" +
+		"[+0.00ms] root (3000.00ms)\n" +
+		"├── [+0.00ms] info: hello world\n" +
+		"└── [+1000.00ms] child (1000.00ms) [error]\n" +
+		"    └── [+2000.00ms] error: generic: test error"
+
+	traces := viewer.GetTraces()
+	g.Expect(traces).To(HaveExactElements(
+		PointTo(MatchAllFields(Fields{
+			"TraceID":      Not(BeEmpty()),
+			"Name":         Equal("root"),
+			"Timestamp":    Equal(s.CLK.GetMock().Now().Add(-3 * time.Second)),
+			"DurationMS":   Equal(float64(3000)),
+			"HasError":     BeTrue(),
+			"IsIncomplete": BeFalse(),
+			"Tree":         Equal(tree),
+		}))))
+	g.Expect(buf.String()).To(Equal(fmt.Sprintf("trace %v\n%v\n", traces[0].TraceID, tree)))
+
+	resp, err := http.Get(fmt.Sprintf("http://%v/", viewer.GetAddr()))
+	g.Expect(err).To(Succeed())
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	g.Expect(err).To(Succeed())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(string(body)).To(ContainSubstring("root (3000.00ms) [error]"))
+	g.Expect(string(body)).To(ContainSubstring("error: generic: test error</pre>"))
+}
+
+func (s *ViewerSuite) TestTraceViewer_Incomplete(ctx context.Context, g *WithT) {
+	viewer := logm.NewTraceViewer(&logm.TraceViewerConfig{MaxTraces: 1})
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: viewer,
+	})
+	g.Expect(err).To(Succeed())
+
+	ctx = logm.NewSingletonInjector(logm.NewRawLogFromClient(client))(ctx)
+	logm.MustGet(ctx).EmitInfo("background")
+
+	for _, name := range []string{"first", "second"} {
+		_, end := logm.MustGet(ctx).Begin(name)
+		end()
+	}
+
+	ctx, _ = logm.MustGet(ctx).Begin("root")
+	_, end := logm.MustGet(ctx).Begin("child")
+	end()
+
+	g.Expect(viewer.GetTraces()).To(HaveExactElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Name": Equal("second"),
+		}))))
+
+	client.Close()
+
+	g.Expect(viewer.GetTraces()).To(HaveExactElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"Name":         Equal("child"),
+			"IsIncomplete": BeTrue(),
+			"Tree":         Equal("[+0.00ms] child (0.00ms)"),
+		}))))
+	g.Expect(viewer.GetAddr()).To(BeEmpty())
+}
+
+func (*ViewerSuite) TestTraceViewer_MaxPendingTraces(g *WithT) {
+	viewer := logm.NewTraceViewer(&logm.TraceViewerConfig{MaxPendingTraces: 1})
+
+	newSpan := func(traceID, spanID, name string) *transmission.Event {
+		return &transmission.Event{
+			Data: map[string]any{
+				"name":            name,
+				"duration_ms":     1.0,
+				"trace.trace_id":  traceID,
+				"trace.span_id":   spanID,
+				"trace.parent_id": "missing-root",
+			},
+		}
+	}
+
+	// The root spans are never received, so the oldest trace is rendered as incomplete to make room for the new one.
+	viewer.Add(newSpan("t1", "s1", "first"))
+	viewer.Add(newSpan("t1", "s2", "second"))
+	g.Expect(viewer.GetTraces()).To(BeEmpty())
+
+	viewer.Add(newSpan("t2", "s3", "third"))
+	g.Expect(viewer.GetTraces()).To(HaveExactElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"TraceID":      Equal("t1"),
+			"Name":         Equal("first"),
+			"IsIncomplete": BeTrue(),
+			"Tree":         Equal("[+0.00ms] first (1.00ms)\n[+0.00ms] second (1.00ms)"),
+		}))))
+
+	g.Expect(viewer.Stop()).To(Succeed())
+	g.Expect(viewer.GetTraces()).To(HaveExactElements(
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"TraceID":      Equal("t2"),
+			"IsIncomplete": BeTrue(),
+		})),
+		PointTo(MatchFields(IgnoreExtras, Fields{
+			"TraceID": Equal("t1"),
+		}))))
+}
@@ -0,0 +1,54 @@
+package logm
+
+import (
+	"context"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+// startWatchdog starts a timer that emits a warning on the span if it is still running after the given budget.
+func (sL *spanLogImpl) startWatchdog(ctx context.Context, budget time.Duration) {
+	if budget <= 0 {
+		return
+	}
+
+	sL.budget = budget
+	sL.watchdog = clkm.MustGet(ctx).AfterFunc(budget, func() {
+		sL.m.Lock()
+		isEnded := sL.isEnded
+		sL.m.Unlock()
+
+		if !isEnded {
+			sL.EmitWarning(ctx, errorz.Errorf("span '%v' exceeded its budget of %v", sL.name, budget))
+		}
+	})
+}
+
+// captureDeadline records the time remaining before the context deadline (if any) when the span begins. It is measured
+// on the clock in context, which is expected to be the one used to create the deadline (e.g. [clkm.Clock.WithTimeout]).
+func (sL *spanLogImpl) captureDeadline(ctx context.Context) {
+	if deadline, ok := ctx.Deadline(); ok {
+		sL.hasDeadline = true
+		sL.deadlineRemaining = clkm.MustGet(ctx).Until(deadline)
+	}
+}
+
+// addContextFields adds fields describing the context the span ran under, and its budget, to the span event.
+func (sL *spanLogImpl) addContextFields(ctx context.Context, af AddField) {
+	if sL.hasDeadline {
+		af.AddField("context.deadline_remaining_ms", float64(sL.deadlineRemaining)/float64(time.Millisecond))
+	}
+
+	if err := ctx.Err(); err != nil {
+		af.AddField("context.status", string(GetErrorSpanStatus(err)))
+	}
+
+	if sL.watchdog != nil {
+		sL.watchdog.Stop()
+		af.AddField("span.budget_ms", float64(sL.budget)/float64(time.Millisecond))
+		af.AddField("span.over_budget", clkm.MustGet(ctx).Now().Sub(sL.startTime) > sL.budget)
+	}
+}
@@ -0,0 +1,142 @@
+package logm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm"
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type WatchdogSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestWatchdogSuite(t *testing.T) {
+	fixturez.RunSuite(t, &WatchdogSuite{})
+}
+
+func (s *WatchdogSuite) TestContextFields(ctx context.Context, g *WithT) {
+	func() {
+		_, end := logm.MustGet(ctx).Begin("none")
+		defer end()
+	}()
+
+	func() {
+		ctx, cancel := clkm.MustGet(ctx).WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+
+		s.CLK.GetMock().Add(time.Second)
+
+		ctx, end := logm.MustGet(ctx).Begin("deadline")
+		defer end()
+	}()
+
+	func() {
+		ctx, cancel := clkm.MustGet(ctx).WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		ctx, end := logm.MustGet(ctx).Begin("deadline-exceeded")
+		defer end()
+
+		s.CLK.GetMock().Add(2 * time.Second)
+		g.Eventually(ctx.Done()).Should(BeClosed())
+	}()
+
+	func() {
+		ctx, cancel := context.WithCancel(ctx)
+
+		ctx, end := logm.MustGet(ctx).Begin("cancelled")
+		defer end()
+
+		cancel()
+	}()
+
+	tree := s.LOG.GetMock().GetTree()
+
+	g.Expect(tree).To(tlogm.HaveSpan("none", HaveField("Fields", And(
+		Not(HaveKey("context.deadline_remaining_ms")),
+		Not(HaveKey("context.status")),
+		Not(HaveKey("span.budget_ms"))))))
+
+	g.Expect(tree).To(tlogm.HaveSpan("deadline", HaveField("Fields", And(
+		HaveKeyWithValue("context.deadline_remaining_ms", float64(2000)),
+		Not(HaveKey("context.status"))))))
+
+	g.Expect(tree).To(tlogm.HaveSpan("deadline-exceeded", HaveField("Fields", And(
+		HaveKeyWithValue("context.deadline_remaining_ms", float64(1000)),
+		HaveKeyWithValue("context.status", string(logm.SpanStatusDeadlineExceeded))))))
+
+	g.Expect(tree).To(tlogm.HaveSpan("cancelled", HaveField("Fields", And(
+		Not(HaveKey("context.deadline_remaining_ms")),
+		HaveKeyWithValue("context.status", string(logm.SpanStatusCancelled))))))
+}
+
+func (s *WatchdogSuite) TestBudget(ctx context.Context, g *WithT) {
+	func() {
+		_, end := logm.MustGet(ctx).Begin("within-budget", logm.BeginBudget(2*time.Second))
+		defer end()
+
+		s.CLK.GetMock().Add(time.Second)
+	}()
+
+	func() {
+		_, end := logm.MustGet(ctx).Begin("over-budget", logm.BeginBudget(time.Second))
+		defer end()
+
+		s.CLK.GetMock().Add(2 * time.Second)
+
+		g.Eventually(func() any { return s.LOG.GetMock().GetTree() }).
+			Should(tlogm.HaveEvent("warning", "span 'over-budget' exceeded its budget of 1s"))
+	}()
+
+	tree := s.LOG.GetMock().GetTree()
+
+	g.Expect(tree).To(tlogm.HaveSpan("within-budget",
+		HaveField("Fields", And(
+			HaveKeyWithValue("span.budget_ms", float64(2000)),
+			HaveKeyWithValue("span.over_budget", false))),
+		Not(tlogm.HaveEvent("warning", nil))))
+
+	g.Expect(tree).To(tlogm.HaveSpan("over-budget",
+		HaveField("Fields", And(
+			HaveKeyWithValue("span.budget_ms", float64(1000)),
+			HaveKeyWithValue("span.over_budget", true))),
+		tlogm.HaveEvent("warning", "span 'over-budget' exceeded its budget of 1s")))
+}
+
+func (s *WatchdogSuite) TestDefaultBudget(ctx context.Context, g *WithT) {
+	sender := tlogm.NewMockSender()
+
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "test-honeycomb-api-key",
+		Dataset:      "test-dataset",
+		SampleRate:   1,
+		Transmission: sender,
+	})
+	g.Expect(err).To(Succeed())
+	defer client.Close()
+
+	ctx = logm.NewSingletonInjector(logm.NewRawLogFromClient(client, logm.RawLogSpanBudget(time.Second)))(ctx)
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("default")
+		defer end()
+
+		func() {
+			_, end := logm.MustGet(ctx).Begin("override", logm.BeginBudget(time.Minute))
+			defer end()
+		}()
+	}()
+
+	g.Expect(sender).To(tlogm.HaveSpan("default", HaveField("Fields", HaveKeyWithValue("span.budget_ms", float64(1000)))))
+	g.Expect(sender).To(tlogm.HaveSpan("override", HaveField("Fields", HaveKeyWithValue("span.budget_ms", float64(60000)))))
+}
@@ -0,0 +1,184 @@
+package logm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+// Default values for [WorkerConfig].
+const (
+	DefaultWorkerHeartbeatInterval = time.Minute
+)
+
+// WorkerConfig describes the configuration for [RunWorker].
+type WorkerConfig struct {
+	// Name is the name of the worker, used as span name for iterations.
+	Name string
+	// Interval is the delay between the end of an iteration and the start of the next one.
+	Interval time.Duration
+	// HeartbeatInterval is how often a heartbeat event is emitted while the worker is running.
+	HeartbeatInterval time.Duration
+	// BeginOptions are applied to the span of each iteration.
+	BeginOptions []BeginOption
+}
+
+// WorkerStats describes the state of a worker.
+type WorkerStats struct {
+	Iterations   int64
+	Failures     int64
+	Panics       int64
+	LastDuration time.Duration
+}
+
+type workerState struct {
+	m         *sync.Mutex
+	stats     WorkerStats
+	startTime time.Time
+}
+
+func (s *workerState) get() WorkerStats {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.stats
+}
+
+func (s *workerState) record(duration time.Duration, err error, panicked bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.stats.Iterations++
+	s.stats.LastDuration = duration
+
+	if err != nil {
+		s.stats.Failures++
+	}
+
+	if panicked {
+		s.stats.Panics++
+	}
+}
+
+// RunWorker runs f in a loop until the context is canceled, tracing each iteration as a new root trace. Errors are
+// emitted, and panics are recovered and emitted as errors, so that they don't stop the loop. A heartbeat event is
+// emitted periodically with the worker stats. RunWorker returns the final stats once the context is canceled.
+func RunWorker(ctx context.Context, cfg *WorkerConfig, f func(ctx context.Context) error) WorkerStats {
+	errorz.Assertf(cfg != nil && cfg.Name != "", "worker name is empty")
+
+	clk := clkm.MustGet(ctx)
+	heartbeatInterval := cfg.HeartbeatInterval
+
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = DefaultWorkerHeartbeatInterval
+	}
+
+	state := &workerState{
+		m:         &sync.Mutex{},
+		startTime: clk.Now(),
+	}
+
+	heartbeatStopC := make(chan struct{})
+	heartbeatDoneC := make(chan struct{})
+
+	go func() {
+		defer close(heartbeatDoneC)
+
+		ticker := clk.Ticker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-heartbeatStopC:
+				return
+			case <-ticker.C:
+				emitWorkerHeartbeat(ctx, cfg.Name, state)
+			}
+		}
+	}()
+
+	defer func() {
+		close(heartbeatStopC)
+		<-heartbeatDoneC
+	}()
+
+	for ctx.Err() == nil {
+		startTime := clk.Now()
+		panicked, err := runWorkerIteration(ctx, cfg, state.get().Iterations+1, f)
+
+		if isWorkerCanceled(ctx, err) {
+			break
+		}
+
+		state.record(clk.Now().Sub(startTime), err, panicked)
+
+		if cfg.Interval > 0 {
+			timer := clk.Timer(cfg.Interval)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}
+
+	return state.get()
+}
+
+func runWorkerIteration(ctx context.Context, cfg *WorkerConfig, iteration int64, f func(ctx context.Context) error) (panicked bool, outErr error) {
+	ctx, end := getRootRawLog(ctx).Begin(ctx, cfg.Name, append(
+		[]BeginOption{BeginM("worker.iteration", iteration)},
+		cfg.BeginOptions...)...)
+	defer end()
+
+	defer func() {
+		if outErr != nil && !isWorkerCanceled(ctx, outErr) {
+			if panicked {
+				MustGet(ctx).SetErrorMetadataKey("worker.panic", true)
+			}
+			maybeHandleError(ctx, outErr)
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			outErr = newPanicError(ctx, r)
+		}
+	}()
+
+	return false, errorz.MaybeWrap(f(withIsRecovering(ctx)))
+}
+
+func isWorkerCanceled(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err())
+}
+
+func emitWorkerHeartbeat(ctx context.Context, name string, state *workerState) {
+	stats := state.get()
+
+	getRootRawLog(ctx).EmitInfo(ctx, "worker heartbeat: %v",
+		EmitA(name),
+		EmitMetadata{
+			"name":             name,
+			"iterations":       stats.Iterations,
+			"failures":         stats.Failures,
+			"panics":           stats.Panics,
+			"last_duration_ms": float64(stats.LastDuration) / float64(time.Millisecond),
+			"uptime_ms":        float64(clkm.MustGet(ctx).Now().Sub(state.startTime)) / float64(time.Millisecond),
+		})
+}
+
+func getRootRawLog(ctx context.Context) RawLog {
+	switch rawLog := ctx.Value(logContextKey).(type) {
+	case *spanLogImpl:
+		return rawLog.bL
+	default:
+		return rawLog.(RawLog)
+	}
+}
@@ -0,0 +1,127 @@
+package logm_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type WorkerSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestWorkerSuite(t *testing.T) {
+	fixturez.RunSuite(t, &WorkerSuite{})
+}
+
+func (s *WorkerSuite) TestRunWorker(ctx context.Context, g *WithT) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ctx, end := logm.MustGet(ctx).Begin("parent")
+	defer end()
+
+	iteration := 0
+
+	stats := logm.RunWorker(ctx, &logm.WorkerConfig{Name: "worker"}, func(ctx context.Context) error {
+		iteration++
+
+		switch iteration {
+		case 2:
+			panic(errorz.Errorf("worker panic"))
+		case 3:
+			return errorz.Errorf("worker error")
+		case 4:
+			cancel()
+			return ctx.Err()
+		default:
+			return nil
+		}
+	})
+
+	g.Expect(stats).To(MatchFields(IgnoreExtras, Fields{
+		"Iterations": Equal(int64(3)),
+		"Failures":   Equal(int64(2)),
+		"Panics":     Equal(int64(1)),
+	}))
+
+	events := s.LOG.GetMock().GetEvents()
+
+	g.Expect(events).To(HaveLen(6))
+	g.Expect(events[0].Data).To(And(
+		HaveKeyWithValue("name", "worker"),
+		HaveKeyWithValue("scope.metadata.worker.iteration", int64(1)),
+		Not(HaveKey("trace.parent_id")),
+		Not(HaveKey("error"))))
+	g.Expect(events[1].Data).To(And(
+		HaveKeyWithValue("name", "error"),
+		HaveKeyWithValue("error.message", "worker panic")))
+	g.Expect(events[2].Data).To(And(
+		HaveKeyWithValue("name", "worker"),
+		HaveKeyWithValue("scope.metadata.worker.iteration", int64(2)),
+		HaveKeyWithValue("scope.metadata.worker.panic", true),
+		HaveKeyWithValue("error", true)))
+	g.Expect(events[3].Data).To(HaveKeyWithValue("error.message", "worker error"))
+	g.Expect(events[4].Data).To(And(
+		HaveKeyWithValue("scope.metadata.worker.iteration", int64(3)),
+		Not(HaveKey("scope.metadata.worker.panic")),
+		HaveKeyWithValue("error", true)))
+	g.Expect(events[5].Data).To(And(
+		HaveKeyWithValue("scope.metadata.worker.iteration", int64(4)),
+		Not(HaveKey("error"))))
+
+	for _, e := range events {
+		g.Expect(e.Data["trace.trace_id"]).ToNot(Equal(logm.MustGet(ctx).GetCurrentTraceLink().TraceID))
+	}
+}
+
+func (s *WorkerSuite) TestRunWorker_Heartbeat(ctx context.Context, g *WithT) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	releaseC := make(chan struct{})
+	done := &atomic.Bool{}
+
+	go func() {
+		defer done.Store(true)
+
+		logm.RunWorker(ctx, &logm.WorkerConfig{
+			Name:              "worker",
+			Interval:          time.Second,
+			HeartbeatInterval: 10 * time.Second,
+		}, func(ctx context.Context) error {
+			<-releaseC
+			return nil
+		})
+	}()
+
+	releaseC <- struct{}{}
+
+	// The heartbeat ticker is registered asynchronously, so keep advancing the clock until it fires. The second
+	// iteration blocks on releaseC, so the number of completed iterations stays at one.
+	g.Eventually(func() []*transmission.Event {
+		s.CLK.GetMock().Add(10 * time.Second)
+		return s.LOG.GetMock().GetEvents()
+	}, "1s", "10ms").Should(ContainElement(PointTo(MatchFields(IgnoreExtras, Fields{
+		"Data": And(
+			HaveKeyWithValue("info.message", "worker heartbeat: worker"),
+			HaveKeyWithValue("info.metadata.iterations", int64(1)),
+			HaveKeyWithValue("info.metadata.uptime_ms", BeNumerically(">=", float64(10000)))),
+	}))))
+
+	releaseC <- struct{}{}
+	cancel()
+	g.Eventually(done.Load, "1s", "10ms").Should(BeTrue())
+}
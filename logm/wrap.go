@@ -6,7 +6,8 @@ import (
 	"github.com/ibrt/golang-utils/errorz"
 )
 
-// Wrap0 traces a function that returns (error).
+// Wrap0 traces a function that returns (error). Errors set the span status and are emitted, except for context
+// cancellation and deadlines, which only set the span status (see [GetErrorSpanStatus]).
 func Wrap0(
 	ctx context.Context,
 	name string,
@@ -16,7 +17,7 @@ func Wrap0(
 	ctx, end := MustGet(ctx).Begin(name, options...)
 	defer end()
 
-	err := errorz.Catch0Ctx(ctx, func(ctx context.Context) error {
+	err := catch0Ctx(ctx, func(ctx context.Context) error {
 		return errorz.MaybeWrap(f(ctx))
 	})
 	maybeHandleError(ctx, err)
@@ -33,10 +34,11 @@ func Wrap0Panic(
 	ctx, end := MustGet(ctx).Begin(name, options...)
 	defer end()
 
-	err := errorz.Catch0Ctx(ctx, func(ctx context.Context) error {
+	err := catch0Ctx(ctx, func(ctx context.Context) error {
 		return errorz.MaybeWrap(f(ctx))
 	})
 	maybeHandleError(ctx, err)
+	maybeRepanic(ctx, err)
 	errorz.MaybeMustWrap(err)
 }
 
@@ -50,7 +52,7 @@ func Wrap1[T any](
 	ctx, end := MustGet(ctx).Begin(name, options...)
 	defer end()
 
-	out, err := errorz.Catch1Ctx(ctx, func(ctx context.Context) (T, error) {
+	out, err := catch1Ctx(ctx, func(ctx context.Context) (T, error) {
 		out, err := f(ctx)
 		return out, errorz.MaybeWrap(err)
 	})
@@ -68,11 +70,12 @@ func Wrap1Panic[T any](
 	ctx, end := MustGet(ctx).Begin(name, options...)
 	defer end()
 
-	out, err := errorz.Catch1Ctx(ctx, func(ctx context.Context) (T, error) {
+	out, err := catch1Ctx(ctx, func(ctx context.Context) (T, error) {
 		out, err := f(ctx)
 		return out, errorz.MaybeWrap(err)
 	})
 	maybeHandleError(ctx, err)
+	maybeRepanic(ctx, err)
 	errorz.MaybeMustWrap(err)
 	return out
 }
@@ -87,7 +90,7 @@ func Wrap2[T1 any, T2 any](
 	ctx, end := MustGet(ctx).Begin(name, options...)
 	defer end()
 
-	out1, out2, err := errorz.Catch2Ctx(ctx, func(ctx context.Context) (T1, T2, error) {
+	out1, out2, err := catch2Ctx(ctx, func(ctx context.Context) (T1, T2, error) {
 		out1, out2, err := f(ctx)
 		return out1, out2, errorz.MaybeWrap(err)
 	})
@@ -105,11 +108,12 @@ func Wrap2Panic[T1 any, T2 any](
 	ctx, end := MustGet(ctx).Begin(name, options...)
 	defer end()
 
-	out1, out2, err := errorz.Catch2Ctx(ctx, func(ctx context.Context) (T1, T2, error) {
+	out1, out2, err := catch2Ctx(ctx, func(ctx context.Context) (T1, T2, error) {
 		out1, out2, err := f(ctx)
 		return out1, out2, errorz.MaybeWrap(err)
 	})
 	maybeHandleError(ctx, err)
+	maybeRepanic(ctx, err)
 	errorz.MaybeMustWrap(err)
 	return out1, out2
 }
@@ -124,7 +128,7 @@ func Wrap3[T1 any, T2 any, T3 any](
 	ctx, end := MustGet(ctx).Begin(name, options...)
 	defer end()
 
-	out1, out2, out3, err := errorz.Catch3Ctx(ctx, func(ctx context.Context) (T1, T2, T3, error) {
+	out1, out2, out3, err := catch3Ctx(ctx, func(ctx context.Context) (T1, T2, T3, error) {
 		out1, out2, out3, err := f(ctx)
 		return out1, out2, out3, errorz.MaybeWrap(err)
 	})
@@ -142,17 +146,27 @@ func Wrap3Panic[T1 any, T2 any, T3 any](
 	ctx, end := MustGet(ctx).Begin(name, options...)
 	defer end()
 
-	out1, out2, out3, err := errorz.Catch3Ctx(ctx, func(ctx context.Context) (T1, T2, T3, error) {
+	out1, out2, out3, err := catch3Ctx(ctx, func(ctx context.Context) (T1, T2, T3, error) {
 		out1, out2, out3, err := f(ctx)
 		return out1, out2, out3, errorz.MaybeWrap(err)
 	})
 	maybeHandleError(ctx, err)
+	maybeRepanic(ctx, err)
 	errorz.MaybeMustWrap(err)
 	return out1, out2, out3
 }
 
+// maybeHandleError records the error on the span. Context cancellation and deadlines are expected outcomes (e.g. a
+// client going away), so they only set the span status, without setting the error flag or emitting an error.
 func maybeHandleError(ctx context.Context, err error) {
 	if err != nil {
+		status := GetErrorSpanStatus(err)
+		MustGet(ctx).SetStatus(status, err.Error())
+
+		if status != SpanStatusError {
+			return
+		}
+
 		if getIsEmitted(err) {
 			MustGet(ctx).SetErrorFlag()
 		} else {
@@ -1008,3 +1008,37 @@ func (s *WrapSuite) TestWrap3Panic_Error(ctx context.Context, g *WithT) {
 			})),
 		))
 }
+
+func (s *WrapSuite) TestWrap_Tree(ctx context.Context, g *WithT) {
+	g.Expect(logm.Wrap0(ctx, "parent",
+		func(ctx context.Context) error {
+			logm.MustGet(ctx).EmitWarning(errorz.Errorf("test warning"))
+			logm.MustGet(ctx).EmitInfo("test info", logm.EmitMetadata{"k": "v"})
+
+			_, err := logm.Wrap1(ctx, "child",
+				func(ctx context.Context) (int, error) {
+					s.Clock.GetMock().Add(time.Second)
+					return 0, errorz.Errorf("test error")
+				},
+				logm.BeginM("kc", "vc"))
+			return err
+		})).
+		To(MatchError("test error"))
+
+	g.Expect(s.Log.GetMock()).To(tlogm.HaveSpan("parent",
+		tlogm.HaveChild("child",
+			tlogm.HaveMetadata("kc", "vc"),
+			tlogm.HaveError("generic"),
+			tlogm.HaveEvent("error", "test error")),
+		tlogm.HaveEvent("warning", "test warning"),
+		tlogm.HaveEvent("info", "test info", tlogm.HaveMetadata("k", "v")),
+		Not(tlogm.HaveError("generic"))))
+
+	g.Expect(s.Log.GetMock()).ToNot(tlogm.HaveSpan("child", tlogm.HaveChild("parent")))
+	g.Expect(s.Log.GetMock()).To(tlogm.HaveEvent("error", ContainSubstring("error")))
+	g.Expect(s.Log.GetMock().GetTree().FindSpan("child").DurationMS).To(Equal(float64(1000)))
+	g.Expect(s.Log.GetMock().GetTree().MustMarshalSnapshot()).ToNot(Or(
+		ContainSubstring("duration_ms"),
+		ContainSubstring("trace."),
+		ContainSubstring("location")))
+}
@@ -8,19 +8,9 @@ import (
 )
 
 var (
-	_ PG      = (*adapterPGImpl)(nil)
-	_ pgx.Row = (*errRow)(nil)
+	_ PG = (*adapterPGImpl)(nil)
 )
 
-type errRow struct {
-	err error
-}
-
-// Scan implements the [pgx.Row] interface.
-func (r *errRow) Scan(_ ...any) error {
-	return r.err
-}
-
 type adapterPGImpl struct {
 	ctx context.Context
 	pg  RawPG
@@ -0,0 +1,86 @@
+package pgm
+
+import (
+	"github.com/ibrt/golang-modules/logm"
+)
+
+// Attribute keys set by pgm on its spans, in addition to [logm.AttrDBStatement], [logm.AttrDBOperation],
+// [logm.AttrDBRowsAffected] and [logm.AttrDBDuration]. They are registered in [logm.DefaultAttributeRegistry].
+const (
+	AttrDBArgsCount         logm.IntKey      = "db.args_count"
+	AttrDBBatchSize         logm.IntKey      = "db.batch.size"
+	AttrDBBatchDuration     logm.DurationKey = "db.batch.duration_ms"
+	AttrDBConnectDuration   logm.DurationKey = "db.connect.duration_ms"
+	AttrDBAcquireDuration   logm.DurationKey = "db.acquire.duration_ms"
+	AttrDBPoolAcquiredConns logm.IntKey      = "db.pool.acquired_conns"
+	AttrDBPoolIdleConns     logm.IntKey      = "db.pool.idle_conns"
+	AttrDBPoolTotalConns    logm.IntKey      = "db.pool.total_conns"
+	AttrDBPoolMaxConns      logm.IntKey      = "db.pool.max_conns"
+	AttrDBRoute             logm.StringKey   = "db.route"
+	AttrDBRouteReplica      logm.IntKey      = "db.route.replica"
+	AttrDBRouteReason       logm.StringKey   = "db.route.reason"
+	AttrDBRetryAttempt      logm.IntKey      = "db.retry.attempt"
+	AttrDBRetryReason       logm.StringKey   = "db.retry.reason"
+	AttrDBRetryBackoff      logm.DurationKey = "db.retry.backoff_ms"
+	AttrDBSavepointDepth    logm.IntKey      = "db.savepoint_depth"
+)
+
+var (
+	queryErrorAttrKeys   = newPGErrorAttrKeys("db")
+	batchErrorAttrKeys   = newPGErrorAttrKeys("db.batch")
+	connectErrorAttrKeys = newPGErrorAttrKeys("db.connect")
+	acquireErrorAttrKeys = newPGErrorAttrKeys("db.acquire")
+)
+
+var (
+	_ = logm.DefaultAttributeRegistry.
+		MustRegister(AttrDBArgsCount.Spec("Number of statement arguments")).
+		MustRegister(AttrDBBatchSize.Spec("Number of statements in the batch")).
+		MustRegister(AttrDBBatchDuration.Spec("Database batch duration")).
+		MustRegister(AttrDBConnectDuration.Spec("Database connection duration")).
+		MustRegister(AttrDBAcquireDuration.Spec("Database connection acquisition duration")).
+		MustRegister(AttrDBPoolAcquiredConns.Spec("Number of acquired pool connections")).
+		MustRegister(AttrDBPoolIdleConns.Spec("Number of idle pool connections")).
+		MustRegister(AttrDBPoolTotalConns.Spec("Number of pool connections")).
+		MustRegister(AttrDBPoolMaxConns.Spec("Maximum number of pool connections")).
+		MustRegister(AttrDBRoute.Spec("Database the statement was routed to (primary or replica)")).
+		MustRegister(AttrDBRouteReplica.Spec("Index of the replica the statement was routed to")).
+		MustRegister(AttrDBRouteReason.Spec("Reason a read was routed to the primary")).
+		MustRegister(AttrDBRetryAttempt.Spec("Transaction attempt number")).
+		MustRegister(AttrDBRetryReason.Spec("Reason the transaction was retried")).
+		MustRegister(AttrDBRetryBackoff.Spec("Backoff before the transaction was retried")).
+		MustRegister(AttrDBSavepointDepth.Spec("Savepoint nesting depth")).
+		MustRegister(queryErrorAttrKeys.code.Spec("PostgreSQL error code")).
+		MustRegister(queryErrorAttrKeys.severity.Spec("PostgreSQL error severity")).
+		MustRegister(queryErrorAttrKeys.constraint.Spec("PostgreSQL error constraint")).
+		MustRegister(queryErrorAttrKeys.message.Spec("Database error message")).
+		MustRegister(batchErrorAttrKeys.code.Spec("PostgreSQL batch error code")).
+		MustRegister(batchErrorAttrKeys.severity.Spec("PostgreSQL batch error severity")).
+		MustRegister(batchErrorAttrKeys.constraint.Spec("PostgreSQL batch error constraint")).
+		MustRegister(batchErrorAttrKeys.message.Spec("Database batch error message")).
+		MustRegister(connectErrorAttrKeys.code.Spec("PostgreSQL connection error code")).
+		MustRegister(connectErrorAttrKeys.severity.Spec("PostgreSQL connection error severity")).
+		MustRegister(connectErrorAttrKeys.constraint.Spec("PostgreSQL connection error constraint")).
+		MustRegister(connectErrorAttrKeys.message.Spec("Database connection error message")).
+		MustRegister(acquireErrorAttrKeys.code.Spec("PostgreSQL connection acquisition error code")).
+		MustRegister(acquireErrorAttrKeys.severity.Spec("PostgreSQL connection acquisition error severity")).
+		MustRegister(acquireErrorAttrKeys.constraint.Spec("PostgreSQL connection acquisition error constraint")).
+		MustRegister(acquireErrorAttrKeys.message.Spec("Database connection acquisition error message"))
+)
+
+// pgErrorAttrKeys describes the attribute keys used to record an error at a given stage (query, batch, etc.).
+type pgErrorAttrKeys struct {
+	code       logm.StringKey
+	severity   logm.StringKey
+	constraint logm.StringKey
+	message    logm.StringKey
+}
+
+func newPGErrorAttrKeys(prefix string) *pgErrorAttrKeys {
+	return &pgErrorAttrKeys{
+		code:       logm.StringKey(prefix + ".error.code"),
+		severity:   logm.StringKey(prefix + ".error.severity"),
+		constraint: logm.StringKey(prefix + ".error.constraint"),
+		message:    logm.StringKey(prefix + ".error.message"),
+	}
+}
@@ -17,7 +17,19 @@ var (
 )
 
 type backgroundPGImpl struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	replicas *replicaSet
+	o        *pgOptions
+}
+
+func newBackgroundPG(pool *pgxpool.Pool, options ...PGOption) *backgroundPGImpl {
+	o := newPGOptions(options...)
+
+	return &backgroundPGImpl{
+		pool:     pool,
+		replicas: newReplicaSet(o.replicas, o.replicaMaxLag),
+		o:        o,
+	}
 }
 
 // Exec implements the RawPG interface.
@@ -26,37 +38,34 @@ func (b *backgroundPGImpl) Exec(ctx context.Context, name, query string, args ..
 		ctx,
 		fmt.Sprintf("pgm.Exec.[%v]", name),
 		func(ctx context.Context) (pgconn.CommandTag, error) {
-			tag, err := b.pool.Exec(ctx, query, args...)
-			return tag, errorz.MaybeWrap(err)
+			tag, err := b.getPool(ctx, false).Exec(ctx, query, args...)
+			if err != nil {
+				return tag, errorz.Wrap(err)
+			}
+
+			maybeMarkWrite(ctx)
+			return tag, nil
 		})
 }
 
-// Query implements the RawPG interface.
+// Query implements the RawPG interface. The span ends when the rows are closed.
 func (b *backgroundPGImpl) Query(ctx context.Context, name, query string, args ...any) (pgx.Rows, error) {
-	return logm.Wrap1(
+	return traceQuery(
 		ctx,
-		fmt.Sprintf("pgm.Query.[%v]", name),
+		name,
 		func(ctx context.Context) (pgx.Rows, error) {
-			rows, err := b.pool.Query(ctx, query, args...)
-			return rows, errorz.MaybeWrap(err)
+			return b.getPool(ctx, isReadReplicaContext(ctx)).Query(ctx, query, args...)
 		})
 }
 
-// QueryRow implements the RawPG interface.
+// QueryRow implements the RawPG interface. The span ends when the row is scanned.
 func (b *backgroundPGImpl) QueryRow(ctx context.Context, name, query string, args ...any) pgx.Row {
-	row, err := logm.Wrap1(
+	return traceQueryRow(
 		ctx,
-		fmt.Sprintf("pgm.QueryRow.[%v]", name),
-		func(ctx context.Context) (pgx.Row, error) {
-			return b.pool.QueryRow(ctx, query, args...), nil
+		name,
+		func(ctx context.Context) pgx.Row {
+			return b.getPool(ctx, isReadReplicaContext(ctx)).QueryRow(ctx, query, args...)
 		})
-	if err != nil {
-		return &errRow{
-			err: err,
-		}
-	}
-
-	return row
 }
 
 // Begin implements the RawPG interface.
@@ -65,7 +74,10 @@ func (b *backgroundPGImpl) Begin(ctx context.Context, name string, options ...Be
 		ctx,
 		fmt.Sprintf("pgm.Begin.[%v]", name),
 		func(ctx context.Context) (context.Context, func(), func() error, error) {
-			tx, err := b.pool.BeginTx(ctx, newBeginOptions(options...).ToTxOptions())
+			txOptions := newBeginOptions(options...).ToTxOptions()
+			isReadOnly := txOptions.AccessMode == pgx.ReadOnly
+
+			tx, err := b.getTxPool(ctx, txOptions).BeginTx(ctx, txOptions)
 			if err != nil {
 				return nil, nil, nil, errorz.Wrap(err)
 			}
@@ -76,6 +88,55 @@ func (b *backgroundPGImpl) Begin(ctx context.Context, name string, options ...Be
 			}
 
 			ctx = NewSingletonInjector(tP)(ctx)
-			return ctx, func() { tP.end(ctx) }, func() error { return tP.commit(ctx) }, nil
+
+			return ctx, func() { tP.end(ctx) }, func() error {
+				if err := tP.commit(ctx); err != nil {
+					return errorz.Wrap(err)
+				}
+
+				if !isReadOnly {
+					maybeMarkWrite(ctx)
+				}
+
+				return nil
+			}, nil
 		})
 }
+
+// getTxPool returns the pool a transaction should be sent to. Hot standby replicas do not support the serializable
+// isolation level, so serializable transactions are always routed to the primary.
+func (b *backgroundPGImpl) getTxPool(ctx context.Context, txOptions pgx.TxOptions) *pgxpool.Pool {
+	isReadOnly := txOptions.AccessMode == pgx.ReadOnly
+
+	if isReadOnly && txOptions.IsoLevel == pgx.Serializable && b.replicas != nil {
+		logm.SetAttributes(ctx, AttrDBRouteReason.V(routeReasonSerializable))
+		return b.getPool(ctx, false)
+	}
+
+	return b.getPool(ctx, isReadOnly)
+}
+
+// getPool returns the pool a statement or transaction should be sent to, and records the routing decision on the
+// current span if replicas are configured.
+func (b *backgroundPGImpl) getPool(ctx context.Context, isRead bool) *pgxpool.Pool {
+	if b.replicas == nil {
+		return b.pool
+	}
+
+	if isRead {
+		reason := routeReasonReadYourWrites
+
+		if !isReadYourWritesSticky(ctx, b.o.readYourWritesWindow) {
+			var r *replica
+			if r, reason = b.replicas.pick(); r != nil {
+				logm.SetAttributes(ctx, AttrDBRoute.V("replica"), AttrDBRouteReplica.V(int64(r.index)))
+				return r.pool
+			}
+		}
+
+		logm.SetAttributes(ctx, AttrDBRouteReason.V(reason))
+	}
+
+	logm.SetAttributes(ctx, AttrDBRoute.V("primary"))
+	return b.pool
+}
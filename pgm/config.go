@@ -1,6 +1,9 @@
 package pgm
 
 import (
+	"strings"
+	"time"
+
 	"github.com/ibrt/golang-modules/cfgm"
 )
 
@@ -12,13 +15,21 @@ type PGConfigMixin interface {
 
 // PGConfig describes the module configuration.
 type PGConfig struct {
-	PostgresURL string `env:"PG_POSTGRES_URL,required" validate:"required,url"`
+	PostgresURL          string        `env:"PG_POSTGRES_URL,required" validate:"required,url"`
+	ReplicaURLs          []string      `env:"PG_REPLICA_URLS" validate:"dive,url"`
+	ReplicaMaxLag        time.Duration `env:"PG_REPLICA_MAX_LAG" validate:"min=0"`
+	ReplicaCheckInterval time.Duration `env:"PG_REPLICA_CHECK_INTERVAL" validate:"min=0"`
+	ReadYourWritesWindow time.Duration `env:"PG_READ_YOUR_WRITES_WINDOW" validate:"min=0"`
 }
 
 // ToEnv converts the config to an env map.
 func (c *PGConfig) ToEnv(prefix string) map[string]string {
 	return map[string]string{
-		prefix + "PG_POSTGRES_URL": c.PostgresURL,
+		prefix + "PG_POSTGRES_URL":            c.PostgresURL,
+		prefix + "PG_REPLICA_URLS":            strings.Join(c.ReplicaURLs, ","),
+		prefix + "PG_REPLICA_MAX_LAG":         c.ReplicaMaxLag.String(),
+		prefix + "PG_REPLICA_CHECK_INTERVAL":  c.ReplicaCheckInterval.String(),
+		prefix + "PG_READ_YOUR_WRITES_WINDOW": c.ReadYourWritesWindow.String(),
 	}
 }
 
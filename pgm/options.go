@@ -1,12 +1,16 @@
 package pgm
 
 import (
+	"time"
+
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	_ BeginOption = BeginIsoLevel("")
 	_ BeginOption = BeginAccessMode("")
+	_ PGOption    = (PGOptionFunc)(nil)
 )
 
 // BeginOption describes an option.
@@ -15,8 +19,9 @@ type BeginOption interface {
 }
 
 type beginOptions struct {
-	isoLevel   pgx.TxIsoLevel
-	accessMode pgx.TxAccessMode
+	isoLevel    pgx.TxIsoLevel
+	accessMode  pgx.TxAccessMode
+	retryPolicy *RetryPolicy
 }
 
 func newBeginOptions(options ...BeginOption) *beginOptions {
@@ -69,3 +74,49 @@ const (
 func (a BeginAccessMode) Apply(o *beginOptions) {
 	o.accessMode = pgx.TxAccessMode(a)
 }
+
+// PGOption describes an option.
+type PGOption interface {
+	Apply(o *pgOptions)
+}
+
+type pgOptions struct {
+	replicas             []*pgxpool.Pool
+	replicaMaxLag        time.Duration
+	readYourWritesWindow time.Duration
+}
+
+func newPGOptions(options ...PGOption) *pgOptions {
+	o := &pgOptions{}
+
+	for _, option := range options {
+		option.Apply(o)
+	}
+
+	return o
+}
+
+// PGOptionFunc is a shorthand for PGOption.
+type PGOptionFunc func(o *pgOptions)
+
+// Apply implements the PGOption interface.
+func (f PGOptionFunc) Apply(o *pgOptions) {
+	f(o)
+}
+
+// PGReplicas routes reads to the given replicas, see [WithReadReplica]. Replicas are skipped while unhealthy or while
+// their replication lag exceeds maxLag (if positive), in which case reads fall back to the primary.
+func PGReplicas(replicas []*pgxpool.Pool, maxLag time.Duration) PGOptionFunc {
+	return func(o *pgOptions) {
+		o.replicas = replicas
+		o.replicaMaxLag = maxLag
+	}
+}
+
+// PGReadYourWritesWindow sets how long reads are routed to the primary after a write, see [WithReadYourWrites]. A zero
+// window routes them to the primary for the remaining lifetime of the context.
+func PGReadYourWritesWindow(window time.Duration) PGOptionFunc {
+	return func(o *pgOptions) {
+		o.readYourWritesWindow = window
+	}
+}
@@ -19,6 +19,12 @@ type contextKey int
 
 const (
 	pgContextKey contextKey = iota
+	queryStartTimeContextKey
+	batchStartTimeContextKey
+	connectStartTimeContextKey
+	acquireStartTimeContextKey
+	readReplicaContextKey
+	readYourWritesContextKey
 )
 
 // PG describes the module (with cached context).
@@ -45,32 +51,49 @@ func NewInitializer(migCfg *MigrationsConfig) injectz.Initializer {
 			logm.MustGet(ctx)
 			pgCfg := cfgm.MustGet[PGConfigMixin](ctx).GetPGConfig()
 
-			poolCfg, err := pgxpool.ParseConfig(pgCfg.PostgresURL)
-			errorz.MaybeMustWrap(err)
+			pool := mustNewPool(ctx, pgCfg.PostgresURL)
+			maybeMustApplyMigrationsInternal(ctx, pool, migCfg)
 
-			pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
-			errorz.MaybeMustWrap(err)
+			replicas := make([]*pgxpool.Pool, 0, len(pgCfg.ReplicaURLs))
+			for _, replicaURL := range pgCfg.ReplicaURLs {
+				replicas = append(replicas, mustNewPool(ctx, replicaURL))
+			}
 
-			maybeMustApplyMigrationsInternal(ctx, pool, migCfg)
-			return NewSingletonInjector(NewPGFromPool(pool)), getReleaser(outCtx, pool), nil
+			pg := newBackgroundPG(pool,
+				PGReplicas(replicas, pgCfg.ReplicaMaxLag),
+				PGReadYourWritesWindow(pgCfg.ReadYourWritesWindow))
+
+			stopChecks := pg.replicas.startChecks(outCtx, pgCfg.ReplicaCheckInterval)
+			return NewSingletonInjector(pg), getReleaser(outCtx, pg, stopChecks), nil
 		})
 	}
 }
 
-func getReleaser(ctx context.Context, pool *pgxpool.Pool) injectz.Releaser {
+func mustNewPool(ctx context.Context, postgresURL string) *pgxpool.Pool {
+	poolCfg, err := pgxpool.ParseConfig(postgresURL)
+	errorz.MaybeMustWrap(err)
+	poolCfg.ConnConfig.Tracer = newTracer()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	errorz.MaybeMustWrap(err)
+	return pool
+}
+
+func getReleaser(ctx context.Context, pg *backgroundPGImpl, stopChecks func()) injectz.Releaser {
 	return func() {
 		_ = logm.Wrap0(ctx, "pgm.Releaser", func(_ context.Context) error {
-			pool.Close()
+			stopChecks()
+			pg.replicas.close()
+			pg.pool.Close()
 			return nil
 		})
 	}
 }
 
-// NewPGFromPool initializes a new [RawPG] using the given [*pgxpool.Pool].
-func NewPGFromPool(pool *pgxpool.Pool) RawPG {
-	return &backgroundPGImpl{
-		pool: pool,
-	}
+// NewPGFromPool initializes a new [RawPG] using the given [*pgxpool.Pool] as primary. Replicas given using
+// [PGReplicas] are assumed healthy and up-to-date, as they are only checked when initialized by [NewInitializer].
+func NewPGFromPool(pool *pgxpool.Pool, options ...PGOption) RawPG {
+	return newBackgroundPG(pool, options...)
 }
 
 // NewSingletonInjector injects.
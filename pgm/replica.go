@@ -0,0 +1,320 @@
+package pgm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ibrt/golang-modules/clkm"
+	"github.com/ibrt/golang-modules/logm"
+)
+
+const (
+	// DefaultReplicaCheckInterval is the default interval between replica health and replication lag checks.
+	DefaultReplicaCheckInterval = 5 * time.Second
+
+	// replicaReceiverStallTimeout is how long a WAL receiver can go without receiving messages from the primary before
+	// the replica is considered unhealthy. It matches the default wal_receiver_timeout.
+	replicaReceiverStallTimeout = time.Minute
+
+	// A server that is not in recovery (e.g. a primary used as replica) has no lag. On a replica that has replayed all
+	// the WAL it received, the last replayed transaction may be old simply because the primary is idle, so it is only
+	// up-to-date as long as its WAL receiver is streaming. Only roles with the privileges of pg_read_all_stats can see
+	// the status of the WAL receiver, other roles only see whether it is running.
+	replicaStatusQuery = `
+		SELECT
+			pg_is_in_recovery(),
+			r.pid IS NOT NULL,
+			COALESCE(r.status, ''),
+			COALESCE(EXTRACT(EPOCH FROM now() - r.last_msg_receipt_time), 0)::float8,
+			CASE
+				WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+			END::float8
+		FROM (SELECT 1) AS d
+		LEFT JOIN pg_stat_wal_receiver AS r ON true`
+)
+
+// Known routing reasons, recorded as [AttrDBRouteReason] when a read is routed to the primary.
+const (
+	routeReasonReadYourWrites    = "read_your_writes"
+	routeReasonReplicasUnhealthy = "replicas_unhealthy"
+	routeReasonReplicasLagging   = "replicas_lagging"
+	routeReasonSerializable      = "serializable"
+)
+
+// WithReadReplica returns a context in which non-transactional queries (i.e. [RawPG.Query] and [RawPG.QueryRow]) are
+// routed to a replica, if one is available. Read-only transactions (see [BeginAccessModeReadOnly]) are always routed
+// to a replica, if one is available, unless they use the (default) serializable isolation level, which hot standby
+// replicas do not support: use [BeginIsoLevelRepeatableRead] (or lower) for read-only transactions meant for replicas.
+func WithReadReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readReplicaContextKey, true)
+}
+
+func isReadReplicaContext(ctx context.Context) bool {
+	isReadReplica, _ := ctx.Value(readReplicaContextKey).(bool)
+	return isReadReplica
+}
+
+type readYourWrites struct {
+	m             *sync.Mutex
+	lastWriteTime *time.Time
+}
+
+// WithReadYourWrites returns a context (e.g. for a request) that tracks writes routed to the primary through it or its
+// descendants. After a write, reads are routed to the primary for the read-your-writes window (see
+// [PGReadYourWritesWindow]), so that they observe it even if replicas are lagging. Successful [RawPG.Exec] calls
+// outside transactions, and commits of read-write transactions, count as writes. Writes performed using [RawPG.Query]
+// or [RawPG.QueryRow] (e.g. "INSERT ... RETURNING") are not tracked.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesContextKey, &readYourWrites{
+		m: &sync.Mutex{},
+	})
+}
+
+// maybeMarkWrite records a write on the read-your-writes state of the context, if any.
+func maybeMarkWrite(ctx context.Context) {
+	if r, ok := ctx.Value(readYourWritesContextKey).(*readYourWrites); ok {
+		now := clkm.MustGet(ctx).Now()
+
+		r.m.Lock()
+		defer r.m.Unlock()
+		r.lastWriteTime = &now
+	}
+}
+
+// isReadYourWritesSticky returns true if reads in the given context must be routed to the primary.
+func isReadYourWritesSticky(ctx context.Context, window time.Duration) bool {
+	r, ok := ctx.Value(readYourWritesContextKey).(*readYourWrites)
+	if !ok {
+		return false
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.lastWriteTime == nil {
+		return false
+	}
+
+	return window <= 0 || clkm.MustGet(ctx).Now().Sub(*r.lastWriteTime) < window
+}
+
+// replicaStatus describes the result of a replica check.
+type replicaStatus struct {
+	isInRecovery   bool
+	hasReceiver    bool
+	receiverStatus string
+	receiptAge     time.Duration
+	lag            time.Duration
+}
+
+// getError returns an error if the replica cannot serve up-to-date reads because its WAL receiver is missing or
+// stalled, nil otherwise.
+func (s *replicaStatus) getError() error {
+	if !s.isInRecovery {
+		return nil
+	}
+
+	if !s.hasReceiver {
+		return errorz.Errorf("WAL receiver is not running")
+	}
+
+	if s.receiverStatus != "" && s.receiverStatus != "streaming" {
+		return errorz.Errorf("WAL receiver is not streaming (status: %v)", s.receiverStatus)
+	}
+
+	if s.receiptAge > replicaReceiverStallTimeout {
+		return errorz.Errorf("WAL receiver received no messages for %v", s.receiptAge)
+	}
+
+	return nil
+}
+
+type replica struct {
+	index     int
+	pool      *pgxpool.Pool
+	m         *sync.Mutex
+	isHealthy bool
+	lag       time.Duration
+}
+
+func (r *replica) getState() (bool, time.Duration) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.isHealthy, r.lag
+}
+
+func (r *replica) setState(isHealthy bool, lag time.Duration) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.isHealthy, r.lag = isHealthy, lag
+}
+
+// replicaSet picks replicas for reads, skipping those that are unhealthy or lagging. Replicas are considered healthy
+// and up-to-date until checked.
+type replicaSet struct {
+	replicas []*replica
+	maxLag   time.Duration
+	next     *atomic.Uint64
+}
+
+func newReplicaSet(pools []*pgxpool.Pool, maxLag time.Duration) *replicaSet {
+	if len(pools) == 0 {
+		return nil
+	}
+
+	s := &replicaSet{
+		replicas: make([]*replica, 0, len(pools)),
+		maxLag:   maxLag,
+		next:     &atomic.Uint64{},
+	}
+
+	for i, pool := range pools {
+		s.replicas = append(s.replicas, &replica{
+			index:     i,
+			pool:      pool,
+			m:         &sync.Mutex{},
+			isHealthy: true,
+		})
+	}
+
+	return s
+}
+
+// pick returns the next eligible replica in round-robin order, or nil and the reason why none is eligible.
+func (s *replicaSet) pick() (*replica, string) {
+	start := s.next.Add(1)
+	reason := routeReasonReplicasUnhealthy
+
+	for i := range s.replicas {
+		r := s.replicas[(start+uint64(i))%uint64(len(s.replicas))]
+		isHealthy, lag := r.getState()
+
+		if !isHealthy {
+			continue
+		}
+
+		if s.maxLag > 0 && lag > s.maxLag {
+			reason = routeReasonReplicasLagging
+			continue
+		}
+
+		return r, ""
+	}
+
+	return nil, reason
+}
+
+// check updates the health and replication lag of all replicas. Checks run often, so they are not traced: an event is
+// emitted only when a replica changes state (see update).
+func (s *replicaSet) check(ctx context.Context, timeout time.Duration) {
+	for _, r := range s.replicas {
+		status, err := r.getStatus(ctx, timeout)
+		if err == nil {
+			err = status.getError()
+		}
+
+		lag := time.Duration(0)
+		if status != nil {
+			lag = status.lag
+		}
+
+		r.update(ctx, err, lag, s.maxLag)
+	}
+}
+
+// update sets the state of the replica, emitting a warning when it becomes unhealthy or lagging, and an info event
+// when it recovers.
+func (r *replica) update(ctx context.Context, err error, lag time.Duration, maxLag time.Duration) {
+	prevIsHealthy, prevLag := r.getState()
+	r.setState(err == nil, lag)
+
+	isLagging := func(isHealthy bool, lag time.Duration) bool {
+		return isHealthy && maxLag > 0 && lag > maxLag
+	}
+
+	switch {
+	case err != nil && prevIsHealthy:
+		logm.MustGet(ctx).EmitWarning(errorz.Wrap(err, errorz.Errorf("replica %v is unhealthy", r.index)))
+	case isLagging(err == nil, lag) && !isLagging(prevIsHealthy, prevLag):
+		logm.MustGet(ctx).EmitWarning(errorz.Errorf("replica %v is lagging by %v", r.index, lag))
+	case err == nil && !isLagging(true, lag) && (!prevIsHealthy || isLagging(prevIsHealthy, prevLag)):
+		logm.MustGet(ctx).EmitInfo("replica %v recovered", logm.EmitA(r.index), logm.EmitMetadata{
+			"replica": r.index,
+			"lag_ms":  float64(lag) / float64(time.Millisecond),
+		})
+	}
+}
+
+func (r *replica) getStatus(ctx context.Context, timeout time.Duration) (*replicaStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var receiptAgeSeconds, lagSeconds float64
+	status := &replicaStatus{}
+
+	if err := r.pool.QueryRow(ctx, replicaStatusQuery).Scan(
+		&status.isInRecovery,
+		&status.hasReceiver,
+		&status.receiverStatus,
+		&receiptAgeSeconds,
+		&lagSeconds); err != nil {
+		return nil, errorz.Wrap(err)
+	}
+
+	status.receiptAge = time.Duration(receiptAgeSeconds * float64(time.Second))
+	status.lag = time.Duration(lagSeconds * float64(time.Second))
+	return status, nil
+}
+
+// startChecks checks the replicas once, then every interval. It returns a function that stops the checks.
+func (s *replicaSet) startChecks(ctx context.Context, interval time.Duration) func() {
+	if s == nil {
+		return func() {}
+	}
+
+	if interval <= 0 {
+		interval = DefaultReplicaCheckInterval
+	}
+
+	s.check(ctx, interval)
+
+	stopC := make(chan struct{})
+	doneC := make(chan struct{})
+
+	go func() {
+		defer close(doneC)
+
+		ticker := clkm.MustGet(ctx).Ticker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopC:
+				return
+			case <-ticker.C:
+				s.check(ctx, interval)
+			}
+		}
+	}()
+
+	return func() {
+		close(stopC)
+		<-doneC
+	}
+}
+
+func (s *replicaSet) close() {
+	if s == nil {
+		return
+	}
+
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
+}
@@ -0,0 +1,213 @@
+package pgm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type testContextKey struct{}
+
+type ReplicaSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestReplicaSuite(t *testing.T) {
+	fixturez.RunSuite(t, &ReplicaSuite{})
+}
+
+func (*ReplicaSuite) TestPick(g *WithT) {
+	s := newReplicaSet([]*pgxpool.Pool{{}, {}, {}}, time.Second)
+
+	picked := make([]int, 0)
+	for i := 0; i < 6; i++ {
+		r, reason := s.pick()
+		g.Expect(reason).To(BeEmpty())
+		picked = append(picked, r.index)
+	}
+	g.Expect(picked).To(HaveExactElements(1, 2, 0, 1, 2, 0))
+
+	s.replicas[1].setState(false, 0)
+	s.replicas[2].setState(true, 2*time.Second)
+
+	for i := 0; i < 3; i++ {
+		r, reason := s.pick()
+		g.Expect(reason).To(BeEmpty())
+		g.Expect(r).To(BeIdenticalTo(s.replicas[0]))
+	}
+
+	s.replicas[0].setState(false, 0)
+	r, reason := s.pick()
+	g.Expect(r).To(BeNil())
+	g.Expect(reason).To(Equal(routeReasonReplicasLagging))
+
+	s.replicas[2].setState(false, 0)
+	r, reason = s.pick()
+	g.Expect(r).To(BeNil())
+	g.Expect(reason).To(Equal(routeReasonReplicasUnhealthy))
+
+	g.Expect(newReplicaSet(nil, time.Second)).To(BeNil())
+}
+
+func (*ReplicaSuite) TestReplicaStatus(g *WithT) {
+	g.Expect((&replicaStatus{}).getError()).To(Succeed())
+	g.Expect((&replicaStatus{isInRecovery: true, hasReceiver: true, receiverStatus: "streaming"}).getError()).To(Succeed())
+	g.Expect((&replicaStatus{isInRecovery: true, hasReceiver: true}).getError()).To(Succeed())
+
+	g.Expect((&replicaStatus{isInRecovery: true}).getError()).
+		To(MatchError("WAL receiver is not running"))
+	g.Expect((&replicaStatus{isInRecovery: true, hasReceiver: true, receiverStatus: "stopping"}).getError()).
+		To(MatchError("WAL receiver is not streaming (status: stopping)"))
+	g.Expect((&replicaStatus{isInRecovery: true, hasReceiver: true, receiptAge: 2 * time.Minute}).getError()).
+		To(MatchError("WAL receiver received no messages for 2m0s"))
+}
+
+func (s *ReplicaSuite) TestUpdate(ctx context.Context, g *WithT) {
+	r := newReplicaSet([]*pgxpool.Pool{{}}, time.Second).replicas[0]
+
+	// Events are only emitted on state changes.
+	for _, lag := range []time.Duration{0, 500 * time.Millisecond} {
+		r.update(ctx, nil, lag, time.Second)
+	}
+	g.Expect(s.LOG.GetMock().GetEvents()).To(BeEmpty())
+
+	for i := 0; i < 2; i++ {
+		r.update(ctx, errorz.Errorf("WAL receiver is not running"), 0, time.Second)
+	}
+	isHealthy, _ := r.getState()
+	g.Expect(isHealthy).To(BeFalse())
+
+	for i := 0; i < 2; i++ {
+		r.update(ctx, nil, 2*time.Second, time.Second)
+	}
+	isHealthy, lag := r.getState()
+	g.Expect(isHealthy).To(BeTrue())
+	g.Expect(lag).To(Equal(2 * time.Second))
+
+	for i := 0; i < 2; i++ {
+		r.update(ctx, nil, 0, time.Second)
+	}
+
+	g.Expect(s.LOG.GetMock().GetEvents()).To(HaveLen(3))
+	g.Expect(s.LOG.GetMock()).To(And(
+		tlogm.HaveEvent("warning", "replica 0 is unhealthy: WAL receiver is not running"),
+		tlogm.HaveEvent("warning", "replica 0 is lagging by 2s"),
+		tlogm.HaveEvent("info", "replica 0 recovered", tlogm.HaveMetadata("lag_ms", 0.0))))
+}
+
+func (s *ReplicaSuite) TestIsReadYourWritesSticky(ctx context.Context, g *WithT) {
+	g.Expect(isReadYourWritesSticky(ctx, time.Minute)).To(BeFalse())
+	maybeMarkWrite(ctx)
+	g.Expect(isReadYourWritesSticky(ctx, time.Minute)).To(BeFalse())
+
+	ctx = WithReadYourWrites(ctx)
+	g.Expect(isReadYourWritesSticky(ctx, time.Minute)).To(BeFalse())
+
+	maybeMarkWrite(ctx)
+	g.Expect(isReadYourWritesSticky(ctx, time.Minute)).To(BeTrue())
+	g.Expect(isReadYourWritesSticky(ctx, 0)).To(BeTrue())
+
+	s.CLK.GetMock().Add(time.Minute)
+	g.Expect(isReadYourWritesSticky(ctx, time.Minute)).To(BeFalse())
+	g.Expect(isReadYourWritesSticky(ctx, 0)).To(BeTrue())
+
+	// Writes are shared with descendant contexts.
+	maybeMarkWrite(context.WithValue(ctx, testContextKey{}, true))
+	g.Expect(isReadYourWritesSticky(ctx, time.Minute)).To(BeTrue())
+}
+
+func (s *ReplicaSuite) TestGetPool(ctx context.Context, g *WithT) {
+	primary := &pgxpool.Pool{}
+	replicas := []*pgxpool.Pool{{}, {}}
+	b := newBackgroundPG(primary, PGReplicas(replicas, time.Second), PGReadYourWritesWindow(time.Minute))
+	ctx = WithReadYourWrites(ctx)
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("write")
+		defer end()
+		g.Expect(b.getPool(ctx, false)).To(BeIdenticalTo(primary))
+	}()
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("read")
+		defer end()
+		g.Expect(b.getPool(ctx, true)).To(BeIdenticalTo(replicas[1]))
+	}()
+
+	// Routing a statement to the primary is not a write by itself.
+	g.Expect(isReadYourWritesSticky(ctx, time.Minute)).To(BeFalse())
+	maybeMarkWrite(ctx)
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("read-your-writes")
+		defer end()
+		g.Expect(b.getPool(ctx, true)).To(BeIdenticalTo(primary))
+	}()
+
+	b.replicas.replicas[0].setState(false, 0)
+	b.replicas.replicas[1].setState(false, 0)
+	s.CLK.GetMock().Add(time.Minute)
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("unhealthy")
+		defer end()
+		g.Expect(b.getPool(ctx, true)).To(BeIdenticalTo(primary))
+	}()
+
+	g.Expect(s.LOG.GetMock().GetTree()).To(And(
+		tlogm.HaveSpan("write", tlogm.HaveAttribute("db.route", "primary")),
+		tlogm.HaveSpan("read", tlogm.HaveAttribute("db.route", "replica"), tlogm.HaveAttribute("db.route.replica", int64(1))),
+		tlogm.HaveSpan("read-your-writes",
+			tlogm.HaveAttribute("db.route", "primary"),
+			tlogm.HaveAttribute("db.route.reason", routeReasonReadYourWrites)),
+		tlogm.HaveSpan("unhealthy",
+			tlogm.HaveAttribute("db.route", "primary"),
+			tlogm.HaveAttribute("db.route.reason", routeReasonReplicasUnhealthy))))
+
+	g.Expect(newBackgroundPG(primary).getPool(ctx, true)).To(BeIdenticalTo(primary))
+}
+
+func (s *ReplicaSuite) TestGetTxPool(ctx context.Context, g *WithT) {
+	primary := &pgxpool.Pool{}
+	replicas := []*pgxpool.Pool{{}}
+	b := newBackgroundPG(primary, PGReplicas(replicas, 0))
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("read-write")
+		defer end()
+		g.Expect(b.getTxPool(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadWrite})).
+			To(BeIdenticalTo(primary))
+	}()
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("read-only")
+		defer end()
+		g.Expect(b.getTxPool(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})).
+			To(BeIdenticalTo(replicas[0]))
+	}()
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("serializable")
+		defer end()
+		g.Expect(b.getTxPool(ctx, newBeginOptions(BeginAccessModeReadOnly).ToTxOptions())).
+			To(BeIdenticalTo(primary))
+	}()
+
+	g.Expect(s.LOG.GetMock().GetTree()).To(And(
+		tlogm.HaveSpan("read-write", tlogm.HaveAttribute("db.route", "primary")),
+		tlogm.HaveSpan("read-only", tlogm.HaveAttribute("db.route", "replica")),
+		tlogm.HaveSpan("serializable",
+			tlogm.HaveAttribute("db.route", "primary"),
+			tlogm.HaveAttribute("db.route.reason", routeReasonSerializable))))
+}
@@ -0,0 +1,163 @@
+package pgm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/ibrt/golang-modules/clkm"
+)
+
+var (
+	_ BeginOption = (*RetryPolicy)(nil)
+)
+
+// Known retry reasons, as returned by [DefaultRetryClassifier].
+const (
+	RetryReasonSerializationFailure = "serialization_failure"
+	RetryReasonDeadlockDetected     = "deadlock_detected"
+	RetryReasonLockNotAvailable     = "lock_not_available"
+	RetryReasonConnectionFailure    = "connection_failure"
+)
+
+// RetryClassifier returns the reason why a failed transaction attempt can be retried, or an empty string if it cannot.
+// If isCommitting is true the error was returned by the commit, so the transaction may have been committed: only errors
+// guaranteeing that it was not should be retried.
+type RetryClassifier func(err error, isCommitting bool) string
+
+// RetryPolicy describes how [Wrap0] (etc.) retry transactions. It can be given as [BeginOption], and is ignored by
+// nested transactions, whose errors are propagated to the enclosing retry loop.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Values below 1 are treated as 1.
+	MaxAttempts int
+
+	// InitialBackoff is the backoff before the second attempt. It is multiplied by Multiplier before each subsequent
+	// attempt, up to MaxBackoff (if positive). Multipliers below 1 are treated as 1.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter is the fraction of each backoff that is randomized, between 0 and 1: backoffs are uniformly distributed
+	// between (1 - Jitter) times and 1 times their nominal value.
+	Jitter float64
+
+	// Budget is the maximum total time spent on all attempts, if positive. Retries that would start after the budget
+	// (or the context deadline) is exhausted are not attempted.
+	Budget time.Duration
+
+	// Classifier determines which errors are retried. It defaults to [DefaultRetryClassifier].
+	Classifier RetryClassifier
+}
+
+// NewDefaultRetryPolicy returns the [*RetryPolicy] used by [Wrap0] (etc.) unless another one is given as option. It
+// makes up to 10 attempts. The first backoff is between 100ms and 500ms, and it doubles for each subsequent attempt, up
+// to 5s.
+func NewDefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.8,
+		Classifier:     DefaultRetryClassifier,
+	}
+}
+
+// Apply implements the [BeginOption] interface.
+func (p *RetryPolicy) Apply(o *beginOptions) {
+	o.retryPolicy = p
+}
+
+func (p *RetryPolicy) getMaxAttempts() int {
+	return max(p.MaxAttempts, 1)
+}
+
+// getBackoff returns the (jittered) backoff before the given attempt (starting at 2).
+func (p *RetryPolicy) getBackoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(max(p.Multiplier, 1), float64(attempt-2))
+
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, float64(p.MaxBackoff))
+	}
+
+	backoff -= backoff * min(max(p.Jitter, 0), 1) * rand.Float64()
+	return time.Duration(backoff)
+}
+
+func (p *RetryPolicy) classify(err error, isCommitting bool) string {
+	if p.Classifier != nil {
+		return p.Classifier(err, isCommitting)
+	}
+
+	return DefaultRetryClassifier(err, isCommitting)
+}
+
+// DefaultRetryClassifier retries serialization failures, deadlocks and lock timeouts (e.g. "FOR UPDATE NOWAIT"), which
+// always cause the transaction to be rolled back, and connection failures that did not happen while committing.
+func DefaultRetryClassifier(err error, isCommitting bool) string {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ""
+	}
+
+	if pgErr := errAsPGError(err); pgErr != nil {
+		switch {
+		case pgErr.Code == pgerrcode.SerializationFailure:
+			return RetryReasonSerializationFailure
+		case pgErr.Code == pgerrcode.DeadlockDetected:
+			return RetryReasonDeadlockDetected
+		case pgErr.Code == pgerrcode.LockNotAvailable:
+			return RetryReasonLockNotAvailable
+		case pgerrcode.IsConnectionException(pgErr.Code) && !isCommitting:
+			return RetryReasonConnectionFailure
+		default:
+			return ""
+		}
+	}
+
+	if !isCommitting && isConnectionError(err) {
+		return RetryReasonConnectionFailure
+	}
+
+	return ""
+}
+
+func isConnectionError(err error) bool {
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	if _, ok := errorz.As[*pgconn.ConnectError](err); ok {
+		return true
+	}
+
+	if _, ok := errorz.As[net.Error](err); ok {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// sleep waits for the given duration using the context clock, or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := clkm.MustGet(ctx).Timer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return errorz.Wrap(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
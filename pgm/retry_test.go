@@ -0,0 +1,186 @@
+package pgm
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type RetrySuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestRetrySuite(t *testing.T) {
+	fixturez.RunSuite(t, &RetrySuite{})
+}
+
+func (*RetrySuite) TestNewDefaultRetryPolicy(g *WithT) {
+	p := NewDefaultRetryPolicy()
+	g.Expect(p).ToNot(BeIdenticalTo(NewDefaultRetryPolicy()))
+	g.Expect(p.getMaxAttempts()).To(Equal(10))
+
+	for i := 0; i < 100; i++ {
+		g.Expect(p.getBackoff(2)).To(And(
+			BeNumerically(">=", 100*time.Millisecond),
+			BeNumerically("<=", 500*time.Millisecond)))
+		g.Expect(p.getBackoff(20)).To(And(
+			BeNumerically(">=", time.Second),
+			BeNumerically("<=", 5*time.Second)))
+	}
+}
+
+func (*RetrySuite) TestGetBackoff(g *WithT) {
+	p := &RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+
+	g.Expect([]time.Duration{p.getBackoff(2), p.getBackoff(3), p.getBackoff(4), p.getBackoff(5), p.getBackoff(6)}).
+		To(HaveExactElements(
+			100*time.Millisecond,
+			200*time.Millisecond,
+			400*time.Millisecond,
+			800*time.Millisecond,
+			time.Second))
+
+	p = &RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 0.5, Jitter: 2}
+	for i := 0; i < 100; i++ {
+		g.Expect(p.getBackoff(5)).To(BeNumerically("<=", 100*time.Millisecond))
+	}
+
+	g.Expect((&RetryPolicy{MaxAttempts: -1}).getMaxAttempts()).To(Equal(1))
+}
+
+func (*RetrySuite) TestDefaultRetryClassifier(g *WithT) {
+	type testCase struct {
+		err          error
+		isCommitting bool
+		reason       string
+	}
+
+	for i, tc := range []testCase{
+		{&pgconn.PgError{Code: pgerrcode.SerializationFailure}, false, RetryReasonSerializationFailure},
+		{&pgconn.PgError{Code: pgerrcode.SerializationFailure}, true, RetryReasonSerializationFailure},
+		{errorz.Wrap(&pgconn.PgError{Code: pgerrcode.DeadlockDetected}), false, RetryReasonDeadlockDetected},
+		{&pgconn.PgError{Code: pgerrcode.LockNotAvailable}, false, RetryReasonLockNotAvailable},
+		{&pgconn.PgError{Code: pgerrcode.ConnectionFailure}, false, RetryReasonConnectionFailure},
+		{&pgconn.PgError{Code: pgerrcode.ConnectionFailure}, true, ""},
+		{&pgconn.PgError{Code: pgerrcode.UniqueViolation}, false, ""},
+		{errorz.Wrap(io.ErrUnexpectedEOF), false, RetryReasonConnectionFailure},
+		{io.EOF, true, ""},
+		{&net.OpError{Op: "read", Err: errorz.Errorf("reset")}, false, RetryReasonConnectionFailure},
+		{errorz.Wrap(context.Canceled), false, ""},
+		{context.DeadlineExceeded, false, ""},
+		{errorz.Errorf("test error"), false, ""},
+	} {
+		g.Expect(DefaultRetryClassifier(tc.err, tc.isCommitting)).To(Equal(tc.reason), "%v", i)
+	}
+
+	p := &RetryPolicy{Classifier: func(error, bool) string { return "custom" }}
+	g.Expect(p.classify(errorz.Errorf("test error"), false)).To(Equal("custom"))
+	g.Expect((&RetryPolicy{}).classify(io.EOF, false)).To(Equal(RetryReasonConnectionFailure))
+}
+
+func (s *RetrySuite) TestSleep(ctx context.Context, g *WithT) {
+	g.Expect(sleep(ctx, 0)).To(Succeed())
+
+	errC := make(chan error, 1)
+	go func() {
+		errC <- sleep(ctx, time.Second)
+	}()
+
+	g.Eventually(func() chan error {
+		s.CLK.GetMock().Add(100 * time.Millisecond)
+		return errC
+	}).Should(Receive(BeNil()))
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	g.Expect(sleep(ctx, time.Second)).To(MatchError(context.Canceled))
+}
+
+func (s *RetrySuite) TestRetry(ctx context.Context, g *WithT) {
+	calls := make([]string, 0)
+	ctx = NewSingletonInjector(&testRawPG{calls: &calls})(ctx)
+	attempts := 0
+
+	errC := make(chan error, 1)
+	go func() {
+		errC <- Wrap0(ctx, "tx", func(ctx context.Context) error {
+			attempts++
+			return &pgconn.PgError{Code: pgerrcode.SerializationFailure}
+		}, &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, Multiplier: 2})
+	}()
+
+	var err error
+	g.Eventually(func() chan error {
+		s.CLK.GetMock().Add(100 * time.Millisecond)
+		return errC
+	}).Should(Receive(&err))
+
+	g.Expect(errAsPGError(err)).ToNot(BeNil())
+	g.Expect(attempts).To(Equal(3))
+	g.Expect(calls).To(HaveExactElements("begin", "rollback", "begin", "rollback", "begin", "rollback"))
+
+	g.Expect(s.LOG.GetMock().GetTree()).To(tlogm.HaveSpan("pgm.Wrap.[tx]",
+		tlogm.HaveChild("pgm.Wrap.0.[tx]", tlogm.HaveAttribute("db.retry.attempt", int64(1))),
+		tlogm.HaveChild("pgm.Wrap.1.[tx]",
+			tlogm.HaveAttribute("db.retry.attempt", int64(2)),
+			tlogm.HaveAttribute("db.retry.reason", RetryReasonSerializationFailure),
+			tlogm.HaveAttribute("db.retry.backoff_ms", 1000.0)),
+		tlogm.HaveChild("pgm.Wrap.2.[tx]",
+			tlogm.HaveAttribute("db.retry.attempt", int64(3)),
+			tlogm.HaveAttribute("db.retry.backoff_ms", 2000.0))))
+}
+
+func (*RetrySuite) TestRetry_NotRetryable(ctx context.Context, g *WithT) {
+	calls := make([]string, 0)
+	ctx = NewSingletonInjector(&testRawPG{calls: &calls})(ctx)
+	attempts := 0
+
+	err := Wrap0(ctx, "tx", func(ctx context.Context) error {
+		attempts++
+		return &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+	})
+
+	g.Expect(errAsPGError(err)).ToNot(BeNil())
+	g.Expect(attempts).To(Equal(1))
+	g.Expect(calls).To(HaveExactElements("begin", "rollback"))
+}
+
+func (s *RetrySuite) TestRetry_Budget(ctx context.Context, g *WithT) {
+	calls := make([]string, 0)
+	ctx = NewSingletonInjector(&testRawPG{calls: &calls})(ctx)
+	attempts := 0
+
+	f := func(ctx context.Context) error {
+		attempts++
+		return &pgconn.PgError{Code: pgerrcode.SerializationFailure}
+	}
+
+	// The retry would start after the budget is exhausted.
+	err := Wrap0(ctx, "tx", f, &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, Budget: 500 * time.Millisecond})
+	g.Expect(errAsPGError(err)).ToNot(BeNil())
+	g.Expect(attempts).To(Equal(1))
+
+	// The retry would start after the context deadline.
+	ctx, cancel := context.WithDeadline(ctx, s.CLK.GetMock().Now().Add(time.Minute))
+	defer cancel()
+
+	err = Wrap0(ctx, "tx", f, &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour})
+	g.Expect(errAsPGError(err)).ToNot(BeNil())
+	g.Expect(attempts).To(Equal(2))
+}
@@ -0,0 +1,110 @@
+package pgm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/ibrt/golang-modules/logm"
+)
+
+var (
+	_ pgx.Rows = (*spanRows)(nil)
+	_ pgx.Row  = (*spanRow)(nil)
+)
+
+// spanRows ends the span of a query when the rows are closed: pgx only reports the outcome of a query to the tracer at
+// that point, so the span must still be open.
+type spanRows struct {
+	pgx.Rows
+	ctx  context.Context
+	end  func()
+	once *sync.Once
+}
+
+// Next implements the [pgx.Rows] interface.
+func (r *spanRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	// pgx closes the rows once they are exhausted, callers are not required to do it again.
+	r.Close()
+	return false
+}
+
+// Close implements the [pgx.Rows] interface.
+func (r *spanRows) Close() {
+	r.Rows.Close()
+	r.once.Do(func() { endQuerySpan(r.ctx, r.end, errorz.MaybeWrap(r.Rows.Err())) })
+}
+
+// spanRow ends the span of a query when the row is scanned, see [*spanRows].
+type spanRow struct {
+	row  pgx.Row
+	ctx  context.Context
+	end  func()
+	once *sync.Once
+}
+
+// Scan implements the [pgx.Row] interface.
+func (r *spanRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		err = errorz.Wrap(err)
+	}
+
+	r.once.Do(func() { endQuerySpan(r.ctx, r.end, err) })
+	return err
+}
+
+// traceQuery runs the given query in a span that ends when the returned rows are closed.
+func traceQuery(ctx context.Context, name string, query func(ctx context.Context) (pgx.Rows, error)) (pgx.Rows, error) {
+	ctx, end := logm.MustGet(ctx).Begin(fmt.Sprintf("pgm.Query.[%v]", name))
+
+	rows, err := query(ctx)
+	if err != nil {
+		err = errorz.Wrap(err)
+		endQuerySpan(ctx, end, err)
+		return nil, err
+	}
+
+	return &spanRows{
+		Rows: rows,
+		ctx:  ctx,
+		end:  end,
+		once: &sync.Once{},
+	}, nil
+}
+
+// traceQueryRow runs the given query in a span that ends when the returned row is scanned.
+func traceQueryRow(ctx context.Context, name string, queryRow func(ctx context.Context) pgx.Row) pgx.Row {
+	ctx, end := logm.MustGet(ctx).Begin(fmt.Sprintf("pgm.QueryRow.[%v]", name))
+
+	return &spanRow{
+		row:  queryRow(ctx),
+		ctx:  ctx,
+		end:  end,
+		once: &sync.Once{},
+	}
+}
+
+// endQuerySpan records the error (if any) on the span and ends it. A missing row is not considered an error, and
+// context errors only set the span status, as in [logm.Wrap0].
+func endQuerySpan(ctx context.Context, end func(), err error) {
+	defer end()
+
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log := logm.MustGet(ctx)
+		status := logm.GetErrorSpanStatus(err)
+		log.SetStatus(status, err.Error())
+
+		if status == logm.SpanStatusError {
+			log.EmitError(err)
+		}
+	}
+}
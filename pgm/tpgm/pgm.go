@@ -30,6 +30,7 @@ type Helper struct {
 	MigrationsConfig *pgm.MigrationsConfig
 	dbName           string
 	origPostgresURL  string
+	origReplicaURLs  []string
 	releaser         func()
 }
 
@@ -41,6 +42,12 @@ func (h *Helper) BeforeSuite(ctx context.Context, _ *gomega.WithT) context.Conte
 	h.mustCreateDB(ctx, cfg.PostgresURL, h.dbName)
 	h.origPostgresURL, cfg.PostgresURL = cfg.PostgresURL, h.mustSelectDB(cfg.PostgresURL, h.dbName)
 
+	// Replicas (if any) are expected to replicate the test database from the primary.
+	h.origReplicaURLs = cfg.ReplicaURLs
+	cfg.ReplicaURLs = memz.TransformSlice(cfg.ReplicaURLs, func(_ int, replicaURL string) string {
+		return h.mustSelectDB(replicaURL, h.dbName)
+	})
+
 	injector, releaser := pgm.NewInitializer(h.MigrationsConfig)(ctx)
 	h.releaser = releaser
 	return injector(ctx)
@@ -53,9 +60,11 @@ func (h *Helper) AfterSuite(ctx context.Context, _ *gomega.WithT) {
 
 	cfg := cfgm.MustGet[pgm.PGConfigMixin](ctx).GetPGConfig()
 	cfg.PostgresURL = h.origPostgresURL
+	cfg.ReplicaURLs = h.origReplicaURLs
 	h.mustDropDB(ctx, cfg.PostgresURL, h.dbName)
 
 	h.origPostgresURL = ""
+	h.origReplicaURLs = nil
 	h.dbName = ""
 }
 
@@ -0,0 +1,189 @@
+package pgm
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ibrt/golang-utils/memz"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ibrt/golang-modules/clkm"
+	"github.com/ibrt/golang-modules/logm"
+)
+
+var (
+	_ pgx.QueryTracer       = (*tracer)(nil)
+	_ pgx.BatchTracer       = (*tracer)(nil)
+	_ pgx.ConnectTracer     = (*tracer)(nil)
+	_ pgxpool.AcquireTracer = (*tracer)(nil)
+)
+
+var (
+	sqlStringLiteralRegexp  = regexp.MustCompile(`(?s)'(?:[^']|'')*'`)
+	sqlNumericLiteralRegexp = regexp.MustCompile(`(^|[^\w$.])-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?\b`)
+	sqlLineCommentRegexp    = regexp.MustCompile(`--[^\n]*`)
+	sqlBlockCommentRegexp   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	sqlWhitespaceRegexp     = regexp.MustCompile(`\s+`)
+)
+
+type tracer struct {
+	// intentionally empty
+}
+
+func newTracer() *tracer {
+	return &tracer{}
+}
+
+// TraceQueryStart implements the [pgx.QueryTracer] interface.
+func (t *tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if log := logm.MaybeGet(ctx); log != nil {
+		logm.SetAttributes(ctx,
+			logm.AttrDBStatement.V(normalizeSQL(data.SQL)),
+			AttrDBArgsCount.V(int64(len(data.Args))))
+		return context.WithValue(ctx, queryStartTimeContextKey, clkm.MustGet(ctx).Now())
+	}
+
+	return ctx
+}
+
+// TraceQueryEnd implements the [pgx.QueryTracer] interface.
+func (t *tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if log := logm.MaybeGet(ctx); log != nil {
+		if startTime, ok := ctx.Value(queryStartTimeContextKey).(time.Time); ok {
+			logm.SetAttributes(ctx, logm.AttrDBDuration.V(getDuration(ctx, startTime)))
+		}
+
+		addCommandTagAttributes(ctx, data.CommandTag)
+		maybeAddPGErrorAttributes(ctx, queryErrorAttrKeys, data.Err)
+	}
+}
+
+// TraceBatchStart implements the [pgx.BatchTracer] interface.
+func (t *tracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if logm.MaybeGet(ctx) != nil {
+		if data.Batch != nil {
+			logm.SetAttributes(ctx, AttrDBBatchSize.V(int64(data.Batch.Len())))
+		}
+		return context.WithValue(ctx, batchStartTimeContextKey, clkm.MustGet(ctx).Now())
+	}
+
+	return ctx
+}
+
+// TraceBatchQuery implements the [pgx.BatchTracer] interface.
+func (t *tracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if log := logm.MaybeGet(ctx); log != nil {
+		log.EmitDebug("db.batch.query",
+			logm.EmitM(string(logm.AttrDBStatement), normalizeSQL(data.SQL)),
+			logm.EmitM(string(AttrDBArgsCount), len(data.Args)),
+			logm.EmitM(string(logm.AttrDBRowsAffected), data.CommandTag.RowsAffected()))
+
+		maybeAddPGErrorAttributes(ctx, batchErrorAttrKeys, data.Err)
+	}
+}
+
+// TraceBatchEnd implements the [pgx.BatchTracer] interface.
+func (t *tracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	if logm.MaybeGet(ctx) != nil {
+		if startTime, ok := ctx.Value(batchStartTimeContextKey).(time.Time); ok {
+			logm.SetAttributes(ctx, AttrDBBatchDuration.V(getDuration(ctx, startTime)))
+		}
+
+		maybeAddPGErrorAttributes(ctx, batchErrorAttrKeys, data.Err)
+	}
+}
+
+// TraceConnectStart implements the [pgx.ConnectTracer] interface.
+func (t *tracer) TraceConnectStart(ctx context.Context, _ pgx.TraceConnectStartData) context.Context {
+	if log := logm.MaybeGet(ctx); log != nil {
+		return context.WithValue(ctx, connectStartTimeContextKey, clkm.MustGet(ctx).Now())
+	}
+
+	return ctx
+}
+
+// TraceConnectEnd implements the [pgx.ConnectTracer] interface.
+func (t *tracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if logm.MaybeGet(ctx) != nil {
+		if startTime, ok := ctx.Value(connectStartTimeContextKey).(time.Time); ok {
+			logm.SetAttributes(ctx, AttrDBConnectDuration.V(getDuration(ctx, startTime)))
+		}
+
+		maybeAddPGErrorAttributes(ctx, connectErrorAttrKeys, data.Err)
+	}
+}
+
+// TraceAcquireStart implements the [pgxpool.AcquireTracer] interface.
+func (t *tracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	if log := logm.MaybeGet(ctx); log != nil {
+		return context.WithValue(ctx, acquireStartTimeContextKey, clkm.MustGet(ctx).Now())
+	}
+
+	return ctx
+}
+
+// TraceAcquireEnd implements the [pgxpool.AcquireTracer] interface.
+func (t *tracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	if logm.MaybeGet(ctx) != nil {
+		if startTime, ok := ctx.Value(acquireStartTimeContextKey).(time.Time); ok {
+			logm.SetAttributes(ctx, AttrDBAcquireDuration.V(getDuration(ctx, startTime)))
+		}
+
+		if pool != nil {
+			stat := pool.Stat()
+			logm.SetAttributes(ctx,
+				AttrDBPoolAcquiredConns.V(int64(stat.AcquiredConns())),
+				AttrDBPoolIdleConns.V(int64(stat.IdleConns())),
+				AttrDBPoolTotalConns.V(int64(stat.TotalConns())),
+				AttrDBPoolMaxConns.V(int64(stat.MaxConns())))
+		}
+
+		maybeAddPGErrorAttributes(ctx, acquireErrorAttrKeys, data.Err)
+	}
+}
+
+func getDuration(ctx context.Context, startTime time.Time) time.Duration {
+	return clkm.MustGet(ctx).Now().Sub(startTime)
+}
+
+func addCommandTagAttributes(ctx context.Context, tag pgconn.CommandTag) {
+	if fields := strings.Fields(tag.String()); len(fields) > 0 {
+		logm.SetAttributes(ctx,
+			logm.AttrDBOperation.V(fields[0]),
+			logm.AttrDBRowsAffected.V(tag.RowsAffected()))
+	}
+}
+
+func maybeAddPGErrorAttributes(ctx context.Context, keys *pgErrorAttrKeys, err error) {
+	if err == nil {
+		return
+	}
+
+	if pgErr := errAsPGError(err); pgErr != nil {
+		logm.SetAttributes(ctx,
+			keys.code.V(pgErr.Code),
+			keys.severity.V(memz.Ternary(pgErr.SeverityUnlocalized != "", pgErr.SeverityUnlocalized, pgErr.Severity)))
+
+		if pgErr.ConstraintName != "" {
+			logm.SetAttributes(ctx, keys.constraint.V(pgErr.ConstraintName))
+		}
+		return
+	}
+
+	logm.SetAttributes(ctx, keys.message.V(err.Error()))
+}
+
+// normalizeSQL strips literals and comments from the given SQL statement and collapses whitespace, so that statements
+// differing only in their literal values can be grouped together.
+func normalizeSQL(sql string) string {
+	sql = sqlBlockCommentRegexp.ReplaceAllString(sql, " ")
+	sql = sqlLineCommentRegexp.ReplaceAllString(sql, " ")
+	sql = sqlStringLiteralRegexp.ReplaceAllString(sql, "?")
+	sql = sqlNumericLiteralRegexp.ReplaceAllString(sql, "${1}?")
+	sql = sqlWhitespaceRegexp.ReplaceAllString(sql, " ")
+	return strings.TrimSpace(sql)
+}
@@ -0,0 +1,228 @@
+package pgm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+type TracerSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestTracerSuite(t *testing.T) {
+	fixturez.RunSuite(t, &TracerSuite{})
+}
+
+func (*TracerSuite) TestNormalizeSQL(g *WithT) {
+	for sql, normalized := range map[string]string{
+		"SELECT 1":                          "SELECT ?",
+		"SELECT * FROM users WHERE id = $1": "SELECT * FROM users WHERE id = $1",
+		"SELECT * FROM users WHERE name = 'it''s' AND n = -1":              "SELECT * FROM users WHERE name = ? AND n = ?",
+		"SELECT x1, t2.y FROM t2 WHERE z > 1.5e3":                          "SELECT x1, t2.y FROM t2 WHERE z > ?",
+		"SELECT a -- comment\nFROM  b /* multi\nline */ WHERE c IN (1, 2)": "SELECT a FROM b WHERE c IN (?, ?)",
+		"  INSERT\tINTO t (a)\n VALUES ('x')  ":                            "INSERT INTO t (a) VALUES (?)",
+	} {
+		g.Expect(normalizeSQL(sql)).To(Equal(normalized), sql)
+	}
+}
+
+func (s *TracerSuite) TestTraceQuery(ctx context.Context, g *WithT) {
+	t := newTracer()
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("query")
+		defer end()
+
+		ctx = t.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+			SQL:  "UPDATE users SET name = 'name' WHERE id = $1",
+			Args: []any{1},
+		})
+
+		s.CLK.GetMock().Add(10 * time.Millisecond)
+
+		t.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{
+			CommandTag: pgconn.NewCommandTag("UPDATE 3"),
+		})
+	}()
+
+	g.Expect(s.LOG.GetMock().GetTree()).To(tlogm.HaveSpan("query",
+		tlogm.HaveAttribute("db.statement", "UPDATE users SET name = ? WHERE id = $1"),
+		tlogm.HaveAttribute("db.args_count", int64(1)),
+		tlogm.HaveAttribute("db.duration_ms", 10.0),
+		tlogm.HaveAttribute("db.operation", "UPDATE"),
+		tlogm.HaveAttribute("db.rows_affected", int64(3))))
+}
+
+func (s *TracerSuite) TestTraceQuery_Error(ctx context.Context, g *WithT) {
+	t := newTracer()
+
+	func() {
+		ctx, end := logm.MustGet(ctx).Begin("query")
+		defer end()
+
+		ctx = t.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "INSERT INTO users (id) VALUES (1)"})
+		t.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{
+			Err: &pgconn.PgError{
+				Code:                pgerrcode.UniqueViolation,
+				Severity:            "ERROR",
+				SeverityUnlocalized: "ERROR",
+				ConstraintName:      "users_pkey",
+			},
+		})
+		logm.MustGet(ctx).SetErrorFlag()
+	}()
+
+	g.Expect(s.LOG.GetMock().GetTree()).To(tlogm.HaveSpan("query",
+		tlogm.HaveAttribute("db.statement", "INSERT INTO users (id) VALUES (?)"),
+		tlogm.HaveAttribute("db.error.code", pgerrcode.UniqueViolation),
+		tlogm.HaveAttribute("db.error.severity", "ERROR"),
+		tlogm.HaveAttribute("db.error.constraint", "users_pkey")))
+}
+
+func (s *TracerSuite) TestTraceQuery_NoLog(g *WithT) {
+	t := newTracer()
+	ctx := context.Background()
+
+	g.Expect(t.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})).To(Equal(ctx))
+	g.Expect(func() { t.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{}) }).ToNot(Panic())
+}
+
+func (s *TracerSuite) TestTraceQuery_Rows(ctx context.Context, g *WithT) {
+	t := newTracer()
+
+	rows, err := traceQuery(ctx, "select", func(ctx context.Context) (pgx.Rows, error) {
+		ctx = t.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT id FROM users"})
+
+		return newTestRows(2, nil, func() {
+			s.CLK.GetMock().Add(5 * time.Millisecond)
+			t.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 2")})
+		}), nil
+	})
+	g.Expect(err).To(Succeed())
+
+	// The span is still open while the rows are being read.
+	g.Expect(s.LOG.GetMock().GetTree().GetSpans()).To(BeEmpty())
+
+	for rows.Next() {
+		// intentionally empty
+	}
+
+	g.Expect(s.LOG.GetMock().GetTree()).To(tlogm.HaveSpan("pgm.Query.[select]",
+		tlogm.HaveAttribute("db.statement", "SELECT id FROM users"),
+		tlogm.HaveAttribute("db.duration_ms", 5.0),
+		tlogm.HaveAttribute("db.rows_affected", int64(2))))
+
+	// Closing again does not end the span twice.
+	rows.Close()
+	g.Expect(s.LOG.GetMock().GetTree().GetSpans()).To(HaveLen(1))
+}
+
+func (s *TracerSuite) TestTraceQuery_RowsError(ctx context.Context, g *WithT) {
+	rows, err := traceQuery(ctx, "select", func(ctx context.Context) (pgx.Rows, error) {
+		return newTestRows(0, errorz.Errorf("rows error"), func() {}), nil
+	})
+	g.Expect(err).To(Succeed())
+	rows.Close()
+
+	_, err = traceQuery(ctx, "fail", func(ctx context.Context) (pgx.Rows, error) {
+		return nil, errorz.Errorf("query error")
+	})
+	g.Expect(err).To(MatchError("query error"))
+
+	_, err = traceQuery(ctx, "cancel", func(ctx context.Context) (pgx.Rows, error) {
+		return nil, errorz.Wrap(context.Canceled)
+	})
+	g.Expect(err).To(MatchError(context.Canceled))
+
+	tree := s.LOG.GetMock().GetTree()
+	g.Expect(tree).To(tlogm.HaveSpan("pgm.Query.[select]", tlogm.HaveError("generic")))
+	g.Expect(tree).To(tlogm.HaveSpan("pgm.Query.[fail]", tlogm.HaveError("generic")))
+	g.Expect(tree.FindSpan("pgm.Query.[cancel]").HasError).To(BeFalse())
+	g.Expect(tree.FindSpan("pgm.Query.[cancel]").Events).To(BeEmpty())
+}
+
+func (s *TracerSuite) TestTraceQueryRow(ctx context.Context, g *WithT) {
+	row := traceQueryRow(ctx, "found", func(ctx context.Context) pgx.Row {
+		return &testRow{err: nil}
+	})
+
+	g.Expect(s.LOG.GetMock().GetTree().GetSpans()).To(BeEmpty())
+	g.Expect(row.Scan()).To(Succeed())
+
+	row = traceQueryRow(ctx, "not-found", func(ctx context.Context) pgx.Row {
+		return &testRow{err: pgx.ErrNoRows}
+	})
+	g.Expect(row.Scan()).To(MatchError(pgx.ErrNoRows))
+
+	row = traceQueryRow(ctx, "fail", func(ctx context.Context) pgx.Row {
+		return &testRow{err: errorz.Errorf("scan error")}
+	})
+	g.Expect(row.Scan()).To(MatchError("scan error"))
+
+	tree := s.LOG.GetMock().GetTree()
+	g.Expect(tree.FindSpan("pgm.QueryRow.[found]").HasError).To(BeFalse())
+	g.Expect(tree.FindSpan("pgm.QueryRow.[not-found]").HasError).To(BeFalse())
+	g.Expect(tree).To(tlogm.HaveSpan("pgm.QueryRow.[fail]", tlogm.HaveError("generic")))
+}
+
+type testRows struct {
+	pgx.Rows
+	remaining int
+	err       error
+	onClose   func()
+	isClosed  bool
+}
+
+func newTestRows(n int, err error, onClose func()) *testRows {
+	return &testRows{
+		remaining: n,
+		err:       err,
+		onClose:   onClose,
+	}
+}
+
+// Next implements the [pgx.Rows] interface.
+func (r *testRows) Next() bool {
+	if r.remaining > 0 {
+		r.remaining--
+		return true
+	}
+
+	r.Close()
+	return false
+}
+
+// Close implements the [pgx.Rows] interface.
+func (r *testRows) Close() {
+	if !r.isClosed {
+		r.isClosed = true
+		r.onClose()
+	}
+}
+
+// Err implements the [pgx.Rows] interface.
+func (r *testRows) Err() error {
+	return r.err
+}
+
+type testRow struct {
+	err error
+}
+
+// Scan implements the [pgx.Row] interface.
+func (r *testRow) Scan(_ ...any) error {
+	return r.err
+}
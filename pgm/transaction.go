@@ -17,8 +17,9 @@ var (
 )
 
 type transactionPGImpl struct {
-	tx   pgx.Tx
-	name string
+	tx    pgx.Tx
+	name  string
+	depth int
 }
 
 // Exec implements the [RawPG] interface.
@@ -32,37 +33,50 @@ func (t *transactionPGImpl) Exec(ctx context.Context, name, query string, args .
 		})
 }
 
-// Query implements the [RawPG] interface.
+// Query implements the [RawPG] interface. The span ends when the rows are closed.
 func (t *transactionPGImpl) Query(ctx context.Context, name, query string, args ...any) (pgx.Rows, error) {
-	return logm.Wrap1(
+	return traceQuery(
 		ctx,
-		fmt.Sprintf("pgm.Query.[%v]", name),
+		name,
 		func(ctx context.Context) (pgx.Rows, error) {
-			rows, err := t.tx.Query(ctx, query, args...)
-			return rows, errorz.MaybeWrap(err)
+			return t.tx.Query(ctx, query, args...)
 		})
 }
 
-// QueryRow implements the [RawPG] interface.
+// QueryRow implements the [RawPG] interface. The span ends when the row is scanned.
 func (t *transactionPGImpl) QueryRow(ctx context.Context, name, query string, args ...any) pgx.Row {
-	row, err := logm.Wrap1(
+	return traceQueryRow(
 		ctx,
-		fmt.Sprintf("pgm.QueryRow.[%v]", name),
-		func(ctx context.Context) (pgx.Row, error) {
-			return t.tx.QueryRow(ctx, query, args...), nil
+		name,
+		func(ctx context.Context) pgx.Row {
+			return t.tx.QueryRow(ctx, query, args...)
 		})
-	if err != nil {
-		return &errRow{
-			err: err,
-		}
-	}
-
-	return row
 }
 
-// Begin implements the [RawPG] interface.
-func (t *transactionPGImpl) Begin(_ context.Context, _ string, _ ...BeginOption) (context.Context, func(), func() error, error) {
-	return nil, nil, nil, errorz.Errorf("nested transaction")
+// Begin implements the [RawPG] interface. It starts a nested transaction using a savepoint: ending it without committing
+// rolls back to the savepoint, committing it releases the savepoint. The options are ignored, as savepoints share the
+// isolation level and access mode of the enclosing transaction.
+func (t *transactionPGImpl) Begin(ctx context.Context, name string, _ ...BeginOption) (context.Context, func(), func() error, error) {
+	return logm.Wrap3(
+		ctx,
+		fmt.Sprintf("pgm.Savepoint.[%v]", name),
+		func(ctx context.Context) (context.Context, func(), func() error, error) {
+			logm.SetAttributes(ctx, AttrDBSavepointDepth.V(int64(t.depth+1)))
+
+			tx, err := t.tx.Begin(ctx)
+			if err != nil {
+				return nil, nil, nil, errorz.Wrap(err)
+			}
+
+			tP := &transactionPGImpl{
+				tx:    tx,
+				name:  name,
+				depth: t.depth + 1,
+			}
+
+			ctx = NewSingletonInjector(tP)(ctx)
+			return ctx, func() { tP.end(ctx) }, func() error { return tP.commit(ctx) }, nil
+		})
 }
 
 func (t *transactionPGImpl) end(ctx context.Context) {
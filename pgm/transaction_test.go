@@ -0,0 +1,190 @@
+package pgm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ibrt/golang-utils/errorz"
+	"github.com/ibrt/golang-utils/fixturez"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	. "github.com/onsi/gomega"
+
+	"github.com/ibrt/golang-modules/clkm/tclkm"
+	"github.com/ibrt/golang-modules/logm/tlogm"
+)
+
+var (
+	_ pgx.Tx = (*testTx)(nil)
+	_ RawPG  = (*testRawPG)(nil)
+)
+
+// testTx is a fake [pgx.Tx] that records the statements it receives. Nested transactions are savepoints.
+type testTx struct {
+	pgx.Tx
+	calls    *[]string
+	depth    int
+	isClosed bool
+}
+
+func newTestTx(calls *[]string) *testTx {
+	return &testTx{
+		calls: calls,
+	}
+}
+
+func (t *testTx) record(format string, args ...any) {
+	*t.calls = append(*t.calls, fmt.Sprintf(format, args...))
+}
+
+func (t *testTx) Begin(_ context.Context) (pgx.Tx, error) {
+	t.record("savepoint %v", t.depth+1)
+
+	return &testTx{
+		calls: t.calls,
+		depth: t.depth + 1,
+	}, nil
+}
+
+func (t *testTx) Commit(_ context.Context) error {
+	t.isClosed = true
+
+	if t.depth > 0 {
+		t.record("release %v", t.depth)
+	} else {
+		t.record("commit")
+	}
+
+	return nil
+}
+
+func (t *testTx) Rollback(_ context.Context) error {
+	if t.isClosed {
+		return pgx.ErrTxClosed
+	}
+
+	t.isClosed = true
+
+	if t.depth > 0 {
+		t.record("rollback to %v", t.depth)
+	} else {
+		t.record("rollback")
+	}
+
+	return nil
+}
+
+func (t *testTx) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	t.record("exec %v: %v", t.depth, sql)
+	return pgconn.NewCommandTag("SELECT 1"), nil
+}
+
+// testRawPG is a fake [RawPG] that begins transactions using [*testTx].
+type testRawPG struct {
+	RawPG
+	calls *[]string
+}
+
+func (p *testRawPG) Begin(ctx context.Context, name string, _ ...BeginOption) (context.Context, func(), func() error, error) {
+	*p.calls = append(*p.calls, "begin")
+
+	tP := &transactionPGImpl{
+		tx:   newTestTx(p.calls),
+		name: name,
+	}
+
+	ctx = NewSingletonInjector(tP)(ctx)
+	return ctx, func() { tP.end(ctx) }, func() error { return tP.commit(ctx) }, nil
+}
+
+type TransactionSuite struct {
+	CLK *tclkm.MockHelper
+	LOG *tlogm.MockHelper
+}
+
+func TestTransactionSuite(t *testing.T) {
+	fixturez.RunSuite(t, &TransactionSuite{})
+}
+
+func (s *TransactionSuite) TestSavepoints(ctx context.Context, g *WithT) {
+	calls := make([]string, 0)
+	ctx = NewSingletonInjector(&transactionPGImpl{tx: newTestTx(&calls), name: "tx"})(ctx)
+
+	g.Expect(Wrap0(ctx, "outer", func(ctx context.Context) error {
+		if _, err := MustGet(ctx).Exec("q1", "SELECT 1"); err != nil {
+			return errorz.Wrap(err)
+		}
+
+		if err := Wrap0(ctx, "inner", func(ctx context.Context) error {
+			_, err := MustGet(ctx).Exec("q2", "SELECT 2")
+			return errorz.MaybeWrap(err)
+		}); err != nil {
+			return errorz.Wrap(err)
+		}
+
+		g.Expect(Wrap0(ctx, "failing", func(ctx context.Context) error {
+			return errorz.Errorf("test error")
+		})).To(MatchError("test error"))
+
+		return nil
+	})).To(Succeed())
+
+	g.Expect(calls).To(HaveExactElements(
+		"savepoint 1",
+		"exec 1: SELECT 1",
+		"savepoint 2",
+		"exec 2: SELECT 2",
+		"release 2",
+		"savepoint 2",
+		"rollback to 2",
+		"release 1"))
+
+	g.Expect(s.LOG.GetMock().GetTree()).To(tlogm.HaveSpan("pgm.Wrap.[outer]",
+		tlogm.HaveChild("pgm.Wrap.0.[outer]",
+			tlogm.HaveChild("pgm.Savepoint.[outer]", tlogm.HaveAttribute("db.savepoint_depth", int64(1))))))
+}
+
+func (*TransactionSuite) TestSavepoints_NoRetry(ctx context.Context, g *WithT) {
+	calls := make([]string, 0)
+	ctx = NewSingletonInjector(&transactionPGImpl{tx: newTestTx(&calls), name: "tx"})(ctx)
+	attempts := 0
+
+	err := Wrap0(ctx, "inner", func(ctx context.Context) error {
+		attempts++
+		return &pgconn.PgError{Code: pgerrcode.SerializationFailure}
+	}, &RetryPolicy{MaxAttempts: 3})
+
+	g.Expect(errAsPGError(err)).ToNot(BeNil())
+	g.Expect(attempts).To(Equal(1))
+	g.Expect(calls).To(HaveExactElements("savepoint 1", "rollback to 1"))
+}
+
+func (*TransactionSuite) TestSavepoints_OuterRetry(ctx context.Context, g *WithT) {
+	calls := make([]string, 0)
+	ctx = NewSingletonInjector(&testRawPG{calls: &calls})(ctx)
+	attempts := 0
+
+	g.Expect(Wrap0(ctx, "outer", func(ctx context.Context) error {
+		attempts++
+
+		return Wrap0(ctx, "inner", func(ctx context.Context) error {
+			if attempts == 1 {
+				return &pgconn.PgError{Code: pgerrcode.SerializationFailure}
+			}
+			return nil
+		})
+	}, &RetryPolicy{MaxAttempts: 3})).To(Succeed())
+
+	g.Expect(attempts).To(Equal(2))
+	g.Expect(calls).To(HaveExactElements(
+		"begin",
+		"savepoint 1",
+		"rollback to 1",
+		"rollback",
+		"begin",
+		"savepoint 1",
+		"release 1",
+		"commit"))
+}
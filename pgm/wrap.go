@@ -3,23 +3,18 @@ package pgm
 import (
 	"context"
 	"fmt"
-	"math/rand/v2"
 	"time"
 
 	"github.com/ibrt/golang-utils/errorz"
-	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 
+	"github.com/ibrt/golang-modules/clkm"
 	"github.com/ibrt/golang-modules/logm"
 )
 
-const (
-	txMaxRetries    = 10
-	randJitterMinMs = 100
-	randJitterMaxMs = 500
-)
-
-// Wrap0 wraps a function that returns (error) in a transaction, retrying it if needed.
+// Wrap0 wraps a function that returns (error) in a transaction, retrying it according to the [*RetryPolicy] (see
+// [NewDefaultRetryPolicy]). If called within a transaction, it uses a savepoint, and retryable errors are propagated to
+// the enclosing retry loop.
 func Wrap0(
 	ctx context.Context,
 	name string,
@@ -30,48 +25,13 @@ func Wrap0(
 		ctx,
 		fmt.Sprintf("pgm.Wrap.[%v]", name),
 		func(ctx context.Context) error {
-			var err error
-
-			for i := 0; i < txMaxRetries; i++ {
-				if err = wrap0(ctx, name, i, f, options...); err != nil {
-					if pgErr := errAsPGError(err); pgErr != nil && pgErr.Code == pgerrcode.SerializationFailure && i < txMaxRetries-1 {
-						time.Sleep(randJitter())
-						continue
-					}
-				}
-
-				break
-			}
-
-			return errorz.MaybeWrap(err)
-		})
-}
-
-func wrap0(
-	ctx context.Context,
-	name string,
-	i int,
-	f func(ctx context.Context) error,
-	options ...BeginOption) error {
-	return logm.Wrap0(
-		ctx,
-		fmt.Sprintf("pgm.Wrap.%v.[%v]", i, name),
-		func(ctx context.Context) (err error) {
-			ctx, end, commit, err := MustGet(ctx).Begin(name, options...)
-			if err != nil {
-				return errorz.Wrap(err)
-			}
-			defer end()
-
-			if err := f(ctx); err != nil {
-				return errorz.Wrap(err)
-			}
-
-			return errorz.MaybeWrap(commit())
+			return retry(ctx, name, f, options...)
 		})
 }
 
-// Wrap1 wraps a function that returns (T, error) in a transaction, retrying it if needed.
+// Wrap1 wraps a function that returns (T, error) in a transaction, retrying it according to the [*RetryPolicy] (see
+// [NewDefaultRetryPolicy]). If called within a transaction, it uses a savepoint, and retryable errors are propagated to
+// the enclosing retry loop.
 func Wrap1[T any](
 	ctx context.Context,
 	name string,
@@ -83,51 +43,19 @@ func Wrap1[T any](
 		fmt.Sprintf("pgm.Wrap.[%v]", name),
 		func(ctx context.Context) (T, error) {
 			var t T
-			var err error
-
-			for i := 0; i < txMaxRetries; i++ {
-				if t, err = wrap1(ctx, name, i, f, options...); err != nil {
-					if pgErr := errAsPGError(err); pgErr != nil && pgErr.Code == pgerrcode.SerializationFailure && i < txMaxRetries-1 {
-						time.Sleep(randJitter())
-						continue
-					}
-				}
 
-				break
-			}
+			err := retry(ctx, name, func(ctx context.Context) (err error) {
+				t, err = f(ctx)
+				return err
+			}, options...)
 
 			return t, errorz.MaybeWrap(err)
 		})
 }
 
-func wrap1[T any](
-	ctx context.Context,
-	name string,
-	i int,
-	f func(ctx context.Context) (T, error),
-	options ...BeginOption) (T, error) {
-	return logm.Wrap1(
-		ctx,
-		fmt.Sprintf("pgm.Wrap.%v.[%v]", i, name),
-		func(ctx context.Context) (T, error) {
-			var t T
-
-			ctx, end, commit, err := MustGet(ctx).Begin(name, options...)
-			if err != nil {
-				return t, errorz.Wrap(err)
-			}
-			defer end()
-
-			t, err = f(ctx)
-			if err != nil {
-				return t, errorz.Wrap(err)
-			}
-
-			return t, errorz.MaybeWrap(commit())
-		})
-}
-
-// Wrap2 wraps a function that returns (T1, T2, error) in a transaction, retrying it if needed.
+// Wrap2 wraps a function that returns (T1, T2, error) in a transaction, retrying it according to the [*RetryPolicy]
+// (see [NewDefaultRetryPolicy]). If called within a transaction, it uses a savepoint, and retryable errors are
+// propagated to the enclosing retry loop.
 func Wrap2[T1 any, T2 any](
 	ctx context.Context,
 	name string,
@@ -140,52 +68,19 @@ func Wrap2[T1 any, T2 any](
 		func(ctx context.Context) (T1, T2, error) {
 			var t1 T1
 			var t2 T2
-			var err error
-
-			for i := 0; i < txMaxRetries; i++ {
-				if t1, t2, err = wrap2(ctx, name, i, f, options...); err != nil {
-					if pgErr := errAsPGError(err); pgErr != nil && pgErr.Code == pgerrcode.SerializationFailure && i < txMaxRetries-1 {
-						time.Sleep(randJitter())
-						continue
-					}
-				}
 
-				break
-			}
+			err := retry(ctx, name, func(ctx context.Context) (err error) {
+				t1, t2, err = f(ctx)
+				return err
+			}, options...)
 
 			return t1, t2, errorz.MaybeWrap(err)
 		})
 }
 
-func wrap2[T1 any, T2 any](
-	ctx context.Context,
-	name string,
-	i int,
-	f func(ctx context.Context) (T1, T2, error),
-	options ...BeginOption) (T1, T2, error) {
-	return logm.Wrap2(
-		ctx,
-		fmt.Sprintf("pgm.Wrap.%v.[%v]", i, name),
-		func(ctx context.Context) (T1, T2, error) {
-			var t1 T1
-			var t2 T2
-
-			ctx, end, commit, err := MustGet(ctx).Begin(name, options...)
-			if err != nil {
-				return t1, t2, errorz.Wrap(err)
-			}
-			defer end()
-
-			t1, t2, err = f(ctx)
-			if err != nil {
-				return t1, t2, errorz.Wrap(err)
-			}
-
-			return t1, t2, errorz.MaybeWrap(commit())
-		})
-}
-
-// Wrap3 wraps a function that returns (T1, T2, T3, error) in a transaction, retrying it if needed.
+// Wrap3 wraps a function that returns (T1, T2, T3, error) in a transaction, retrying it according to the
+// [*RetryPolicy] (see [NewDefaultRetryPolicy]). If called within a transaction, it uses a savepoint, and retryable
+// errors are propagated to the enclosing retry loop.
 func Wrap3[T1 any, T2 any, T3 any](
 	ctx context.Context,
 	name string,
@@ -199,50 +94,107 @@ func Wrap3[T1 any, T2 any, T3 any](
 			var t1 T1
 			var t2 T2
 			var t3 T3
-			var err error
-
-			for i := 0; i < txMaxRetries; i++ {
-				if t1, t2, t3, err = wrap3(ctx, name, i, f, options...); err != nil {
-					if pgErr := errAsPGError(err); pgErr != nil && pgErr.Code == pgerrcode.SerializationFailure && i < txMaxRetries-1 {
-						time.Sleep(randJitter())
-						continue
-					}
-				}
 
-				break
-			}
+			err := retry(ctx, name, func(ctx context.Context) (err error) {
+				t1, t2, t3, err = f(ctx)
+				return err
+			}, options...)
 
 			return t1, t2, t3, errorz.MaybeWrap(err)
 		})
 }
 
-func wrap3[T1 any, T2 any, T3 any](
+// retry runs the given function in a transaction, retrying it according to the retry policy. Nested transactions
+// (i.e. savepoints) are attempted once, so that retryable errors reach the outermost retry loop, which restarts the
+// whole transaction.
+func retry(
 	ctx context.Context,
 	name string,
-	i int,
-	f func(ctx context.Context) (T1, T2, T3, error),
-	options ...BeginOption) (T1, T2, T3, error) {
-	return logm.Wrap3(
+	f func(ctx context.Context) error,
+	options ...BeginOption) error {
+
+	p := newBeginOptions(options...).retryPolicy
+	if p == nil {
+		p = NewDefaultRetryPolicy()
+	}
+
+	maxAttempts := p.getMaxAttempts()
+	if _, ok := ctx.Value(pgContextKey).(*transactionPGImpl); ok {
+		maxAttempts = 1
+	}
+
+	clk := clkm.MustGet(ctx)
+	startTime := clk.Now()
+	reason := ""
+	backoff := time.Duration(0)
+
+	for attempt := 1; ; attempt++ {
+		isCommitting, err := wrapAttempt(ctx, name, attempt, reason, backoff, f, options...)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= maxAttempts {
+			return errorz.Wrap(err)
+		}
+
+		if reason = p.classify(err, isCommitting); reason == "" {
+			return errorz.Wrap(err)
+		}
+
+		backoff = p.getBackoff(attempt + 1)
+		retryTime := clk.Now().Add(backoff)
+
+		if p.Budget > 0 && retryTime.Sub(startTime) > p.Budget {
+			return errorz.Wrap(err)
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && retryTime.After(deadline) {
+			return errorz.Wrap(err)
+		}
+
+		if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+			return errorz.Wrap(err, sleepErr)
+		}
+	}
+}
+
+// wrapAttempt runs an attempt in a transaction, recording its number and the reason for retrying (if not the first
+// attempt) on its span. It returns true if the error (if any) was returned by the commit.
+func wrapAttempt(
+	ctx context.Context,
+	name string,
+	attempt int,
+	reason string,
+	backoff time.Duration,
+	f func(ctx context.Context) error,
+	options ...BeginOption) (isCommitting bool, err error) {
+
+	err = logm.Wrap0(
 		ctx,
-		fmt.Sprintf("pgm.Wrap.%v.[%v]", i, name),
-		func(ctx context.Context) (T1, T2, T3, error) {
-			var t1 T1
-			var t2 T2
-			var t3 T3
+		fmt.Sprintf("pgm.Wrap.%v.[%v]", attempt-1, name),
+		func(ctx context.Context) error {
+			logm.SetAttributes(ctx, AttrDBRetryAttempt.V(int64(attempt)))
+
+			if reason != "" {
+				logm.SetAttributes(ctx, AttrDBRetryReason.V(reason), AttrDBRetryBackoff.V(backoff))
+			}
 
 			ctx, end, commit, err := MustGet(ctx).Begin(name, options...)
 			if err != nil {
-				return t1, t2, t3, errorz.Wrap(err)
+				return errorz.Wrap(err)
 			}
 			defer end()
 
-			t1, t2, t3, err = f(ctx)
-			if err != nil {
-				return t1, t2, t3, errorz.Wrap(err)
+			if err := f(ctx); err != nil {
+				return errorz.Wrap(err)
 			}
 
-			return t1, t2, t3, errorz.MaybeWrap(commit())
+			isCommitting = true
+			return errorz.MaybeWrap(commit())
 		})
+
+	return isCommitting, err
 }
 
 func errAsPGError(err error) *pgconn.PgError {
@@ -251,7 +203,3 @@ func errAsPGError(err error) *pgconn.PgError {
 	}
 	return nil
 }
-
-func randJitter() time.Duration {
-	return time.Duration(randJitterMinMs+((randJitterMaxMs-randJitterMinMs)*rand.Float64())) * time.Millisecond
-}